@@ -30,8 +30,16 @@ module "eks" {
   vpc_id     = var.vpc_id
   subnet_ids = length(var.private_subnet_ids) > 0 ? var.private_subnet_ids : var.private_subnets
 
-  cluster_endpoint_public_access  = true
-  cluster_endpoint_private_access = true
+  cluster_endpoint_public_access       = var.cluster_endpoint_public_access
+  cluster_endpoint_private_access      = var.cluster_endpoint_private_access
+  cluster_endpoint_public_access_cidrs = var.cluster_endpoint_public_access_cidrs
+
+  # Without the module's key there is no key to encrypt secrets with.
+  create_kms_key            = var.create_kms_key
+  cluster_encryption_config = var.create_kms_key ? tomap({ resources = ["secrets"] }) : tomap({})
+
+  cluster_enabled_log_types              = var.cluster_enabled_log_types
+  cloudwatch_log_group_retention_in_days = var.cloudwatch_log_group_retention_in_days
 
   enable_cluster_creator_admin_permissions = true
 
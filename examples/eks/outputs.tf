@@ -43,16 +43,36 @@ output "node_security_group_id" {
   value       = module.eks.node_security_group_id
 }
 
+output "oidc_provider" {
+  description = "The OpenID Connect identity provider"
+  value       = module.eks.oidc_provider
+}
+
 output "oidc_provider_arn" {
   description = "The ARN of the OIDC Provider"
   value       = module.eks.oidc_provider_arn
 }
 
+output "cluster_iam_role_arn" {
+  description = "IAM role ARN of the EKS cluster"
+  value       = module.eks.cluster_iam_role_arn
+}
+
+output "kms_key_arn" {
+  description = "The ARN of the KMS key that encrypts cluster secrets"
+  value       = module.eks.kms_key_arn
+}
+
 output "eks_managed_node_groups" {
   description = "Map of attribute maps for all EKS managed node groups created"
   value       = module.eks.eks_managed_node_groups
 }
 
+output "cluster_addons" {
+  description = "Map of attribute maps for all EKS cluster addons enabled"
+  value       = module.eks.cluster_addons
+}
+
 ################################################################################
 # Kubeconfig Helper
 ################################################################################
@@ -39,6 +39,42 @@ variable "environment" {
   default     = "test"
 }
 
+variable "cluster_endpoint_public_access" {
+  description = "Expose the API endpoint outside the VPC"
+  type        = bool
+  default     = true
+}
+
+variable "cluster_endpoint_private_access" {
+  description = "Expose the API endpoint inside the VPC"
+  type        = bool
+  default     = true
+}
+
+variable "cluster_endpoint_public_access_cidrs" {
+  description = "CIDR blocks allowed to reach the public API endpoint"
+  type        = list(string)
+  default     = ["0.0.0.0/0"]
+}
+
+variable "create_kms_key" {
+  description = "Create a KMS key for envelope encryption of secrets"
+  type        = bool
+  default     = true
+}
+
+variable "cluster_enabled_log_types" {
+  description = "Control plane log types to send to CloudWatch"
+  type        = list(string)
+  default     = ["api", "audit", "authenticator"]
+}
+
+variable "cloudwatch_log_group_retention_in_days" {
+  description = "Retention of the control plane log group"
+  type        = number
+  default     = 7
+}
+
 variable "node_instance_types" {
   description = "Instance types for the managed node group"
   type        = list(string)
@@ -58,6 +58,11 @@ output "cluster_iam_role_name" {
   value       = module.eks.cluster_iam_role_name
 }
 
+output "kms_key_arn" {
+  description = "The ARN of the KMS key that encrypts cluster secrets"
+  value       = module.eks.kms_key_arn
+}
+
 output "eks_managed_node_groups" {
   description = "Map of attribute maps for all EKS managed node groups created"
   value       = module.eks.eks_managed_node_groups
@@ -9,6 +9,11 @@ set -euo pipefail
 
 TEST_DIR="${TEST_DIR:-test}"
 
+if [[ -d .task/runs ]] && [[ -n "$(ls -A .task/runs 2>/dev/null)" ]]; then
+  echo "WARNING: .task/runs holds Terraform state from an unfinished matrix run."
+  echo "         Its resources may still exist; run 'task test-integration-resume' or 'task cleanup-run' first."
+fi
+
 echo "Cleaning Terraform state and caches..."
 find . -type d -name ".terraform" -exec rm -rf {} + 2>/dev/null || true
 find . -type f -name ".terraform.lock.hcl" -delete 2>/dev/null || true
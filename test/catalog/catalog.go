@@ -0,0 +1,197 @@
+// Package catalog provides offline EKS version lifecycle data from the
+// checked-in eks_versions.json, so version selection and support warnings
+// work without calling AWS.
+//
+// Update eks_versions.json when AWS announces a new EKS version or changes a
+// support date, and bump "updated".
+package catalog
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/apex/terratest-eks/version"
+)
+
+// SchemaVersion is the eks_versions.json format this package understands.
+const SchemaVersion = 1
+
+const dateLayout = "2006-01-02"
+
+//go:embed eks_versions.json
+var embedded []byte
+
+// Phase is where a version sits in the EKS support lifecycle at a point in time.
+type Phase string
+
+const (
+	PhaseUnreleased Phase = "unreleased"
+	PhaseStandard   Phase = "standard"
+	PhaseExtended   Phase = "extended"
+	PhaseEndOfLife  Phase = "end-of-life"
+)
+
+// Entry is the lifecycle of a single EKS version. Support ends at the start
+// of the given day (UTC).
+type Entry struct {
+	Version              version.KubeVersion
+	ReleaseDate          time.Time
+	EndOfStandardSupport time.Time
+	EndOfExtendedSupport time.Time
+}
+
+// Phase returns the support phase of the entry at time at.
+func (e Entry) Phase(at time.Time) Phase {
+	switch {
+	case at.Before(e.ReleaseDate):
+		return PhaseUnreleased
+	case at.Before(e.EndOfStandardSupport):
+		return PhaseStandard
+	case at.Before(e.EndOfExtendedSupport):
+		return PhaseExtended
+	default:
+		return PhaseEndOfLife
+	}
+}
+
+// PhaseEnds returns when the phase the entry is in at time at ends. It
+// returns the zero time for PhaseEndOfLife.
+func (e Entry) PhaseEnds(at time.Time) time.Time {
+	switch e.Phase(at) {
+	case PhaseUnreleased:
+		return e.ReleaseDate
+	case PhaseStandard:
+		return e.EndOfStandardSupport
+	case PhaseExtended:
+		return e.EndOfExtendedSupport
+	}
+	return time.Time{}
+}
+
+// Catalog is the parsed version catalog, ordered oldest version first.
+type Catalog struct {
+	Updated time.Time
+	Source  string
+	Entries []Entry
+}
+
+type catalogFile struct {
+	SchemaVersion int    `json:"schema_version"`
+	Updated       string `json:"updated"`
+	Source        string `json:"source"`
+	Versions      []struct {
+		Version              string `json:"version"`
+		ReleaseDate          string `json:"release_date"`
+		EndOfStandardSupport string `json:"end_of_standard_support"`
+		EndOfExtendedSupport string `json:"end_of_extended_support"`
+	} `json:"versions"`
+}
+
+// Load returns the catalog embedded from eks_versions.json.
+func Load() (*Catalog, error) {
+	return Parse(embedded)
+}
+
+// LoadFile reads a catalog from disk, e.g. a newer copy than the embedded one.
+func LoadFile(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse decodes and checks a catalog: the schema version must match, every
+// date must parse, dates must be in lifecycle order, and versions must be unique.
+func Parse(data []byte) (*Catalog, error) {
+	var raw catalogFile
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode catalog: %w", err)
+	}
+
+	if raw.SchemaVersion != SchemaVersion {
+		return nil, fmt.Errorf("unsupported catalog schema_version %d, want %d", raw.SchemaVersion, SchemaVersion)
+	}
+
+	updated, err := time.Parse(dateLayout, raw.Updated)
+	if err != nil {
+		return nil, fmt.Errorf("invalid catalog updated date: %w", err)
+	}
+
+	cat := &Catalog{Updated: updated, Source: raw.Source}
+	seen := make(map[version.KubeVersion]bool)
+
+	for _, rv := range raw.Versions {
+		v, err := version.Parse(rv.Version)
+		if err != nil {
+			return nil, fmt.Errorf("catalog entry: %w", err)
+		}
+		if seen[v] {
+			return nil, fmt.Errorf("catalog entry %s is duplicated", v)
+		}
+		seen[v] = true
+
+		entry := Entry{Version: v}
+		dates := []struct {
+			name string
+			raw  string
+			dst  *time.Time
+		}{
+			{"release_date", rv.ReleaseDate, &entry.ReleaseDate},
+			{"end_of_standard_support", rv.EndOfStandardSupport, &entry.EndOfStandardSupport},
+			{"end_of_extended_support", rv.EndOfExtendedSupport, &entry.EndOfExtendedSupport},
+		}
+		for _, d := range dates {
+			if *d.dst, err = time.Parse(dateLayout, d.raw); err != nil {
+				return nil, fmt.Errorf("catalog entry %s has invalid %s: %w", v, d.name, err)
+			}
+		}
+
+		if !entry.ReleaseDate.Before(entry.EndOfStandardSupport) || !entry.EndOfStandardSupport.Before(entry.EndOfExtendedSupport) {
+			return nil, fmt.Errorf("catalog entry %s dates must be release < end of standard < end of extended", v)
+		}
+
+		cat.Entries = append(cat.Entries, entry)
+	}
+
+	sort.Slice(cat.Entries, func(i, j int) bool {
+		return cat.Entries[i].Version.Less(cat.Entries[j].Version)
+	})
+
+	return cat, nil
+}
+
+// Lookup returns the entry for v.
+func (c *Catalog) Lookup(v version.KubeVersion) (Entry, bool) {
+	for _, e := range c.Entries {
+		if e.Version == v {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Versions returns every version in the catalog, oldest first.
+func (c *Catalog) Versions() []version.KubeVersion {
+	versions := make([]version.KubeVersion, 0, len(c.Entries))
+	for _, e := range c.Entries {
+		versions = append(versions, e.Version)
+	}
+	return versions
+}
+
+// Available returns the versions that can be created at time at (standard
+// or extended support), oldest first.
+func (c *Catalog) Available(at time.Time) []version.KubeVersion {
+	var versions []version.KubeVersion
+	for _, e := range c.Entries {
+		if p := e.Phase(at); p == PhaseStandard || p == PhaseExtended {
+			versions = append(versions, e.Version)
+		}
+	}
+	return versions
+}
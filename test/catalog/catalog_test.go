@@ -0,0 +1,109 @@
+package catalog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apex/terratest-eks/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestLoadEmbedded(t *testing.T) {
+	cat, err := Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, cat.Entries)
+
+	versions := cat.Versions()
+	for i := 1; i < len(versions); i++ {
+		assert.Equal(t, versions[i-1].NextMinor(), versions[i], "catalog should have no gaps between minors")
+	}
+
+	latest := cat.Entries[len(cat.Entries)-1]
+	assert.False(t, latest.ReleaseDate.After(cat.Updated), "updated date should not predate the newest release")
+}
+
+func TestEntryPhase(t *testing.T) {
+	e := Entry{
+		Version:              version.MustParse("1.31"),
+		ReleaseDate:          date("2024-09-26"),
+		EndOfStandardSupport: date("2025-11-26"),
+		EndOfExtendedSupport: date("2026-11-26"),
+	}
+
+	tests := []struct {
+		at        string
+		phase     Phase
+		phaseEnds string
+	}{
+		{"2024-09-25", PhaseUnreleased, "2024-09-26"},
+		{"2024-09-26", PhaseStandard, "2025-11-26"},
+		{"2025-11-25", PhaseStandard, "2025-11-26"},
+		{"2025-11-26", PhaseExtended, "2026-11-26"},
+		{"2026-11-26", PhaseEndOfLife, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.at, func(t *testing.T) {
+			assert.Equal(t, tt.phase, e.Phase(date(tt.at)))
+			if tt.phaseEnds == "" {
+				assert.True(t, e.PhaseEnds(date(tt.at)).IsZero())
+			} else {
+				assert.Equal(t, date(tt.phaseEnds), e.PhaseEnds(date(tt.at)))
+			}
+		})
+	}
+}
+
+func TestCatalogQueries(t *testing.T) {
+	cat, err := Parse([]byte(`{
+		"schema_version": 1,
+		"updated": "2025-06-01",
+		"versions": [
+			{"version": "1.33", "release_date": "2025-05-29", "end_of_standard_support": "2026-07-29", "end_of_extended_support": "2027-07-29"},
+			{"version": "1.29", "release_date": "2024-01-23", "end_of_standard_support": "2025-03-23", "end_of_extended_support": "2026-03-23"},
+			{"version": "1.27", "release_date": "2023-05-24", "end_of_standard_support": "2024-07-24", "end_of_extended_support": "2025-07-24"}
+		]
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"1.27", "1.29", "1.33"}, version.Strings(cat.Versions()), "entries are sorted")
+	assert.Equal(t, []string{"1.27", "1.29"}, version.Strings(cat.Available(date("2025-05-01"))))
+	assert.Equal(t, []string{"1.29", "1.33"}, version.Strings(cat.Available(date("2025-08-01"))))
+
+	entry, ok := cat.Lookup(version.MustParse("1.29"))
+	require.True(t, ok)
+	assert.Equal(t, date("2025-03-23"), entry.EndOfStandardSupport)
+
+	_, ok = cat.Lookup(version.MustParse("1.30"))
+	assert.False(t, ok)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"bad json":       `{`,
+		"wrong schema":   `{"schema_version": 2, "updated": "2025-01-01", "versions": []}`,
+		"bad updated":    `{"schema_version": 1, "updated": "soon", "versions": []}`,
+		"bad version":    `{"schema_version": 1, "updated": "2025-01-01", "versions": [{"version": "1.3x"}]}`,
+		"bad date":       `{"schema_version": 1, "updated": "2025-01-01", "versions": [{"version": "1.31", "release_date": "2024-13-01", "end_of_standard_support": "2025-11-26", "end_of_extended_support": "2026-11-26"}]}`,
+		"dates reversed": `{"schema_version": 1, "updated": "2025-01-01", "versions": [{"version": "1.31", "release_date": "2024-09-26", "end_of_standard_support": "2026-11-26", "end_of_extended_support": "2025-11-26"}]}`,
+		"duplicate": `{"schema_version": 1, "updated": "2025-01-01", "versions": [
+			{"version": "1.31", "release_date": "2024-09-26", "end_of_standard_support": "2025-11-26", "end_of_extended_support": "2026-11-26"},
+			{"version": "1.31", "release_date": "2024-09-26", "end_of_standard_support": "2025-11-26", "end_of_extended_support": "2026-11-26"}]}`,
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
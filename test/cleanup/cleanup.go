@@ -0,0 +1,350 @@
+// Package cleanup finds and deletes AWS resources left behind by test runs,
+// selected by the Pipeline, RunID, and Environment tags every fixture applies.
+// It is the safety net behind `defer terraform.Destroy`: a run that timed out
+// or lost its runner can still be cleaned up from its tags alone.
+//
+// Resources are deleted in dependency order (node groups before clusters,
+// NAT gateways before their Elastic IPs, the VPC last), and each deletion that
+// AWS completes asynchronously is waited for before the next kind starts.
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+// Tag keys set by getPipelineTags in the integration tests.
+const (
+	TagPipeline    = "Pipeline"
+	TagRunID       = "RunID"
+	TagEnvironment = "Environment"
+	TagCreatedAt   = "CreatedAt"
+	TagExpiresAt   = "ExpiresAt"
+)
+
+// Kind is a type of resource the cleaner handles. Kinds are declared in the
+// order they are deleted.
+type Kind int
+
+const (
+	KindNodegroup Kind = iota
+	KindCluster
+	KindOIDCProvider
+	KindIAMRole
+	KindKMSKey
+	KindLogGroup
+	KindNATGateway
+	KindEIP
+	KindNetworkInterface
+	KindSecurityGroup
+	KindVPC
+)
+
+var kindNames = [...]string{
+	KindNodegroup:        "EKSNodegroup",
+	KindCluster:          "EKSCluster",
+	KindOIDCProvider:     "IAMOIDCProvider",
+	KindIAMRole:          "IAMRole",
+	KindKMSKey:           "KMSKey",
+	KindLogGroup:         "CloudWatchLogGroup",
+	KindNATGateway:       "NATGateway",
+	KindEIP:              "ElasticIP",
+	KindNetworkInterface: "NetworkInterface",
+	KindSecurityGroup:    "SecurityGroup",
+	KindVPC:              "VPC",
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+	return kindNames[k]
+}
+
+// Filter selects resources by tag. Empty fields match any value, but Pipeline
+// is required so a cleanup can never match a whole account.
+type Filter struct {
+	Pipeline    string
+	RunID       string
+	Environment string
+}
+
+// Validate checks that f is narrow enough to delete with.
+func (f Filter) Validate() error {
+	if f.Pipeline == "" {
+		return errors.New("cleanup filter needs a Pipeline tag")
+	}
+	return nil
+}
+
+// Match reports whether tags carry every non-empty field of f.
+func (f Filter) Match(tags map[string]string) bool {
+	for key, want := range f.tags() {
+		if tags[key] != want {
+			return false
+		}
+	}
+	return true
+}
+
+func (f Filter) String() string {
+	var parts []string
+	for _, key := range []string{TagPipeline, TagRunID, TagEnvironment} {
+		if v, ok := f.tags()[key]; ok {
+			parts = append(parts, key+"="+v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f Filter) tags() map[string]string {
+	tags := make(map[string]string, 3)
+	for key, v := range map[string]string{TagPipeline: f.Pipeline, TagRunID: f.RunID, TagEnvironment: f.Environment} {
+		if v != "" {
+			tags[key] = v
+		}
+	}
+	return tags
+}
+
+// Resource is one AWS resource matched by a Filter.
+type Resource struct {
+	Kind Kind
+	// ID is what the delete call takes: a name, ARN, or EC2 ID.
+	ID string
+	// Cluster is the owning cluster of a node group.
+	Cluster string
+	// ARN is empty for EC2 resources, whose describe calls don't return one.
+	ARN  string
+	Tags map[string]string
+}
+
+func (r Resource) String() string {
+	if r.Cluster != "" {
+		return fmt.Sprintf("%s %s/%s", r.Kind, r.Cluster, r.ID)
+	}
+	return fmt.Sprintf("%s %s", r.Kind, r.ID)
+}
+
+// Clients are the AWS APIs the cleaner calls.
+type Clients struct {
+	EKS  eksiface.EKSAPI
+	EC2  ec2iface.EC2API
+	IAM  iamiface.IAMAPI
+	KMS  kmsiface.KMSAPI
+	Logs cloudwatchlogsiface.CloudWatchLogsAPI
+}
+
+// NewClients creates clients from sess. A non-empty endpoint sends every
+// request there instead of AWS, e.g. to a fake.
+func NewClients(sess *session.Session, endpoint string) Clients {
+	var cfg []*aws.Config
+	if endpoint != "" {
+		cfg = append(cfg, &aws.Config{Endpoint: aws.String(endpoint)})
+	}
+	return Clients{
+		EKS:  eks.New(sess, cfg...),
+		EC2:  ec2.New(sess, cfg...),
+		IAM:  iam.New(sess, cfg...),
+		KMS:  kms.New(sess, cfg...),
+		Logs: cloudwatchlogs.New(sess, cfg...),
+	}
+}
+
+// Cleaner finds and deletes the resources matching Filter.
+type Cleaner struct {
+	Clients Clients
+	Filter  Filter
+	// Out receives a line per deletion. Nil discards them.
+	Out io.Writer
+	// WaiterOptions tune the waits for asynchronous deletions.
+	WaiterOptions []request.WaiterOption
+	// RoleNameContains, if set, skips IAM roles whose name doesn't contain
+	// it before fetching their tags, which otherwise costs a call per role
+	// in the account.
+	RoleNameContains string
+}
+
+// Find returns the matching resources in deletion order. A failure to list
+// one kind doesn't stop the others; the resources found are returned with the
+// joined errors.
+func (c *Cleaner) Find(ctx context.Context) ([]Resource, error) {
+	if err := c.Filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	var found []Resource
+	var errs []error
+	for _, find := range []func(context.Context) ([]Resource, error){
+		c.findEKS,
+		c.findIAM,
+		c.findKMSKeys,
+		c.findLogGroups,
+		c.findNetwork,
+	} {
+		resources, err := find(ctx)
+		found = append(found, resources...)
+		errs = append(errs, err)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Kind != found[j].Kind {
+			return found[i].Kind < found[j].Kind
+		}
+		return found[i].String() < found[j].String()
+	})
+	return found, errors.Join(errs...)
+}
+
+// Delete deletes resources kind by kind in dependency order. All deletions of
+// a kind are started before any is waited for. Failures are reported and
+// returned joined; deletion carries on so one stuck resource doesn't strand
+// the rest.
+func (c *Cleaner) Delete(ctx context.Context, resources []Resource) error {
+	byKind := make(map[Kind][]Resource)
+	for _, r := range resources {
+		byKind[r.Kind] = append(byKind[r.Kind], r)
+	}
+
+	var errs []error
+	for kind := range kindNames {
+		if Kind(kind) == KindSecurityGroup {
+			// Groups that reference each other can't be deleted until the
+			// rules doing so are gone.
+			errs = append(errs, c.revokeSecurityGroupRules(ctx, byKind[KindSecurityGroup]))
+		}
+		var started []Resource
+		for _, r := range byKind[Kind(kind)] {
+			c.logf("Deleting %s", r)
+			if err := ignoreNotFound(c.delete(ctx, r)); err != nil {
+				errs = append(errs, c.failed(r, err))
+				continue
+			}
+			started = append(started, r)
+		}
+		for _, r := range started {
+			if err := ignoreNotFound(c.wait(ctx, r)); err != nil {
+				errs = append(errs, c.failed(r, err))
+				continue
+			}
+			c.logf("Deleted %s", r)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Cleaner) delete(ctx context.Context, r Resource) error {
+	switch r.Kind {
+	case KindNodegroup:
+		return c.deleteNodegroup(ctx, r)
+	case KindCluster:
+		return c.deleteCluster(ctx, r)
+	case KindOIDCProvider:
+		return c.deleteOIDCProvider(ctx, r)
+	case KindIAMRole:
+		return c.deleteRole(ctx, r)
+	case KindKMSKey:
+		return c.deleteKMSKey(ctx, r)
+	case KindLogGroup:
+		return c.deleteLogGroup(ctx, r)
+	case KindNATGateway:
+		return c.deleteNATGateway(ctx, r)
+	case KindEIP:
+		return c.releaseEIP(ctx, r)
+	case KindNetworkInterface:
+		return c.deleteNetworkInterfaces(ctx, r.ID, []*ec2.Filter{
+			{Name: aws.String("network-interface-id"), Values: aws.StringSlice([]string{r.ID})},
+		})
+	case KindSecurityGroup:
+		_, err := c.Clients.EC2.DeleteSecurityGroupWithContext(ctx, &ec2.DeleteSecurityGroupInput{GroupId: aws.String(r.ID)})
+		return err
+	case KindVPC:
+		return c.deleteVPC(ctx, r)
+	}
+	return fmt.Errorf("unknown resource kind %s", r.Kind)
+}
+
+// wait blocks until an asynchronous deletion has finished.
+func (c *Cleaner) wait(ctx context.Context, r Resource) error {
+	switch r.Kind {
+	case KindNodegroup:
+		return c.Clients.EKS.WaitUntilNodegroupDeletedWithContext(ctx, &eks.DescribeNodegroupInput{
+			ClusterName:   aws.String(r.Cluster),
+			NodegroupName: aws.String(r.ID),
+		}, c.WaiterOptions...)
+	case KindCluster:
+		return c.Clients.EKS.WaitUntilClusterDeletedWithContext(ctx, &eks.DescribeClusterInput{
+			Name: aws.String(r.ID),
+		}, c.WaiterOptions...)
+	case KindNATGateway:
+		return c.Clients.EC2.WaitUntilNatGatewayDeletedWithContext(ctx, &ec2.DescribeNatGatewaysInput{
+			NatGatewayIds: aws.StringSlice([]string{r.ID}),
+		}, c.WaiterOptions...)
+	}
+	return nil
+}
+
+func (c *Cleaner) failed(r Resource, err error) error {
+	err = fmt.Errorf("failed to delete %s: %w", r, err)
+	c.logf("%v", err)
+	return err
+}
+
+func (c *Cleaner) logf(format string, args ...interface{}) {
+	if c.Out != nil {
+		fmt.Fprintf(c.Out, format+"\n", args...)
+	}
+}
+
+// Plan writes resources as a table, for dry runs.
+func Plan(w io.Writer, resources []Resource) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tID\tTAGS")
+	for _, r := range resources {
+		id := r.ID
+		if r.Cluster != "" {
+			id = r.Cluster + "/" + r.ID
+		}
+		var tags []string
+		for _, key := range []string{TagPipeline, TagRunID, TagEnvironment, TagExpiresAt} {
+			if v, ok := r.Tags[key]; ok {
+				tags = append(tags, key+"="+v)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Kind, id, strings.Join(tags, " "))
+	}
+	_ = tw.Flush()
+}
+
+// ignoreNotFound treats a resource that is already gone as deleted.
+func ignoreNotFound(err error) error {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		code := aerr.Code()
+		if code == "ResourceNotFoundException" || code == iam.ErrCodeNoSuchEntityException ||
+			code == kms.ErrCodeNotFoundException || strings.HasSuffix(code, ".NotFound") || code == "NatGatewayNotFound" {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,370 @@
+package cleanup
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apex/terratest-eks/fakeaws"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	runTags   = map[string]string{TagPipeline: "eks-cluster", TagRunID: "123", TagEnvironment: "ci"}
+	otherTags = map[string]string{TagPipeline: "eks-cluster", TagRunID: "456", TagEnvironment: "ci"}
+)
+
+type testAccount struct {
+	eks  *fakeaws.EKS
+	ec2  *fakeEC2
+	iam  *fakeIAM
+	kms  *fakeKMS
+	logs *fakeLogs
+	log  *callLog
+}
+
+// newTestAccount seeds one cluster, VPC, and supporting resources for run 123
+// and a cluster, role, and log group for run 456 that must be left alone.
+func newTestAccount(t *testing.T) (*testAccount, *Cleaner) {
+	t.Helper()
+
+	a := &testAccount{eks: fakeaws.NewEKS(), log: &callLog{}}
+	t.Cleanup(a.eks.Close)
+
+	a.eks.AddCluster(fakeaws.Cluster{Name: "test-eks-1-33-abc", Version: "1.33", Tags: runTags})
+	require.NoError(t, a.eks.AddNodegroup("test-eks-1-33-abc", fakeaws.Nodegroup{Name: "default", Tags: runTags}))
+	require.NoError(t, a.eks.AddNodegroup("test-eks-1-33-abc", fakeaws.Nodegroup{Name: "untagged"}))
+	a.eks.AddCluster(fakeaws.Cluster{Name: "test-eks-1-33-def", Version: "1.33", Tags: otherTags})
+	require.NoError(t, a.eks.AddNodegroup("test-eks-1-33-def", fakeaws.Nodegroup{Name: "default", Tags: otherTags}))
+
+	tags := tagsOf(TagPipeline, "eks-cluster", TagRunID, "123", TagEnvironment, "ci")
+	a.ec2 = &fakeEC2{
+		log: a.log,
+		natGateways: []*ec2.NatGateway{
+			{NatGatewayId: aws.String("nat-1"), State: aws.String(ec2.NatGatewayStateAvailable), Tags: tags,
+				NatGatewayAddresses: []*ec2.NatGatewayAddress{{AllocationId: aws.String("eipalloc-1")}}},
+			{NatGatewayId: aws.String("nat-old"), State: aws.String(ec2.NatGatewayStateDeleted), Tags: tags},
+		},
+		addresses: []*ec2.Address{
+			{AllocationId: aws.String("eipalloc-1"), AssociationId: aws.String("eipassoc-1"), Tags: tags},
+			{AllocationId: aws.String("eipalloc-other"), Tags: tagsOf(TagPipeline, "eks-cluster", TagRunID, "456")},
+		},
+		vpcs:    []*ec2.Vpc{{VpcId: aws.String("vpc-1"), Tags: tags}},
+		igws:    []*ec2.InternetGateway{{InternetGatewayId: aws.String("igw-1")}},
+		subnets: []*ec2.Subnet{{SubnetId: aws.String("subnet-1")}, {SubnetId: aws.String("subnet-2")}},
+		routeTables: []*ec2.RouteTable{
+			{RouteTableId: aws.String("rtb-main"), Associations: []*ec2.RouteTableAssociation{{Main: aws.Bool(true)}}},
+			{RouteTableId: aws.String("rtb-private")},
+		},
+		securityGroups: []*ec2.SecurityGroup{
+			{GroupId: aws.String("sg-default"), GroupName: aws.String("default"), VpcId: aws.String("vpc-1"), Tags: tags},
+			{GroupId: aws.String("sg-node"), GroupName: aws.String("node"), VpcId: aws.String("vpc-1"), Tags: tags,
+				IpPermissions: []*ec2.IpPermission{{IpProtocol: aws.String("-1"), UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-cluster")}}}}},
+			{GroupId: aws.String("sg-cluster"), GroupName: aws.String("cluster"), VpcId: aws.String("vpc-1"), Tags: tags},
+			// Created by EKS without the run's tags; it goes with the VPC.
+			{GroupId: aws.String("sg-eks"), GroupName: aws.String("eks-cluster-sg-test-eks-1-33-abc"), VpcId: aws.String("vpc-1"),
+				IpPermissions: []*ec2.IpPermission{{IpProtocol: aws.String("-1"), UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-eks")}}}}},
+		},
+	}
+
+	a.iam = &fakeIAM{
+		log: a.log,
+		roles: map[string]*fakeRole{
+			"test-eks-1-33-abc-cluster": {
+				role: &iam.Role{RoleName: aws.String("test-eks-1-33-abc-cluster"), Path: aws.String("/"),
+					Arn: aws.String("arn:aws:iam::123456789012:role/test-eks-1-33-abc-cluster")},
+				tags:     iamTagsOf(TagPipeline, "eks-cluster", TagRunID, "123"),
+				attached: []string{"arn:aws:iam::aws:policy/AmazonEKSClusterPolicy"},
+				inline:   []string{"encryption"},
+				profiles: []string{"nodes"},
+			},
+			"test-eks-1-33-def-cluster": {
+				role: &iam.Role{RoleName: aws.String("test-eks-1-33-def-cluster"), Path: aws.String("/")},
+				tags: iamTagsOf(TagPipeline, "eks-cluster", TagRunID, "456"),
+			},
+			"AWSServiceRoleForAmazonEKS": {
+				role: &iam.Role{RoleName: aws.String("AWSServiceRoleForAmazonEKS"), Path: aws.String("/aws-service-role/eks.amazonaws.com/")},
+				tags: iamTagsOf(TagPipeline, "eks-cluster", TagRunID, "123"),
+			},
+		},
+		providers: map[string][]*iam.Tag{
+			"arn:aws:iam::123456789012:oidc-provider/oidc.eks.us-west-1.amazonaws.com/id/ABC": iamTagsOf(TagPipeline, "eks-cluster", TagRunID, "123"),
+		},
+	}
+
+	a.kms = &fakeKMS{
+		log: a.log,
+		keys: map[string]*fakeKey{
+			"key-run": {
+				meta: &kms.KeyMetadata{KeyManager: aws.String(kms.KeyManagerTypeCustomer), KeyState: aws.String(kms.KeyStateEnabled),
+					Arn: aws.String("arn:aws:kms:us-west-1:123456789012:key/key-run")},
+				tags:    runTags,
+				aliases: []string{"alias/eks/test-eks-1-33-abc"},
+			},
+			"key-pending": {
+				meta: &kms.KeyMetadata{KeyManager: aws.String(kms.KeyManagerTypeCustomer), KeyState: aws.String(kms.KeyStatePendingDeletion)},
+				tags: runTags,
+			},
+			"key-aws": {
+				meta: &kms.KeyMetadata{KeyManager: aws.String(kms.KeyManagerTypeAws), KeyState: aws.String(kms.KeyStateEnabled)},
+			},
+		},
+	}
+
+	a.logs = &fakeLogs{
+		log: a.log,
+		groups: map[string]map[string]string{
+			"/aws/eks/test-eks-1-33-abc/cluster": runTags,
+			"/aws/eks/test-eks-1-33-def/cluster": otherTags,
+		},
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-west-1"),
+		Credentials: credentials.NewStaticCredentials("AKIDTEST", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})
+	require.NoError(t, err)
+
+	c := &Cleaner{
+		Clients: Clients{
+			EKS:  NewClients(sess, a.eks.URL()).EKS,
+			EC2:  a.ec2,
+			IAM:  a.iam,
+			KMS:  a.kms,
+			Logs: a.logs,
+		},
+		Filter:        Filter{Pipeline: "eks-cluster", RunID: "123"},
+		WaiterOptions: []request.WaiterOption{request.WithWaiterDelay(request.ConstantWaiterDelay(time.Millisecond))},
+	}
+	return a, c
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		tags   map[string]string
+		want   bool
+	}{
+		{"pipeline only", Filter{Pipeline: "eks-cluster"}, otherTags, true},
+		{"run matches", Filter{Pipeline: "eks-cluster", RunID: "123"}, runTags, true},
+		{"other run", Filter{Pipeline: "eks-cluster", RunID: "123"}, otherTags, false},
+		{"environment", Filter{Pipeline: "eks-cluster", Environment: "local"}, runTags, false},
+		{"untagged", Filter{Pipeline: "eks-cluster"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Match(tt.tags))
+		})
+	}
+
+	assert.Error(t, Filter{RunID: "123"}.Validate(), "a filter without Pipeline would match the whole account")
+	assert.Equal(t, "Pipeline=eks-cluster, RunID=123", Filter{Pipeline: "eks-cluster", RunID: "123"}.String())
+}
+
+func TestFind(t *testing.T) {
+	_, c := newTestAccount(t)
+
+	found, err := c.Find(context.Background())
+	require.NoError(t, err)
+
+	var got []string
+	for _, r := range found {
+		got = append(got, r.String())
+	}
+	assert.Equal(t, []string{
+		"EKSNodegroup test-eks-1-33-abc/default",
+		"EKSNodegroup test-eks-1-33-abc/untagged",
+		"EKSCluster test-eks-1-33-abc",
+		"IAMOIDCProvider arn:aws:iam::123456789012:oidc-provider/oidc.eks.us-west-1.amazonaws.com/id/ABC",
+		"IAMRole test-eks-1-33-abc-cluster",
+		"KMSKey key-run",
+		"CloudWatchLogGroup /aws/eks/test-eks-1-33-abc/cluster",
+		"NATGateway nat-1",
+		"ElasticIP eipalloc-1",
+		"SecurityGroup sg-cluster",
+		"SecurityGroup sg-node",
+		"VPC vpc-1",
+	}, got, "resources of run 123 in deletion order, without service-linked roles, AWS keys, keys pending deletion, or default security groups")
+
+	arns := make(map[string]string)
+	for _, r := range found {
+		arns[r.String()] = r.ARN
+	}
+	assert.Equal(t, "arn:aws:eks:us-east-1:123456789012:cluster/test-eks-1-33-abc", arns["EKSCluster test-eks-1-33-abc"])
+	assert.Equal(t, "arn:aws:eks:us-east-1:123456789012:nodegroup/test-eks-1-33-abc/default", arns["EKSNodegroup test-eks-1-33-abc/default"])
+	assert.Equal(t, "arn:aws:iam::123456789012:role/test-eks-1-33-abc-cluster", arns["IAMRole test-eks-1-33-abc-cluster"])
+	assert.Equal(t, "arn:aws:kms:us-west-1:123456789012:key/key-run", arns["KMSKey key-run"])
+	assert.Equal(t, "arn:aws:logs:us-west-1:123456789012:log-group:/aws/eks/test-eks-1-33-abc/cluster", arns["CloudWatchLogGroup /aws/eks/test-eks-1-33-abc/cluster"])
+	assert.Empty(t, arns["VPC vpc-1"], "EC2 doesn't return ARNs")
+
+	c.Filter = Filter{}
+	_, err = c.Find(context.Background())
+	assert.Error(t, err)
+}
+
+func TestDelete(t *testing.T) {
+	a, c := newTestAccount(t)
+	var out bytes.Buffer
+	c.Out = &out
+
+	found, err := c.Find(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, c.Delete(context.Background(), found))
+
+	assert.Equal(t, []string{"test-eks-1-33-def"}, a.eks.Clusters(), "the other run's cluster is kept")
+	assert.Contains(t, a.iam.roles, "test-eks-1-33-def-cluster")
+	assert.Contains(t, a.logs.groups, "/aws/eks/test-eks-1-33-def/cluster")
+	assert.Equal(t, kms.KeyStatePendingDeletion, aws.StringValue(a.kms.keys["key-run"].meta.KeyState))
+	assert.Empty(t, a.ec2.vpcs)
+	assert.Len(t, a.ec2.addresses, 1)
+
+	order := []string{
+		"DeleteOpenIDConnectProvider arn:aws:iam::123456789012:oidc-provider/oidc.eks.us-west-1.amazonaws.com/id/ABC",
+		"DeleteRole test-eks-1-33-abc-cluster",
+		"DeleteAlias alias/eks/test-eks-1-33-abc",
+		"ScheduleKeyDeletion key-run",
+		"DeleteLogGroup /aws/eks/test-eks-1-33-abc/cluster",
+		"DeleteNatGateway nat-1",
+		"WaitUntilNatGatewayDeleted nat-1",
+		"ReleaseAddress eipalloc-1",
+		"RevokeSecurityGroupIngress sg-node",
+		"DeleteSecurityGroup sg-cluster",
+		"DetachInternetGateway igw-1",
+		"DeleteSubnet subnet-1",
+		"DeleteRouteTable rtb-private",
+		"RevokeSecurityGroupIngress sg-eks",
+		"DeleteSecurityGroup sg-eks",
+		"DeleteVpc vpc-1",
+	}
+	for i := 1; i < len(order); i++ {
+		require.NotEqual(t, -1, a.log.index(order[i]), "missing call %q in %v", order[i], a.log.calls)
+		assert.Less(t, a.log.index(order[i-1]), a.log.index(order[i]), "%s before %s", order[i-1], order[i])
+	}
+	assert.Equal(t, -1, a.log.index("DeleteRouteTable rtb-main"), "the main route table goes with the VPC")
+	assert.Equal(t, -1, a.log.index("DeleteSecurityGroup sg-default"), "the default security group goes with the VPC")
+
+	assert.Contains(t, out.String(), "Deleted EKSCluster test-eks-1-33-abc")
+	assert.Contains(t, out.String(), "Deleted VPC vpc-1")
+}
+
+func TestDeleteVPCWithLeftoverENIs(t *testing.T) {
+	a, c := newTestAccount(t)
+	eni := func(id, subnet, status string, attachment *ec2.NetworkInterfaceAttachment) *ec2.NetworkInterface {
+		return &ec2.NetworkInterface{
+			NetworkInterfaceId: aws.String(id),
+			VpcId:              aws.String("vpc-1"),
+			SubnetId:           aws.String(subnet),
+			Status:             aws.String(status),
+			Attachment:         attachment,
+		}
+	}
+	elb := eni("eni-elb", "subnet-2", ec2.NetworkInterfaceStatusInUse, nil)
+	elb.RequesterManaged = aws.Bool(true)
+	a.ec2.enis = []*ec2.NetworkInterface{
+		// vpc-cni's warm pool on a node that is gone.
+		eni("eni-cni", "subnet-1", ec2.NetworkInterfaceStatusAvailable, nil),
+		// A secondary interface still attached to a stopped instance.
+		eni("eni-secondary", "subnet-2", ec2.NetworkInterfaceStatusInUse,
+			&ec2.NetworkInterfaceAttachment{AttachmentId: aws.String("eni-attach-1"), DeviceIndex: aws.Int64(1)}),
+		// A load balancer's interface, deleted with the load balancer.
+		elb,
+		eni("eni-other-vpc", "subnet-9", ec2.NetworkInterfaceStatusAvailable, nil),
+	}
+	a.ec2.enis[3].VpcId = aws.String("vpc-2")
+	a.ec2.ownedFor = map[string]int{"eni-elb": 2}
+
+	require.NoError(t, c.Delete(context.Background(), []Resource{{Kind: KindVPC, ID: "vpc-1"}}))
+	assert.Empty(t, a.ec2.vpcs)
+
+	order := []string{
+		"DeleteNetworkInterface eni-cni",
+		"DetachNetworkInterface eni-attach-1",
+		"DeleteNetworkInterface eni-secondary",
+		"DeleteSubnet subnet-1",
+	}
+	for i := 1; i < len(order); i++ {
+		require.NotEqual(t, -1, a.log.index(order[i]), "missing call %q in %v", order[i], a.log.calls)
+		assert.Less(t, a.log.index(order[i-1]), a.log.index(order[i]), "%s before %s", order[i-1], order[i])
+	}
+	assert.NotEqual(t, -1, a.log.index("DeleteSubnet subnet-2"), "subnets go once the load balancer's interface is gone")
+	assert.Equal(t, -1, a.log.index("DeleteNetworkInterface eni-elb"), "requester-managed interfaces are only waited for")
+	require.Len(t, a.ec2.enis, 1)
+	assert.Equal(t, "eni-other-vpc", aws.StringValue(a.ec2.enis[0].NetworkInterfaceId), "other VPCs' interfaces are kept")
+}
+
+func TestDeleteVPCTimesOutOnStuckENIs(t *testing.T) {
+	a, c := newTestAccount(t)
+	a.ec2.enis = []*ec2.NetworkInterface{{
+		NetworkInterfaceId: aws.String("eni-primary"),
+		VpcId:              aws.String("vpc-1"),
+		SubnetId:           aws.String("subnet-1"),
+		Status:             aws.String(ec2.NetworkInterfaceStatusInUse),
+		Attachment:         &ec2.NetworkInterfaceAttachment{AttachmentId: aws.String("eni-attach-0"), DeviceIndex: aws.Int64(0)},
+	}}
+	c.WaiterOptions = append(c.WaiterOptions, request.WithWaiterMaxAttempts(3))
+
+	err := c.Delete(context.Background(), []Resource{{Kind: KindVPC, ID: "vpc-1"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "network interfaces still in vpc-1: eni-primary")
+	assert.Equal(t, -1, a.log.index("DetachNetworkInterface eni-attach-0"), "a primary interface goes with its instance")
+	assert.Len(t, a.ec2.vpcs, 1)
+}
+
+func TestDeleteContinuesPastFailures(t *testing.T) {
+	a, c := newTestAccount(t)
+	a.eks.InjectError(fakeaws.OpDeleteNodegroup, 1, fakeaws.APIError{Status: 403, Code: "AccessDeniedException", Message: "denied"})
+
+	found, err := c.Find(context.Background())
+	require.NoError(t, err)
+	err = c.Delete(context.Background(), found)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to delete EKSNodegroup test-eks-1-33-abc/default")
+	assert.Contains(t, err.Error(), "failed to delete EKSCluster test-eks-1-33-abc", "the cluster can't go while a node group remains")
+	var aerr awserr.Error
+	assert.ErrorAs(t, err, &aerr)
+	assert.Empty(t, a.ec2.vpcs, "later kinds are still deleted")
+}
+
+func TestDeleteIgnoresResourcesAlreadyGone(t *testing.T) {
+	_, c := newTestAccount(t)
+
+	err := c.Delete(context.Background(), []Resource{
+		{Kind: KindCluster, ID: "gone"},
+		{Kind: KindIAMRole, ID: "gone"},
+		{Kind: KindEIP, ID: "eipalloc-gone"},
+	})
+	assert.NoError(t, err)
+}
+
+func TestPlan(t *testing.T) {
+	var out bytes.Buffer
+	Plan(&out, []Resource{
+		{Kind: KindNodegroup, ID: "default", Cluster: "demo", Tags: runTags},
+		{Kind: KindVPC, ID: "vpc-1", Tags: map[string]string{TagPipeline: "eks-cluster", "Name": "demo"}},
+	})
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	require.Len(t, lines, 3)
+	assert.Regexp(t, `^KIND\s+ID\s+TAGS$`, string(lines[0]))
+	assert.Regexp(t, `^EKSNodegroup\s+demo/default\s+Pipeline=eks-cluster RunID=123 Environment=ci$`, string(lines[1]))
+	assert.Regexp(t, `^VPC\s+vpc-1\s+Pipeline=eks-cluster$`, string(lines[2]))
+}
+
+func TestKindString(t *testing.T) {
+	assert.Equal(t, "EKSCluster", KindCluster.String())
+	assert.Equal(t, "Kind(42)", Kind(42).String())
+}
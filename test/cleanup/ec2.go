@@ -0,0 +1,308 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// findNetwork returns matching NAT gateways, Elastic IPs, network
+// interfaces, security groups, and VPCs. EC2 filters by tag server-side.
+func (c *Cleaner) findNetwork(ctx context.Context) ([]Resource, error) {
+	var found []Resource
+
+	err := c.Clients.EC2.DescribeNatGatewaysPagesWithContext(ctx, &ec2.DescribeNatGatewaysInput{
+		Filter: append(c.ec2Filters(), &ec2.Filter{
+			Name:   aws.String("state"),
+			Values: aws.StringSlice([]string{ec2.NatGatewayStatePending, ec2.NatGatewayStateAvailable, ec2.NatGatewayStateFailed}),
+		}),
+	}, func(page *ec2.DescribeNatGatewaysOutput, _ bool) bool {
+		for _, ngw := range page.NatGateways {
+			found = append(found, Resource{Kind: KindNATGateway, ID: aws.StringValue(ngw.NatGatewayId), Tags: ec2Tags(ngw.Tags)})
+		}
+		return true
+	})
+	if err != nil {
+		return found, err
+	}
+
+	addrs, err := c.Clients.EC2.DescribeAddressesWithContext(ctx, &ec2.DescribeAddressesInput{Filters: c.ec2Filters()})
+	if err != nil {
+		return found, err
+	}
+	for _, addr := range addrs.Addresses {
+		found = append(found, Resource{Kind: KindEIP, ID: aws.StringValue(addr.AllocationId), Tags: ec2Tags(addr.Tags)})
+	}
+
+	interfaces, err := c.FindInterfacesAndGroups(ctx, c.ec2Filters())
+	found = append(found, interfaces...)
+	if err != nil {
+		return found, err
+	}
+
+	err = c.Clients.EC2.DescribeVpcsPagesWithContext(ctx, &ec2.DescribeVpcsInput{Filters: c.ec2Filters()},
+		func(page *ec2.DescribeVpcsOutput, _ bool) bool {
+			for _, vpc := range page.Vpcs {
+				found = append(found, Resource{Kind: KindVPC, ID: aws.StringValue(vpc.VpcId), Tags: ec2Tags(vpc.Tags)})
+			}
+			return true
+		})
+	return found, err
+}
+
+// FindInterfacesAndGroups returns the network interfaces and security groups
+// matching filters, whatever the cleaner's Filter. Callers use it for those
+// that EKS and vpc-cni create without the run's tags, by VPC or by the tags
+// they do set. A VPC's default security group is skipped, since it goes with
+// the VPC, and so are NAT gateway interfaces, which go with the gateway.
+func (c *Cleaner) FindInterfacesAndGroups(ctx context.Context, filters []*ec2.Filter) ([]Resource, error) {
+	var found []Resource
+	err := c.Clients.EC2.DescribeNetworkInterfacesPagesWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{Filters: filters},
+		func(page *ec2.DescribeNetworkInterfacesOutput, _ bool) bool {
+			for _, eni := range page.NetworkInterfaces {
+				if aws.StringValue(eni.InterfaceType) != ec2.NetworkInterfaceTypeNatGateway {
+					found = append(found, Resource{Kind: KindNetworkInterface, ID: aws.StringValue(eni.NetworkInterfaceId), Tags: ec2Tags(eni.TagSet)})
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return found, err
+	}
+
+	groups, err := c.Clients.EC2.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{Filters: filters})
+	if err != nil {
+		return found, err
+	}
+	for _, sg := range groups.SecurityGroups {
+		if aws.StringValue(sg.GroupName) != "default" {
+			found = append(found, Resource{Kind: KindSecurityGroup, ID: aws.StringValue(sg.GroupId), Tags: ec2Tags(sg.Tags)})
+		}
+	}
+	return found, nil
+}
+
+func (c *Cleaner) ec2Filters() []*ec2.Filter {
+	var filters []*ec2.Filter
+	for key, v := range c.Filter.tags() {
+		filters = append(filters, &ec2.Filter{Name: aws.String("tag:" + key), Values: aws.StringSlice([]string{v})})
+	}
+	return filters
+}
+
+func ec2Tags(tags []*ec2.Tag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return m
+}
+
+func (c *Cleaner) deleteNATGateway(ctx context.Context, r Resource) error {
+	_, err := c.Clients.EC2.DeleteNatGatewayWithContext(ctx, &ec2.DeleteNatGatewayInput{NatGatewayId: aws.String(r.ID)})
+	return err
+}
+
+// releaseEIP disassociates the address if something other than a NAT gateway
+// still holds it, then releases it.
+func (c *Cleaner) releaseEIP(ctx context.Context, r Resource) error {
+	out, err := c.Clients.EC2.DescribeAddressesWithContext(ctx, &ec2.DescribeAddressesInput{
+		AllocationIds: aws.StringSlice([]string{r.ID}),
+	})
+	if err != nil {
+		return err
+	}
+	for _, addr := range out.Addresses {
+		if addr.AssociationId == nil {
+			continue
+		}
+		_, err := c.Clients.EC2.DisassociateAddressWithContext(ctx, &ec2.DisassociateAddressInput{AssociationId: addr.AssociationId})
+		if ignoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	_, err = c.Clients.EC2.ReleaseAddressWithContext(ctx, &ec2.ReleaseAddressInput{AllocationId: aws.String(r.ID)})
+	return err
+}
+
+// deleteVPC deletes what Terraform puts in a VPC — internet gateways,
+// subnets, route tables, and security groups — and then the VPC. Network
+// interfaces left behind by vpc-cni, EKS, or load balancers go before the
+// subnets, which can't be deleted while they hold any. Security group rules
+// are revoked first because the EKS cluster and node groups reference each
+// other.
+func (c *Cleaner) deleteVPC(ctx context.Context, r Resource) error {
+	vpcFilter := []*ec2.Filter{{Name: aws.String("vpc-id"), Values: aws.StringSlice([]string{r.ID})}}
+	var errs []error
+
+	igws, err := c.Clients.EC2.DescribeInternetGatewaysWithContext(ctx, &ec2.DescribeInternetGatewaysInput{
+		Filters: []*ec2.Filter{{Name: aws.String("attachment.vpc-id"), Values: aws.StringSlice([]string{r.ID})}},
+	})
+	errs = append(errs, err)
+	if err == nil {
+		for _, igw := range igws.InternetGateways {
+			_, err := c.Clients.EC2.DetachInternetGatewayWithContext(ctx, &ec2.DetachInternetGatewayInput{
+				InternetGatewayId: igw.InternetGatewayId,
+				VpcId:             aws.String(r.ID),
+			})
+			errs = append(errs, ignoreNotFound(err))
+			_, err = c.Clients.EC2.DeleteInternetGatewayWithContext(ctx, &ec2.DeleteInternetGatewayInput{InternetGatewayId: igw.InternetGatewayId})
+			errs = append(errs, ignoreNotFound(err))
+		}
+	}
+
+	errs = append(errs, c.deleteNetworkInterfaces(ctx, r.ID, vpcFilter))
+
+	subnets, err := c.Clients.EC2.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{Filters: vpcFilter})
+	errs = append(errs, err)
+	if err == nil {
+		for _, subnet := range subnets.Subnets {
+			_, err := c.Clients.EC2.DeleteSubnetWithContext(ctx, &ec2.DeleteSubnetInput{SubnetId: subnet.SubnetId})
+			errs = append(errs, ignoreNotFound(err))
+		}
+	}
+
+	tables, err := c.Clients.EC2.DescribeRouteTablesWithContext(ctx, &ec2.DescribeRouteTablesInput{Filters: vpcFilter})
+	errs = append(errs, err)
+	if err == nil {
+		for _, table := range tables.RouteTables {
+			if isMainRouteTable(table) {
+				continue
+			}
+			_, err := c.Clients.EC2.DeleteRouteTableWithContext(ctx, &ec2.DeleteRouteTableInput{RouteTableId: table.RouteTableId})
+			errs = append(errs, ignoreNotFound(err))
+		}
+	}
+
+	groups, err := c.Clients.EC2.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{Filters: vpcFilter})
+	errs = append(errs, err)
+	if err == nil {
+		var owned []*ec2.SecurityGroup
+		for _, sg := range groups.SecurityGroups {
+			if aws.StringValue(sg.GroupName) != "default" {
+				owned = append(owned, sg)
+			}
+		}
+		errs = append(errs, c.revokeRules(ctx, owned))
+		for _, sg := range owned {
+			_, err := c.Clients.EC2.DeleteSecurityGroupWithContext(ctx, &ec2.DeleteSecurityGroupInput{GroupId: sg.GroupId})
+			errs = append(errs, ignoreNotFound(err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	_, err = c.Clients.EC2.DeleteVpcWithContext(ctx, &ec2.DeleteVpcInput{VpcId: aws.String(r.ID)})
+	return err
+}
+
+// deleteNetworkInterfaces empties a VPC of network interfaces and waits until
+// they are gone. Available ones are deleted and secondary attachments force
+// detached, then deleted once available. Primary interfaces go with their
+// instance, and requester-managed ones with the service that owns them, so
+// those are only waited for. The wait follows c.WaiterOptions.
+func (c *Cleaner) deleteNetworkInterfaces(ctx context.Context, vpcID string, vpcFilter []*ec2.Filter) error {
+	w := request.Waiter{MaxAttempts: 40, Delay: request.ConstantWaiterDelay(15 * time.Second)}
+	w.ApplyOptions(c.WaiterOptions...)
+
+	detached := make(map[string]bool)
+	for attempt := 1; ; attempt++ {
+		var enis []*ec2.NetworkInterface
+		err := c.Clients.EC2.DescribeNetworkInterfacesPagesWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{Filters: vpcFilter},
+			func(page *ec2.DescribeNetworkInterfacesOutput, _ bool) bool {
+				enis = append(enis, page.NetworkInterfaces...)
+				return true
+			})
+		if err != nil {
+			return err
+		}
+		if len(enis) == 0 {
+			return nil
+		}
+
+		var ids []string
+		var errs []error
+		for _, eni := range enis {
+			id := aws.StringValue(eni.NetworkInterfaceId)
+			ids = append(ids, id)
+			switch {
+			case aws.BoolValue(eni.RequesterManaged):
+			case aws.StringValue(eni.Status) == ec2.NetworkInterfaceStatusAvailable:
+				_, err := c.Clients.EC2.DeleteNetworkInterfaceWithContext(ctx, &ec2.DeleteNetworkInterfaceInput{NetworkInterfaceId: eni.NetworkInterfaceId})
+				errs = append(errs, ignoreNotFound(err))
+			case eni.Attachment != nil && eni.Attachment.AttachmentId != nil && aws.Int64Value(eni.Attachment.DeviceIndex) != 0 && !detached[id]:
+				_, err := c.Clients.EC2.DetachNetworkInterfaceWithContext(ctx, &ec2.DetachNetworkInterfaceInput{
+					AttachmentId: eni.Attachment.AttachmentId,
+					Force:        aws.Bool(true),
+				})
+				errs = append(errs, ignoreNotFound(err))
+				detached[id] = true
+			}
+		}
+
+		if attempt >= w.MaxAttempts {
+			return errors.Join(append(errs, fmt.Errorf("network interfaces still in %s: %s", vpcID, strings.Join(ids, ", ")))...)
+		}
+		if err := aws.SleepWithContext(ctx, w.Delay(attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+// revokeSecurityGroupRules revokes every rule of the security groups in
+// resources.
+func (c *Cleaner) revokeSecurityGroupRules(ctx context.Context, resources []Resource) error {
+	if len(resources) == 0 {
+		return nil
+	}
+	ids := make([]string, len(resources))
+	for i, r := range resources {
+		ids[i] = r.ID
+	}
+	groups, err := c.Clients.EC2.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{{Name: aws.String("group-id"), Values: aws.StringSlice(ids)}},
+	})
+	if err != nil {
+		return err
+	}
+	return c.revokeRules(ctx, groups.SecurityGroups)
+}
+
+// revokeRules revokes every ingress and egress rule of groups.
+func (c *Cleaner) revokeRules(ctx context.Context, groups []*ec2.SecurityGroup) error {
+	var errs []error
+	for _, sg := range groups {
+		if len(sg.IpPermissions) > 0 {
+			_, err := c.Clients.EC2.RevokeSecurityGroupIngressWithContext(ctx, &ec2.RevokeSecurityGroupIngressInput{
+				GroupId:       sg.GroupId,
+				IpPermissions: sg.IpPermissions,
+			})
+			errs = append(errs, ignoreNotFound(err))
+		}
+		if len(sg.IpPermissionsEgress) > 0 {
+			_, err := c.Clients.EC2.RevokeSecurityGroupEgressWithContext(ctx, &ec2.RevokeSecurityGroupEgressInput{
+				GroupId:       sg.GroupId,
+				IpPermissions: sg.IpPermissionsEgress,
+			})
+			errs = append(errs, ignoreNotFound(err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isMainRouteTable(table *ec2.RouteTable) bool {
+	for _, assoc := range table.Associations {
+		if aws.BoolValue(assoc.Main) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package cleanup
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+)
+
+// findEKS returns matching clusters and node groups. Every node group of a
+// matching cluster is included, tagged or not, since the cluster can't be
+// deleted while it has any.
+func (c *Cleaner) findEKS(ctx context.Context) ([]Resource, error) {
+	var names []string
+	err := c.Clients.EKS.ListClustersPagesWithContext(ctx, &eks.ListClustersInput{},
+		func(page *eks.ListClustersOutput, _ bool) bool {
+			names = append(names, aws.StringValueSlice(page.Clusters)...)
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Resource
+	for _, name := range names {
+		out, err := c.Clients.EKS.DescribeClusterWithContext(ctx, &eks.DescribeClusterInput{Name: aws.String(name)})
+		if err != nil {
+			if ignoreNotFound(err) == nil {
+				continue
+			}
+			return found, err
+		}
+		tags := aws.StringValueMap(out.Cluster.Tags)
+		clusterMatches := c.Filter.Match(tags)
+
+		nodegroups, err := c.findNodegroups(ctx, name, clusterMatches)
+		found = append(found, nodegroups...)
+		if err != nil {
+			return found, err
+		}
+		if clusterMatches {
+			found = append(found, Resource{Kind: KindCluster, ID: name, ARN: aws.StringValue(out.Cluster.Arn), Tags: tags})
+		}
+	}
+	return found, nil
+}
+
+func (c *Cleaner) findNodegroups(ctx context.Context, cluster string, all bool) ([]Resource, error) {
+	var names []string
+	err := c.Clients.EKS.ListNodegroupsPagesWithContext(ctx, &eks.ListNodegroupsInput{ClusterName: aws.String(cluster)},
+		func(page *eks.ListNodegroupsOutput, _ bool) bool {
+			names = append(names, aws.StringValueSlice(page.Nodegroups)...)
+			return true
+		})
+	if err != nil {
+		return nil, ignoreNotFound(err)
+	}
+
+	var found []Resource
+	for _, name := range names {
+		out, err := c.Clients.EKS.DescribeNodegroupWithContext(ctx, &eks.DescribeNodegroupInput{
+			ClusterName:   aws.String(cluster),
+			NodegroupName: aws.String(name),
+		})
+		if err != nil {
+			if ignoreNotFound(err) == nil {
+				continue
+			}
+			return found, err
+		}
+		tags := aws.StringValueMap(out.Nodegroup.Tags)
+		if all || c.Filter.Match(tags) {
+			found = append(found, Resource{Kind: KindNodegroup, ID: name, Cluster: cluster, ARN: aws.StringValue(out.Nodegroup.NodegroupArn), Tags: tags})
+		}
+	}
+	return found, nil
+}
+
+func (c *Cleaner) deleteNodegroup(ctx context.Context, r Resource) error {
+	_, err := c.Clients.EKS.DeleteNodegroupWithContext(ctx, &eks.DeleteNodegroupInput{
+		ClusterName:   aws.String(r.Cluster),
+		NodegroupName: aws.String(r.ID),
+	})
+	return err
+}
+
+func (c *Cleaner) deleteCluster(ctx context.Context, r Resource) error {
+	_, err := c.Clients.EKS.DeleteClusterWithContext(ctx, &eks.DeleteClusterInput{Name: aws.String(r.ID)})
+	return err
+}
@@ -0,0 +1,544 @@
+package cleanup
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+// callLog records the mutating calls made to the in-memory fakes, in order.
+type callLog struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (l *callLog) add(call string, ids ...*string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls = append(l.calls, strings.TrimSpace(call+" "+strings.Join(aws.StringValueSlice(ids), " ")))
+}
+
+func (l *callLog) index(call string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, c := range l.calls {
+		if c == call {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchEC2Filters applies tag:<key> filters to tags and any other filter to fields.
+func matchEC2Filters(filters []*ec2.Filter, tags []*ec2.Tag, fields map[string]string) bool {
+	for _, f := range filters {
+		name := aws.StringValue(f.Name)
+		var got string
+		if key, ok := strings.CutPrefix(name, "tag:"); ok {
+			got = ec2Tags(tags)[key]
+		} else if v, ok := fields[name]; ok {
+			got = v
+		} else {
+			continue
+		}
+		matched := false
+		for _, want := range f.Values {
+			matched = matched || aws.StringValue(want) == got
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func tagsOf(kv ...string) []*ec2.Tag {
+	var tags []*ec2.Tag
+	for i := 0; i+1 < len(kv); i += 2 {
+		tags = append(tags, &ec2.Tag{Key: aws.String(kv[i]), Value: aws.String(kv[i+1])})
+	}
+	return tags
+}
+
+// fakeEC2 holds one VPC's worth of networking. Subnets, route tables,
+// security groups, internet gateways, and network interfaces all belong to
+// it. Subnets and the VPC can't be deleted while network interfaces remain.
+type fakeEC2 struct {
+	ec2iface.EC2API
+	log *callLog
+
+	natGateways    []*ec2.NatGateway
+	addresses      []*ec2.Address
+	vpcs           []*ec2.Vpc
+	igws           []*ec2.InternetGateway
+	subnets        []*ec2.Subnet
+	routeTables    []*ec2.RouteTable
+	securityGroups []*ec2.SecurityGroup
+	enis           []*ec2.NetworkInterface
+	// ownedFor is how many more describes a requester-managed interface
+	// survives before its owning service deletes it, by ID.
+	ownedFor map[string]int
+}
+
+// DescribeNetworkInterfacesPagesWithContext advances interfaces that are detaching to available and lets
+// owning services delete their interfaces.
+func (f *fakeEC2) DescribeNetworkInterfacesPagesWithContext(_ context.Context, in *ec2.DescribeNetworkInterfacesInput, fn func(*ec2.DescribeNetworkInterfacesOutput, bool) bool, _ ...request.Option) error {
+	out := &ec2.DescribeNetworkInterfacesOutput{}
+	var kept []*ec2.NetworkInterface
+	for _, eni := range f.enis {
+		id := aws.StringValue(eni.NetworkInterfaceId)
+		if aws.BoolValue(eni.RequesterManaged) {
+			if f.ownedFor[id] == 0 {
+				continue
+			}
+			f.ownedFor[id]--
+		}
+		kept = append(kept, eni)
+		if matchEC2Filters(in.Filters, eni.TagSet, map[string]string{"vpc-id": aws.StringValue(eni.VpcId)}) {
+			out.NetworkInterfaces = append(out.NetworkInterfaces, eni)
+		}
+	}
+	f.enis = kept
+	fn(out, true)
+
+	for _, eni := range f.enis {
+		if aws.StringValue(eni.Status) == "detaching" {
+			eni.Status = aws.String(ec2.NetworkInterfaceStatusAvailable)
+			eni.Attachment = nil
+		}
+	}
+	return nil
+}
+
+func (f *fakeEC2) DetachNetworkInterfaceWithContext(_ context.Context, in *ec2.DetachNetworkInterfaceInput, _ ...request.Option) (*ec2.DetachNetworkInterfaceOutput, error) {
+	f.log.add("DetachNetworkInterface", in.AttachmentId)
+	for _, eni := range f.enis {
+		if eni.Attachment != nil && aws.StringValue(eni.Attachment.AttachmentId) == aws.StringValue(in.AttachmentId) {
+			if aws.Int64Value(eni.Attachment.DeviceIndex) == 0 {
+				return nil, awserr.New("OperationNotPermitted", "cannot detach the primary network interface", nil)
+			}
+			eni.Status = aws.String("detaching")
+			return &ec2.DetachNetworkInterfaceOutput{}, nil
+		}
+	}
+	return nil, awserr.New("InvalidAttachmentID.NotFound", "not found", nil)
+}
+
+func (f *fakeEC2) DeleteNetworkInterfaceWithContext(_ context.Context, in *ec2.DeleteNetworkInterfaceInput, _ ...request.Option) (*ec2.DeleteNetworkInterfaceOutput, error) {
+	for i, eni := range f.enis {
+		if aws.StringValue(eni.NetworkInterfaceId) != aws.StringValue(in.NetworkInterfaceId) {
+			continue
+		}
+		if aws.StringValue(eni.Status) != ec2.NetworkInterfaceStatusAvailable {
+			return nil, awserr.New("InvalidNetworkInterface.InUse", "interface is in use", nil)
+		}
+		f.log.add("DeleteNetworkInterface", in.NetworkInterfaceId)
+		f.enis = append(f.enis[:i], f.enis[i+1:]...)
+		return &ec2.DeleteNetworkInterfaceOutput{}, nil
+	}
+	return nil, awserr.New("InvalidNetworkInterfaceID.NotFound", "not found", nil)
+}
+
+func (f *fakeEC2) DescribeNatGatewaysPagesWithContext(_ context.Context, in *ec2.DescribeNatGatewaysInput, fn func(*ec2.DescribeNatGatewaysOutput, bool) bool, _ ...request.Option) error {
+	out := &ec2.DescribeNatGatewaysOutput{}
+	for _, ngw := range f.natGateways {
+		if matchEC2Filters(in.Filter, ngw.Tags, map[string]string{"state": aws.StringValue(ngw.State)}) {
+			out.NatGateways = append(out.NatGateways, ngw)
+		}
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeEC2) DeleteNatGatewayWithContext(_ context.Context, in *ec2.DeleteNatGatewayInput, _ ...request.Option) (*ec2.DeleteNatGatewayOutput, error) {
+	f.log.add("DeleteNatGateway", in.NatGatewayId)
+	for _, ngw := range f.natGateways {
+		if aws.StringValue(ngw.NatGatewayId) == aws.StringValue(in.NatGatewayId) {
+			ngw.State = aws.String(ec2.NatGatewayStateDeleted)
+			for _, addr := range f.addresses {
+				if aws.StringValue(addr.AllocationId) == aws.StringValue(ngw.NatGatewayAddresses[0].AllocationId) {
+					addr.AssociationId = nil
+				}
+			}
+		}
+	}
+	return &ec2.DeleteNatGatewayOutput{}, nil
+}
+
+func (f *fakeEC2) WaitUntilNatGatewayDeletedWithContext(_ context.Context, in *ec2.DescribeNatGatewaysInput, _ ...request.WaiterOption) error {
+	f.log.add("WaitUntilNatGatewayDeleted", in.NatGatewayIds...)
+	return nil
+}
+
+func (f *fakeEC2) DescribeAddressesWithContext(_ context.Context, in *ec2.DescribeAddressesInput, _ ...request.Option) (*ec2.DescribeAddressesOutput, error) {
+	out := &ec2.DescribeAddressesOutput{}
+	for _, addr := range f.addresses {
+		if len(in.AllocationIds) > 0 && aws.StringValue(in.AllocationIds[0]) != aws.StringValue(addr.AllocationId) {
+			continue
+		}
+		if matchEC2Filters(in.Filters, addr.Tags, nil) {
+			out.Addresses = append(out.Addresses, addr)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeEC2) DisassociateAddressWithContext(_ context.Context, in *ec2.DisassociateAddressInput, _ ...request.Option) (*ec2.DisassociateAddressOutput, error) {
+	f.log.add("DisassociateAddress", in.AssociationId)
+	return &ec2.DisassociateAddressOutput{}, nil
+}
+
+func (f *fakeEC2) ReleaseAddressWithContext(_ context.Context, in *ec2.ReleaseAddressInput, _ ...request.Option) (*ec2.ReleaseAddressOutput, error) {
+	for i, addr := range f.addresses {
+		if aws.StringValue(addr.AllocationId) != aws.StringValue(in.AllocationId) {
+			continue
+		}
+		if addr.AssociationId != nil {
+			return nil, awserr.New("InvalidIPAddress.InUse", "address is in use", nil)
+		}
+		f.log.add("ReleaseAddress", in.AllocationId)
+		f.addresses = append(f.addresses[:i], f.addresses[i+1:]...)
+		return &ec2.ReleaseAddressOutput{}, nil
+	}
+	return nil, awserr.New("InvalidAllocationID.NotFound", "not found", nil)
+}
+
+func (f *fakeEC2) DescribeVpcsPagesWithContext(_ context.Context, in *ec2.DescribeVpcsInput, fn func(*ec2.DescribeVpcsOutput, bool) bool, _ ...request.Option) error {
+	out := &ec2.DescribeVpcsOutput{}
+	for _, vpc := range f.vpcs {
+		if matchEC2Filters(in.Filters, vpc.Tags, nil) {
+			out.Vpcs = append(out.Vpcs, vpc)
+		}
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeEC2) DescribeInternetGatewaysWithContext(context.Context, *ec2.DescribeInternetGatewaysInput, ...request.Option) (*ec2.DescribeInternetGatewaysOutput, error) {
+	return &ec2.DescribeInternetGatewaysOutput{InternetGateways: f.igws}, nil
+}
+
+func (f *fakeEC2) DetachInternetGatewayWithContext(_ context.Context, in *ec2.DetachInternetGatewayInput, _ ...request.Option) (*ec2.DetachInternetGatewayOutput, error) {
+	f.log.add("DetachInternetGateway", in.InternetGatewayId)
+	return &ec2.DetachInternetGatewayOutput{}, nil
+}
+
+func (f *fakeEC2) DeleteInternetGatewayWithContext(_ context.Context, in *ec2.DeleteInternetGatewayInput, _ ...request.Option) (*ec2.DeleteInternetGatewayOutput, error) {
+	f.log.add("DeleteInternetGateway", in.InternetGatewayId)
+	f.igws = nil
+	return &ec2.DeleteInternetGatewayOutput{}, nil
+}
+
+func (f *fakeEC2) DescribeSubnetsWithContext(context.Context, *ec2.DescribeSubnetsInput, ...request.Option) (*ec2.DescribeSubnetsOutput, error) {
+	return &ec2.DescribeSubnetsOutput{Subnets: f.subnets}, nil
+}
+
+func (f *fakeEC2) DeleteSubnetWithContext(_ context.Context, in *ec2.DeleteSubnetInput, _ ...request.Option) (*ec2.DeleteSubnetOutput, error) {
+	for _, eni := range f.enis {
+		if aws.StringValue(eni.SubnetId) == aws.StringValue(in.SubnetId) {
+			return nil, awserr.New("DependencyViolation", "subnet has dependencies and cannot be deleted", nil)
+		}
+	}
+	f.log.add("DeleteSubnet", in.SubnetId)
+	return &ec2.DeleteSubnetOutput{}, nil
+}
+
+func (f *fakeEC2) DescribeRouteTablesWithContext(context.Context, *ec2.DescribeRouteTablesInput, ...request.Option) (*ec2.DescribeRouteTablesOutput, error) {
+	return &ec2.DescribeRouteTablesOutput{RouteTables: f.routeTables}, nil
+}
+
+func (f *fakeEC2) DeleteRouteTableWithContext(_ context.Context, in *ec2.DeleteRouteTableInput, _ ...request.Option) (*ec2.DeleteRouteTableOutput, error) {
+	f.log.add("DeleteRouteTable", in.RouteTableId)
+	return &ec2.DeleteRouteTableOutput{}, nil
+}
+
+func (f *fakeEC2) DescribeSecurityGroupsWithContext(_ context.Context, in *ec2.DescribeSecurityGroupsInput, _ ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error) {
+	out := &ec2.DescribeSecurityGroupsOutput{}
+	for _, sg := range f.securityGroups {
+		fields := map[string]string{"vpc-id": aws.StringValue(sg.VpcId), "group-id": aws.StringValue(sg.GroupId)}
+		if matchEC2Filters(in.Filters, sg.Tags, fields) {
+			out.SecurityGroups = append(out.SecurityGroups, sg)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeEC2) RevokeSecurityGroupIngressWithContext(_ context.Context, in *ec2.RevokeSecurityGroupIngressInput, _ ...request.Option) (*ec2.RevokeSecurityGroupIngressOutput, error) {
+	f.log.add("RevokeSecurityGroupIngress", in.GroupId)
+	return &ec2.RevokeSecurityGroupIngressOutput{}, nil
+}
+
+func (f *fakeEC2) RevokeSecurityGroupEgressWithContext(_ context.Context, in *ec2.RevokeSecurityGroupEgressInput, _ ...request.Option) (*ec2.RevokeSecurityGroupEgressOutput, error) {
+	f.log.add("RevokeSecurityGroupEgress", in.GroupId)
+	return &ec2.RevokeSecurityGroupEgressOutput{}, nil
+}
+
+func (f *fakeEC2) DeleteSecurityGroupWithContext(_ context.Context, in *ec2.DeleteSecurityGroupInput, _ ...request.Option) (*ec2.DeleteSecurityGroupOutput, error) {
+	for i, sg := range f.securityGroups {
+		if aws.StringValue(sg.GroupId) == aws.StringValue(in.GroupId) {
+			f.log.add("DeleteSecurityGroup", in.GroupId)
+			f.securityGroups = append(f.securityGroups[:i], f.securityGroups[i+1:]...)
+			return &ec2.DeleteSecurityGroupOutput{}, nil
+		}
+	}
+	return nil, awserr.New("InvalidGroup.NotFound", "not found", nil)
+}
+
+func (f *fakeEC2) DeleteVpcWithContext(_ context.Context, in *ec2.DeleteVpcInput, _ ...request.Option) (*ec2.DeleteVpcOutput, error) {
+	for _, eni := range f.enis {
+		if aws.StringValue(eni.VpcId) == aws.StringValue(in.VpcId) {
+			return nil, awserr.New("DependencyViolation", "vpc has dependencies and cannot be deleted", nil)
+		}
+	}
+	f.log.add("DeleteVpc", in.VpcId)
+	for i, vpc := range f.vpcs {
+		if aws.StringValue(vpc.VpcId) == aws.StringValue(in.VpcId) {
+			f.vpcs = append(f.vpcs[:i], f.vpcs[i+1:]...)
+			break
+		}
+	}
+	return &ec2.DeleteVpcOutput{}, nil
+}
+
+type fakeRole struct {
+	role     *iam.Role
+	tags     []*iam.Tag
+	attached []string
+	inline   []string
+	profiles []string
+}
+
+// fakeIAM refuses to delete a role that still has policies or instance
+// profiles, as IAM does.
+type fakeIAM struct {
+	iamiface.IAMAPI
+	log *callLog
+
+	roles     map[string]*fakeRole
+	providers map[string][]*iam.Tag
+}
+
+func iamTagsOf(kv ...string) []*iam.Tag {
+	var tags []*iam.Tag
+	for i := 0; i+1 < len(kv); i += 2 {
+		tags = append(tags, &iam.Tag{Key: aws.String(kv[i]), Value: aws.String(kv[i+1])})
+	}
+	return tags
+}
+
+func (f *fakeIAM) ListRolesPagesWithContext(_ context.Context, _ *iam.ListRolesInput, fn func(*iam.ListRolesOutput, bool) bool, _ ...request.Option) error {
+	out := &iam.ListRolesOutput{}
+	for _, name := range sortedNames(f.roles) {
+		out.Roles = append(out.Roles, f.roles[name].role)
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeIAM) ListRoleTagsWithContext(_ context.Context, in *iam.ListRoleTagsInput, _ ...request.Option) (*iam.ListRoleTagsOutput, error) {
+	r, ok := f.roles[aws.StringValue(in.RoleName)]
+	if !ok {
+		return nil, awserr.New(iam.ErrCodeNoSuchEntityException, "no role", nil)
+	}
+	return &iam.ListRoleTagsOutput{Tags: r.tags}, nil
+}
+
+func (f *fakeIAM) ListAttachedRolePoliciesPagesWithContext(_ context.Context, in *iam.ListAttachedRolePoliciesInput, fn func(*iam.ListAttachedRolePoliciesOutput, bool) bool, _ ...request.Option) error {
+	if _, ok := f.roles[aws.StringValue(in.RoleName)]; !ok {
+		return awserr.New(iam.ErrCodeNoSuchEntityException, "no role", nil)
+	}
+	out := &iam.ListAttachedRolePoliciesOutput{}
+	for _, arn := range f.roles[aws.StringValue(in.RoleName)].attached {
+		out.AttachedPolicies = append(out.AttachedPolicies, &iam.AttachedPolicy{PolicyArn: aws.String(arn)})
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeIAM) DetachRolePolicyWithContext(_ context.Context, in *iam.DetachRolePolicyInput, _ ...request.Option) (*iam.DetachRolePolicyOutput, error) {
+	f.log.add("DetachRolePolicy", in.RoleName, in.PolicyArn)
+	r := f.roles[aws.StringValue(in.RoleName)]
+	r.attached = remove(r.attached, aws.StringValue(in.PolicyArn))
+	return &iam.DetachRolePolicyOutput{}, nil
+}
+
+func (f *fakeIAM) ListRolePoliciesPagesWithContext(_ context.Context, in *iam.ListRolePoliciesInput, fn func(*iam.ListRolePoliciesOutput, bool) bool, _ ...request.Option) error {
+	if _, ok := f.roles[aws.StringValue(in.RoleName)]; !ok {
+		return awserr.New(iam.ErrCodeNoSuchEntityException, "no role", nil)
+	}
+	fn(&iam.ListRolePoliciesOutput{PolicyNames: aws.StringSlice(f.roles[aws.StringValue(in.RoleName)].inline)}, true)
+	return nil
+}
+
+func (f *fakeIAM) DeleteRolePolicyWithContext(_ context.Context, in *iam.DeleteRolePolicyInput, _ ...request.Option) (*iam.DeleteRolePolicyOutput, error) {
+	f.log.add("DeleteRolePolicy", in.RoleName, in.PolicyName)
+	r := f.roles[aws.StringValue(in.RoleName)]
+	r.inline = remove(r.inline, aws.StringValue(in.PolicyName))
+	return &iam.DeleteRolePolicyOutput{}, nil
+}
+
+func (f *fakeIAM) ListInstanceProfilesForRolePagesWithContext(_ context.Context, in *iam.ListInstanceProfilesForRoleInput, fn func(*iam.ListInstanceProfilesForRoleOutput, bool) bool, _ ...request.Option) error {
+	if _, ok := f.roles[aws.StringValue(in.RoleName)]; !ok {
+		return awserr.New(iam.ErrCodeNoSuchEntityException, "no role", nil)
+	}
+	out := &iam.ListInstanceProfilesForRoleOutput{}
+	for _, name := range f.roles[aws.StringValue(in.RoleName)].profiles {
+		out.InstanceProfiles = append(out.InstanceProfiles, &iam.InstanceProfile{InstanceProfileName: aws.String(name)})
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeIAM) RemoveRoleFromInstanceProfileWithContext(_ context.Context, in *iam.RemoveRoleFromInstanceProfileInput, _ ...request.Option) (*iam.RemoveRoleFromInstanceProfileOutput, error) {
+	f.log.add("RemoveRoleFromInstanceProfile", in.RoleName, in.InstanceProfileName)
+	r := f.roles[aws.StringValue(in.RoleName)]
+	r.profiles = remove(r.profiles, aws.StringValue(in.InstanceProfileName))
+	return &iam.RemoveRoleFromInstanceProfileOutput{}, nil
+}
+
+func (f *fakeIAM) DeleteRoleWithContext(_ context.Context, in *iam.DeleteRoleInput, _ ...request.Option) (*iam.DeleteRoleOutput, error) {
+	name := aws.StringValue(in.RoleName)
+	r, ok := f.roles[name]
+	if !ok {
+		return nil, awserr.New(iam.ErrCodeNoSuchEntityException, "no role", nil)
+	}
+	if len(r.attached)+len(r.inline)+len(r.profiles) > 0 {
+		return nil, awserr.New(iam.ErrCodeDeleteConflictException, "role has policies", nil)
+	}
+	f.log.add("DeleteRole", in.RoleName)
+	delete(f.roles, name)
+	return &iam.DeleteRoleOutput{}, nil
+}
+
+func (f *fakeIAM) ListOpenIDConnectProvidersWithContext(context.Context, *iam.ListOpenIDConnectProvidersInput, ...request.Option) (*iam.ListOpenIDConnectProvidersOutput, error) {
+	out := &iam.ListOpenIDConnectProvidersOutput{}
+	for _, arn := range sortedNames(f.providers) {
+		out.OpenIDConnectProviderList = append(out.OpenIDConnectProviderList, &iam.OpenIDConnectProviderListEntry{Arn: aws.String(arn)})
+	}
+	return out, nil
+}
+
+func (f *fakeIAM) ListOpenIDConnectProviderTagsWithContext(_ context.Context, in *iam.ListOpenIDConnectProviderTagsInput, _ ...request.Option) (*iam.ListOpenIDConnectProviderTagsOutput, error) {
+	return &iam.ListOpenIDConnectProviderTagsOutput{Tags: f.providers[aws.StringValue(in.OpenIDConnectProviderArn)]}, nil
+}
+
+func (f *fakeIAM) DeleteOpenIDConnectProviderWithContext(_ context.Context, in *iam.DeleteOpenIDConnectProviderInput, _ ...request.Option) (*iam.DeleteOpenIDConnectProviderOutput, error) {
+	f.log.add("DeleteOpenIDConnectProvider", in.OpenIDConnectProviderArn)
+	delete(f.providers, aws.StringValue(in.OpenIDConnectProviderArn))
+	return &iam.DeleteOpenIDConnectProviderOutput{}, nil
+}
+
+type fakeKey struct {
+	meta    *kms.KeyMetadata
+	tags    map[string]string
+	aliases []string
+}
+
+type fakeKMS struct {
+	kmsiface.KMSAPI
+	log *callLog
+
+	keys map[string]*fakeKey
+}
+
+func (f *fakeKMS) ListKeysPagesWithContext(_ context.Context, _ *kms.ListKeysInput, fn func(*kms.ListKeysOutput, bool) bool, _ ...request.Option) error {
+	out := &kms.ListKeysOutput{}
+	for _, id := range sortedNames(f.keys) {
+		out.Keys = append(out.Keys, &kms.KeyListEntry{KeyId: aws.String(id)})
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeKMS) DescribeKeyWithContext(_ context.Context, in *kms.DescribeKeyInput, _ ...request.Option) (*kms.DescribeKeyOutput, error) {
+	return &kms.DescribeKeyOutput{KeyMetadata: f.keys[aws.StringValue(in.KeyId)].meta}, nil
+}
+
+func (f *fakeKMS) ListResourceTagsWithContext(_ context.Context, in *kms.ListResourceTagsInput, _ ...request.Option) (*kms.ListResourceTagsOutput, error) {
+	out := &kms.ListResourceTagsOutput{}
+	for k, v := range f.keys[aws.StringValue(in.KeyId)].tags {
+		out.Tags = append(out.Tags, &kms.Tag{TagKey: aws.String(k), TagValue: aws.String(v)})
+	}
+	return out, nil
+}
+
+func (f *fakeKMS) ListAliasesPagesWithContext(_ context.Context, in *kms.ListAliasesInput, fn func(*kms.ListAliasesOutput, bool) bool, _ ...request.Option) error {
+	out := &kms.ListAliasesOutput{}
+	for _, alias := range f.keys[aws.StringValue(in.KeyId)].aliases {
+		out.Aliases = append(out.Aliases, &kms.AliasListEntry{AliasName: aws.String(alias)})
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeKMS) DeleteAliasWithContext(_ context.Context, in *kms.DeleteAliasInput, _ ...request.Option) (*kms.DeleteAliasOutput, error) {
+	f.log.add("DeleteAlias", in.AliasName)
+	return &kms.DeleteAliasOutput{}, nil
+}
+
+func (f *fakeKMS) ScheduleKeyDeletionWithContext(_ context.Context, in *kms.ScheduleKeyDeletionInput, _ ...request.Option) (*kms.ScheduleKeyDeletionOutput, error) {
+	f.log.add("ScheduleKeyDeletion", in.KeyId)
+	f.keys[aws.StringValue(in.KeyId)].meta.KeyState = aws.String(kms.KeyStatePendingDeletion)
+	return &kms.ScheduleKeyDeletionOutput{}, nil
+}
+
+type fakeLogs struct {
+	cloudwatchlogsiface.CloudWatchLogsAPI
+	log *callLog
+
+	groups map[string]map[string]string
+}
+
+func (f *fakeLogs) DescribeLogGroupsPagesWithContext(_ context.Context, _ *cloudwatchlogs.DescribeLogGroupsInput, fn func(*cloudwatchlogs.DescribeLogGroupsOutput, bool) bool, _ ...request.Option) error {
+	out := &cloudwatchlogs.DescribeLogGroupsOutput{}
+	for _, name := range sortedNames(f.groups) {
+		out.LogGroups = append(out.LogGroups, &cloudwatchlogs.LogGroup{
+			LogGroupName: aws.String(name),
+			Arn:          aws.String("arn:aws:logs:us-west-1:123456789012:log-group:" + name + ":*"),
+		})
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeLogs) ListTagsLogGroupWithContext(_ context.Context, in *cloudwatchlogs.ListTagsLogGroupInput, _ ...request.Option) (*cloudwatchlogs.ListTagsLogGroupOutput, error) {
+	return &cloudwatchlogs.ListTagsLogGroupOutput{Tags: aws.StringMap(f.groups[aws.StringValue(in.LogGroupName)])}, nil
+}
+
+func (f *fakeLogs) DeleteLogGroupWithContext(_ context.Context, in *cloudwatchlogs.DeleteLogGroupInput, _ ...request.Option) (*cloudwatchlogs.DeleteLogGroupOutput, error) {
+	f.log.add("DeleteLogGroup", in.LogGroupName)
+	delete(f.groups, aws.StringValue(in.LogGroupName))
+	return &cloudwatchlogs.DeleteLogGroupOutput{}, nil
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func remove(list []string, v string) []string {
+	var out []string
+	for _, s := range list {
+		if s != v {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,131 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+// findIAM returns matching IAM roles and OIDC providers. IAM is global, so
+// these match across regions; RunID keeps a run from touching another's.
+// Service-linked roles are skipped since only their service can delete them,
+// and so are roles not named for c.RoleNameContains.
+func (c *Cleaner) findIAM(ctx context.Context) ([]Resource, error) {
+	var roles []*iam.Role
+	err := c.Clients.IAM.ListRolesPagesWithContext(ctx, &iam.ListRolesInput{},
+		func(page *iam.ListRolesOutput, _ bool) bool {
+			for _, role := range page.Roles {
+				if !strings.Contains(aws.StringValue(role.RoleName), c.RoleNameContains) {
+					continue
+				}
+				if !strings.HasPrefix(aws.StringValue(role.Path), "/aws-service-role/") {
+					roles = append(roles, role)
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Resource
+	for _, role := range roles {
+		name := aws.StringValue(role.RoleName)
+		out, err := c.Clients.IAM.ListRoleTagsWithContext(ctx, &iam.ListRoleTagsInput{RoleName: aws.String(name)})
+		if err != nil {
+			if ignoreNotFound(err) == nil {
+				continue
+			}
+			return found, err
+		}
+		if tags := iamTags(out.Tags); c.Filter.Match(tags) {
+			found = append(found, Resource{Kind: KindIAMRole, ID: name, ARN: aws.StringValue(role.Arn), Tags: tags})
+		}
+	}
+
+	providers, err := c.Clients.IAM.ListOpenIDConnectProvidersWithContext(ctx, &iam.ListOpenIDConnectProvidersInput{})
+	if err != nil {
+		return found, err
+	}
+	for _, p := range providers.OpenIDConnectProviderList {
+		arn := aws.StringValue(p.Arn)
+		out, err := c.Clients.IAM.ListOpenIDConnectProviderTagsWithContext(ctx, &iam.ListOpenIDConnectProviderTagsInput{
+			OpenIDConnectProviderArn: aws.String(arn),
+		})
+		if err != nil {
+			if ignoreNotFound(err) == nil {
+				continue
+			}
+			return found, err
+		}
+		if tags := iamTags(out.Tags); c.Filter.Match(tags) {
+			found = append(found, Resource{Kind: KindOIDCProvider, ID: arn, ARN: arn, Tags: tags})
+		}
+	}
+	return found, nil
+}
+
+func iamTags(tags []*iam.Tag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return m
+}
+
+func (c *Cleaner) deleteOIDCProvider(ctx context.Context, r Resource) error {
+	_, err := c.Clients.IAM.DeleteOpenIDConnectProviderWithContext(ctx, &iam.DeleteOpenIDConnectProviderInput{
+		OpenIDConnectProviderArn: aws.String(r.ID),
+	})
+	return err
+}
+
+// deleteRole detaches the role's managed policies, deletes its inline
+// policies, and removes it from instance profiles, which IAM requires before
+// the role itself can go.
+func (c *Cleaner) deleteRole(ctx context.Context, r Resource) error {
+	name := aws.String(r.ID)
+	var errs []error
+
+	err := c.Clients.IAM.ListAttachedRolePoliciesPagesWithContext(ctx, &iam.ListAttachedRolePoliciesInput{RoleName: name},
+		func(page *iam.ListAttachedRolePoliciesOutput, _ bool) bool {
+			for _, p := range page.AttachedPolicies {
+				_, err := c.Clients.IAM.DetachRolePolicyWithContext(ctx, &iam.DetachRolePolicyInput{RoleName: name, PolicyArn: p.PolicyArn})
+				errs = append(errs, ignoreNotFound(err))
+			}
+			return true
+		})
+	errs = append(errs, ignoreNotFound(err))
+
+	err = c.Clients.IAM.ListRolePoliciesPagesWithContext(ctx, &iam.ListRolePoliciesInput{RoleName: name},
+		func(page *iam.ListRolePoliciesOutput, _ bool) bool {
+			for _, policy := range page.PolicyNames {
+				_, err := c.Clients.IAM.DeleteRolePolicyWithContext(ctx, &iam.DeleteRolePolicyInput{RoleName: name, PolicyName: policy})
+				errs = append(errs, ignoreNotFound(err))
+			}
+			return true
+		})
+	errs = append(errs, ignoreNotFound(err))
+
+	err = c.Clients.IAM.ListInstanceProfilesForRolePagesWithContext(ctx, &iam.ListInstanceProfilesForRoleInput{RoleName: name},
+		func(page *iam.ListInstanceProfilesForRoleOutput, _ bool) bool {
+			for _, p := range page.InstanceProfiles {
+				_, err := c.Clients.IAM.RemoveRoleFromInstanceProfileWithContext(ctx, &iam.RemoveRoleFromInstanceProfileInput{
+					RoleName:            name,
+					InstanceProfileName: p.InstanceProfileName,
+				})
+				errs = append(errs, ignoreNotFound(err))
+			}
+			return true
+		})
+	errs = append(errs, ignoreNotFound(err))
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	_, err = c.Clients.IAM.DeleteRoleWithContext(ctx, &iam.DeleteRoleInput{RoleName: name})
+	return err
+}
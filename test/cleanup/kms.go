@@ -0,0 +1,81 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// kmsDeletionWindowDays is the shortest waiting period KMS allows.
+const kmsDeletionWindowDays = 7
+
+// findKMSKeys returns matching customer managed keys. Keys already pending
+// deletion are skipped; they can't be deleted any sooner.
+func (c *Cleaner) findKMSKeys(ctx context.Context) ([]Resource, error) {
+	var ids []string
+	err := c.Clients.KMS.ListKeysPagesWithContext(ctx, &kms.ListKeysInput{},
+		func(page *kms.ListKeysOutput, _ bool) bool {
+			for _, k := range page.Keys {
+				ids = append(ids, aws.StringValue(k.KeyId))
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Resource
+	for _, id := range ids {
+		desc, err := c.Clients.KMS.DescribeKeyWithContext(ctx, &kms.DescribeKeyInput{KeyId: aws.String(id)})
+		if err != nil {
+			if ignoreNotFound(err) == nil {
+				continue
+			}
+			return found, err
+		}
+		meta := desc.KeyMetadata
+		if aws.StringValue(meta.KeyManager) != kms.KeyManagerTypeCustomer {
+			continue
+		}
+		switch aws.StringValue(meta.KeyState) {
+		case kms.KeyStatePendingDeletion, kms.KeyStatePendingReplicaDeletion:
+			continue
+		}
+
+		out, err := c.Clients.KMS.ListResourceTagsWithContext(ctx, &kms.ListResourceTagsInput{KeyId: aws.String(id)})
+		if err != nil {
+			return found, err
+		}
+		tags := make(map[string]string, len(out.Tags))
+		for _, tag := range out.Tags {
+			tags[aws.StringValue(tag.TagKey)] = aws.StringValue(tag.TagValue)
+		}
+		if c.Filter.Match(tags) {
+			found = append(found, Resource{Kind: KindKMSKey, ID: id, ARN: aws.StringValue(meta.Arn), Tags: tags})
+		}
+	}
+	return found, nil
+}
+
+// deleteKMSKey deletes the key's aliases, so a rerun can create them again,
+// and schedules the key for deletion.
+func (c *Cleaner) deleteKMSKey(ctx context.Context, r Resource) error {
+	var errs []error
+	err := c.Clients.KMS.ListAliasesPagesWithContext(ctx, &kms.ListAliasesInput{KeyId: aws.String(r.ID)},
+		func(page *kms.ListAliasesOutput, _ bool) bool {
+			for _, alias := range page.Aliases {
+				_, err := c.Clients.KMS.DeleteAliasWithContext(ctx, &kms.DeleteAliasInput{AliasName: alias.AliasName})
+				errs = append(errs, ignoreNotFound(err))
+			}
+			return true
+		})
+	errs = append(errs, err)
+
+	_, err = c.Clients.KMS.ScheduleKeyDeletionWithContext(ctx, &kms.ScheduleKeyDeletionInput{
+		KeyId:               aws.String(r.ID),
+		PendingWindowInDays: aws.Int64(kmsDeletionWindowDays),
+	})
+	return errors.Join(append(errs, err)...)
+}
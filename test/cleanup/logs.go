@@ -0,0 +1,46 @@
+package cleanup
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+// findLogGroups returns matching CloudWatch log groups, such as the EKS
+// control plane's /aws/eks/<cluster>/cluster.
+func (c *Cleaner) findLogGroups(ctx context.Context) ([]Resource, error) {
+	var groups []*cloudwatchlogs.LogGroup
+	err := c.Clients.Logs.DescribeLogGroupsPagesWithContext(ctx, &cloudwatchlogs.DescribeLogGroupsInput{},
+		func(page *cloudwatchlogs.DescribeLogGroupsOutput, _ bool) bool {
+			groups = append(groups, page.LogGroups...)
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Resource
+	for _, g := range groups {
+		name := aws.StringValue(g.LogGroupName)
+		out, err := c.Clients.Logs.ListTagsLogGroupWithContext(ctx, &cloudwatchlogs.ListTagsLogGroupInput{LogGroupName: aws.String(name)})
+		if err != nil {
+			if ignoreNotFound(err) == nil {
+				continue
+			}
+			return found, err
+		}
+		if tags := aws.StringValueMap(out.Tags); c.Filter.Match(tags) {
+			// DescribeLogGroups ARNs end in ":*", which matches the group's streams.
+			arn := strings.TrimSuffix(aws.StringValue(g.Arn), ":*")
+			found = append(found, Resource{Kind: KindLogGroup, ID: name, ARN: arn, Tags: tags})
+		}
+	}
+	return found, nil
+}
+
+func (c *Cleaner) deleteLogGroup(ctx context.Context, r Resource) error {
+	_, err := c.Clients.Logs.DeleteLogGroupWithContext(ctx, &cloudwatchlogs.DeleteLogGroupInput{LogGroupName: aws.String(r.ID)})
+	return err
+}
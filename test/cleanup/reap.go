@@ -0,0 +1,52 @@
+package cleanup
+
+import (
+	"fmt"
+	"time"
+)
+
+// Expiry selects resources that have outlived their run, by the CreatedAt and
+// ExpiresAt tags getPipelineTags stamps. Resources with neither tag never
+// expire: nothing says their run is over.
+type Expiry struct {
+	Now time.Time
+	// MaxAge also expires resources created more than MaxAge ago, whatever
+	// their ExpiresAt. Zero relies on ExpiresAt alone.
+	MaxAge time.Duration
+}
+
+// Expired reports whether tags say the resource has outlived its run, and why.
+// Unparseable timestamps are treated as absent.
+func (e Expiry) Expired(tags map[string]string) (bool, string) {
+	if expires, err := time.Parse(time.RFC3339, tags[TagExpiresAt]); err == nil && e.Now.After(expires) {
+		return true, fmt.Sprintf("expired %s ago", e.Now.Sub(expires).Round(time.Minute))
+	}
+	if e.MaxAge > 0 {
+		if created, err := time.Parse(time.RFC3339, tags[TagCreatedAt]); err == nil && e.Now.Sub(created) > e.MaxAge {
+			return true, fmt.Sprintf("created %s ago", e.Now.Sub(created).Round(time.Minute))
+		}
+	}
+	return false, ""
+}
+
+// Select returns the expired resources, plus every node group of an expired
+// cluster since the cluster can't be deleted before them.
+func (e Expiry) Select(resources []Resource) []Resource {
+	clusters := make(map[string]bool)
+	for _, r := range resources {
+		if r.Kind == KindCluster {
+			if expired, _ := e.Expired(r.Tags); expired {
+				clusters[r.ID] = true
+			}
+		}
+	}
+
+	var selected []Resource
+	for _, r := range resources {
+		expired, _ := e.Expired(r.Tags)
+		if expired || (r.Kind == KindNodegroup && clusters[r.Cluster]) {
+			selected = append(selected, r)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,77 @@
+package cleanup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apex/terratest-eks/fakeaws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stamped(created, expires time.Time) map[string]string {
+	return map[string]string{
+		TagPipeline:  "eks-cluster",
+		TagCreatedAt: created.Format(time.RFC3339),
+		TagExpiresAt: expires.Format(time.RFC3339),
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		maxAge time.Duration
+		tags   map[string]string
+		want   bool
+		reason string
+	}{
+		{"in flight", 0, stamped(now.Add(-time.Hour), now.Add(5*time.Hour)), false, ""},
+		{"ttl expired", 0, stamped(now.Add(-7*time.Hour), now.Add(-time.Hour)), true, "expired 1h0m0s ago"},
+		{"older than max age", 2 * time.Hour, stamped(now.Add(-3*time.Hour), now.Add(3*time.Hour)), true, "created 3h0m0s ago"},
+		{"younger than max age", 2 * time.Hour, stamped(now.Add(-time.Hour), now.Add(5*time.Hour)), false, ""},
+		{"untagged", time.Hour, map[string]string{TagPipeline: "eks-cluster"}, false, ""},
+		{"unparseable", time.Hour, map[string]string{TagCreatedAt: "yesterday", TagExpiresAt: "soon"}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := Expiry{Now: now, MaxAge: tt.maxAge}.Expired(tt.tags)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.reason, reason)
+		})
+	}
+}
+
+func TestSelectExpired(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	old := stamped(now.Add(-8*time.Hour), now.Add(-2*time.Hour))
+	fresh := stamped(now.Add(-time.Hour), now.Add(5*time.Hour))
+
+	a, c := newTestAccount(t)
+	a.eks.AddCluster(fakeaws.Cluster{Name: "orphan", Version: "1.32", Tags: old})
+	require.NoError(t, a.eks.AddNodegroup("orphan", fakeaws.Nodegroup{Name: "default"}))
+	a.eks.AddCluster(fakeaws.Cluster{Name: "in-flight", Version: "1.33", Tags: fresh})
+	require.NoError(t, a.eks.AddNodegroup("in-flight", fakeaws.Nodegroup{Name: "default", Tags: fresh}))
+	a.logs.groups["/aws/eks/orphan/cluster"] = old
+
+	c.Filter = Filter{Pipeline: "eks-cluster"}
+	found, err := c.Find(context.Background())
+	require.NoError(t, err)
+
+	selected := Expiry{Now: now}.Select(found)
+	var got []string
+	for _, r := range selected {
+		got = append(got, r.String())
+	}
+	assert.Equal(t, []string{
+		"EKSNodegroup orphan/default",
+		"EKSCluster orphan",
+		"CloudWatchLogGroup /aws/eks/orphan/cluster",
+	}, got, "the untagged node group goes with its expired cluster; untagged and in-flight resources stay")
+
+	require.NoError(t, c.Delete(context.Background(), selected))
+	assert.Equal(t, []string{"in-flight", "test-eks-1-33-abc", "test-eks-1-33-def"}, a.eks.Clusters())
+}
@@ -0,0 +1,166 @@
+// Command cleanup deletes AWS resources left behind by test runs, selected by
+// their Pipeline, RunID, and Environment tags. It is the CI safety net behind
+// the Go tests' deferred terraform destroy.
+//
+//	cleanup run     [--region r] [--project p] [--run-id id] [--environment e] [--force]
+//	cleanup project [--region r] [--project p] [--environment e] [--force]
+//	cleanup reap    [--region r] [--project p] [--environment e] [--max-age d] [--force]
+//
+// "run" cleans one run's resources. It resolves --project and --run-id from
+// flags, then PROJECT_NAME and PIPELINE_RUN_ID, then .task/run-metadata.env.
+// "project" cleans every run of a project. "reap" cleans only resources past
+// their ExpiresAt tag, or created more than --max-age ago, so it is safe to
+// schedule while runs are in flight.
+//
+// Dry run by default: matching resources are listed, not deleted. Add --force
+// to delete them. --endpoint-url (or AWS_ENDPOINT_URL) sends every request to
+// another endpoint, such as a fake.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/apex/terratest-eks/cleanup"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 || (args[0] != "run" && args[0] != "project" && args[0] != "reap") {
+		return fmt.Errorf("usage: cleanup <run|project|reap> [options]")
+	}
+	subcommand := args[0]
+
+	flags := flag.NewFlagSet("cleanup "+subcommand, flag.ContinueOnError)
+	region := flags.String("region", os.Getenv("AWS_REGION"), "AWS region")
+	project := flags.String("project", "", "Pipeline tag (default PROJECT_NAME)")
+	runID := flags.String("run-id", "", "RunID tag (default PIPELINE_RUN_ID; run only)")
+	environment := flags.String("environment", "", "Environment tag, e.g. ci or local")
+	metadata := flags.String("metadata", ".task/run-metadata.env", "run metadata file to fall back to")
+	endpoint := flags.String("endpoint-url", os.Getenv("AWS_ENDPOINT_URL"), "send AWS requests to this endpoint")
+	maxAge := flags.Duration("max-age", 0, "reap: also delete resources created longer ago than this")
+	force := flags.Bool("force", false, "delete resources instead of listing them")
+	timeout := flags.Duration("timeout", 45*time.Minute, "give up after this long")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if *region == "" {
+		return fmt.Errorf("--region required")
+	}
+	if *project == "" {
+		*project = os.Getenv("PROJECT_NAME")
+	}
+
+	filter := cleanup.Filter{Pipeline: *project, Environment: *environment}
+	if subcommand == "run" {
+		if *runID == "" {
+			*runID = os.Getenv("PIPELINE_RUN_ID")
+		}
+		if *project == "" || *runID == "" {
+			meta, err := readMetadata(*metadata)
+			if err != nil {
+				return err
+			}
+			if *project == "" {
+				*project = meta["PIPELINE_TAG"]
+			}
+			if *runID == "" {
+				*runID = meta["PIPELINE_RUN_ID"]
+			}
+		}
+		if *runID == "" {
+			return fmt.Errorf("could not resolve run-id (use --run-id, PIPELINE_RUN_ID env, or %s)", *metadata)
+		}
+		filter.Pipeline, filter.RunID = *project, *runID
+	}
+	if filter.Pipeline == "" {
+		return fmt.Errorf("could not resolve project (use --project, PROJECT_NAME env, or %s)", *metadata)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            aws.Config{Region: aws.String(*region)},
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	ctx, cancel = context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	c := &cleanup.Cleaner{
+		Clients: cleanup.NewClients(sess, *endpoint),
+		Filter:  filter,
+		Out:     os.Stdout,
+	}
+
+	fmt.Printf("=== Cleanup %s: %s in %s ===\n", subcommand, filter, *region)
+	resources, findErr := c.Find(ctx)
+	if findErr != nil {
+		fmt.Fprintln(os.Stderr, "Warning: some resources could not be listed:", findErr)
+	}
+	if subcommand == "reap" {
+		resources = cleanup.Expiry{Now: time.Now(), MaxAge: *maxAge}.Select(resources)
+	}
+	if len(resources) == 0 {
+		fmt.Println("No matching resources.")
+		return findErr
+	}
+	cleanup.Plan(os.Stdout, resources)
+
+	if !*force {
+		fmt.Printf("\nDRY RUN: %d resources. To delete: task cleanup-%s -- force\n", len(resources), subcommand)
+		return findErr
+	}
+
+	fmt.Println()
+	if err := c.Delete(ctx, resources); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d resources.\n", len(resources))
+	return findErr
+}
+
+// readMetadata parses KEY=VALUE lines from a run metadata file. A missing file
+// is empty.
+func readMetadata(path string) (map[string]string, error) {
+	meta := make(map[string]string)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return meta, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if ok {
+			meta[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return meta, scanner.Err()
+}
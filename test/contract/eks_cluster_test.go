@@ -0,0 +1,78 @@
+// Plan-only contract tests for modules/eks-cluster, run through examples/eks.
+// They need Terraform and registry access but no AWS account, so they run in
+// the unit stage and catch module regressions before an apply is paid for.
+package contract
+
+import (
+	"testing"
+
+	"github.com/apex/terratest-eks/planjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Planned addresses in examples/eks. Renaming one makes Terraform replace the
+// resource on the next apply, so they are part of the contract too.
+const (
+	clusterAddr  = "module.eks.module.eks.aws_eks_cluster.this[0]"
+	logGroupAddr = "module.eks.module.eks.aws_cloudwatch_log_group.this[0]"
+	kmsKeyAddr   = "module.eks.module.eks.module.kms.aws_kms_key.this[0]"
+)
+
+// openCIDR is the public endpoint allow-list entry that must never reach prod.
+const openCIDR = "0.0.0.0/0"
+
+func TestEKSClusterPlanContract(t *testing.T) {
+	fixture := newPlanFixture(t)
+
+	t.Run("KMS key is created when create_kms_key", func(t *testing.T) {
+		plan := fixture.plan(t, nil)
+		planjson.AssertResourceExists(t, plan, kmsKeyAddr)
+		planjson.AssertAttr(t, plan, clusterAddr, "encryption_config.0.resources", []string{"secrets"})
+		assert.True(t, plan.Resource(clusterAddr).Attr("encryption_config.0.provider.0.key_arn").Unknown(),
+			"The cluster should use the key created in the same apply")
+
+		plan = fixture.plan(t, map[string]interface{}{"create_kms_key": false})
+		planjson.AssertResourceCount(t, plan, "aws_kms_key", 0)
+		planjson.AssertAttr(t, plan, clusterAddr, "encryption_config", []string{})
+	})
+
+	t.Run("log types equal cluster_enabled_log_types", func(t *testing.T) {
+		for _, logTypes := range [][]string{
+			{"api", "audit", "authenticator"},
+			{"audit"},
+			{"api", "audit", "authenticator", "controllerManager", "scheduler"},
+		} {
+			plan := fixture.plan(t, map[string]interface{}{"cluster_enabled_log_types": logTypes})
+			require.NotNil(t, plan.Resource(clusterAddr))
+			assert.Equal(t, logTypes, plan.Resource(clusterAddr).Attr("enabled_cluster_log_types").Strings())
+		}
+	})
+
+	t.Run("log group keeps cloudwatch_log_group_retention_in_days", func(t *testing.T) {
+		plan := fixture.plan(t, map[string]interface{}{"cloudwatch_log_group_retention_in_days": 30})
+		planjson.AssertAttr(t, plan, logGroupAddr, "name", "/aws/eks/contract/cluster")
+		planjson.AssertAttr(t, plan, logGroupAddr, "retention_in_days", 30)
+	})
+
+	t.Run("endpoint access follows the cluster_endpoint inputs", func(t *testing.T) {
+		plan := fixture.plan(t, nil)
+		planjson.AssertAttr(t, plan, clusterAddr, "vpc_config.0.endpoint_public_access", true)
+		planjson.AssertAttr(t, plan, clusterAddr, "vpc_config.0.endpoint_private_access", true)
+
+		plan = fixture.plan(t, map[string]interface{}{"cluster_endpoint_public_access": false})
+		planjson.AssertAttr(t, plan, clusterAddr, "vpc_config.0.endpoint_public_access", false)
+		planjson.AssertAttr(t, plan, clusterAddr, "vpc_config.0.endpoint_private_access", true)
+	})
+
+	t.Run("public CIDRs are never 0.0.0.0/0 in prod", func(t *testing.T) {
+		plan := fixture.plan(t, nil, "testdata/prod.tfvars")
+		require.True(t, planjson.AssertAttr(t, plan, clusterAddr, "tags.Environment", "prod"))
+		planjson.AssertAttrNotContains(t, plan, clusterAddr, "vpc_config.0.public_access_cidrs", openCIDR)
+
+		// The default non-prod input is open, so the check above is not
+		// passing vacuously.
+		plan = fixture.plan(t, nil)
+		planjson.AssertAttrContains(t, plan, clusterAddr, "vpc_config.0.public_access_cidrs", openCIDR)
+	})
+}
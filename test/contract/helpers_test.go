@@ -0,0 +1,140 @@
+// Shared helpers for the plan-only contract tests: a copy of examples/eks
+// whose AWS provider talks to fakeaws, planned with terraform plan -out and
+// read back with terraform show -json into a planjson.Document.
+package contract
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/apex/terratest-eks/fakeaws"
+	"github.com/apex/terratest-eks/planjson"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// contractClusterVersion is the version every plan uses. The fake EKS serves
+// addon versions for it only.
+const contractClusterVersion = "1.33"
+
+// fakeProviderOverride is merged into examples/eks's provider "aws" block.
+// EKS and STS are the only APIs the provider calls while planning a new
+// cluster (addon versions and the caller identity); everything else is
+// computed locally.
+const fakeProviderOverride = `provider "aws" {
+  access_key                  = "AKIDTEST"
+  secret_key                  = "secret"
+  skip_credentials_validation = true
+  skip_requesting_account_id  = true
+  skip_metadata_api_check     = true
+  skip_region_validation      = true
+
+  endpoints {
+    eks = %q
+    sts = %q
+  }
+}
+`
+
+// planFixture is an initialised copy of examples/eks. Init downloads the
+// modules and providers once; each plan after that takes seconds.
+type planFixture struct {
+	dir string
+}
+
+// newPlanFixture copies examples/eks, points it at fake EKS and STS servers
+// that live until t ends, and runs terraform init. It skips t when no
+// Terraform binary is installed.
+func newPlanFixture(t *testing.T) *planFixture {
+	t.Helper()
+
+	if _, err := exec.LookPath(terraform.DefaultExecutable); err != nil {
+		t.Skipf("Contract tests need %s on PATH: %v", terraform.DefaultExecutable, err)
+	}
+
+	eks := fakeaws.NewEKS()
+	t.Cleanup(eks.Close)
+	for _, addon := range []string{"coredns", "kube-proxy", "vpc-cni"} {
+		eks.AddAddonVersion(addon, "v1.0.0-eksbuild.1", contractClusterVersion)
+	}
+	sts := fakeaws.NewSTS()
+	t.Cleanup(sts.Close)
+
+	dir := copyExample(t, "eks")
+	override := fmt.Sprintf(fakeProviderOverride, eks.URL(), sts.URL())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fake_aws_override.tf"), []byte(override), 0o644))
+
+	terraform.Init(t, &terraform.Options{TerraformDir: dir, NoColor: true})
+	return &planFixture{dir: dir}
+}
+
+// plan plans the fixture with vars layered over a minimal valid input and
+// returns the parsed plan. varFiles are applied before vars.
+func (f *planFixture) plan(t *testing.T, vars map[string]interface{}, varFiles ...string) *planjson.Document {
+	t.Helper()
+
+	all := map[string]interface{}{
+		"cluster_name":       "contract",
+		"cluster_version":    contractClusterVersion,
+		"vpc_id":             "vpc-0123456789abcdef0",
+		"private_subnet_ids": []string{"subnet-0123456789abcdef0", "subnet-0fedcba9876543210"},
+	}
+	for k, v := range vars {
+		all[k] = v
+	}
+
+	absFiles := make([]string, len(varFiles))
+	for i, file := range varFiles {
+		abs, err := filepath.Abs(file)
+		require.NoError(t, err)
+		absFiles[i] = abs
+	}
+
+	opts := &terraform.Options{
+		TerraformDir: f.dir,
+		Vars:         all,
+		VarFiles:     absFiles,
+		PlanFilePath: filepath.Join(t.TempDir(), "plan.out"),
+		NoColor:      true,
+		EnvVars:      map[string]string{"AWS_EC2_METADATA_DISABLED": "true"},
+	}
+	terraform.Plan(t, opts)
+
+	plan, err := planjson.Parse([]byte(terraform.Show(t, opts)))
+	require.NoError(t, err)
+	return plan
+}
+
+// copyExample copies examples/<name>'s .tf files to a temp dir, making
+// relative module sources absolute as the integration helpers do.
+func copyExample(t *testing.T, name string) string {
+	t.Helper()
+
+	src, err := filepath.Abs(filepath.Join("..", "..", "examples", name))
+	require.NoError(t, err)
+	dst := t.TempDir()
+
+	entries, err := os.ReadDir(src)
+	require.NoError(t, err, "Failed to read %s", src)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tf") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(src, entry.Name()))
+		require.NoError(t, err)
+		content = relativeSource.ReplaceAllFunc(content, func(match []byte) []byte {
+			parts := relativeSource.FindSubmatch(match)
+			return []byte(fmt.Sprintf("%s%s%s", parts[1], filepath.Join(src, string(parts[2])), parts[3]))
+		})
+		require.NoError(t, os.WriteFile(filepath.Join(dst, entry.Name()), content, 0o644))
+	}
+	return dst
+}
+
+// relativeSource matches a module source relative to its fixture.
+var relativeSource = regexp.MustCompile(`(source\s*=\s*")(\.\.[^"]*)(")`)
@@ -0,0 +1,238 @@
+// Package cost estimates what a test run spends on AWS from the checked-in
+// prices.json and how long each resource was deployed, so runs can report
+// their cost and fail when they exceed a budget.
+//
+// Estimates use on-demand hourly rates prorated to the second and ignore
+// storage, data transfer, and minimum billing increments. Update prices.json
+// when AWS changes a rate or a fixture starts using a new instance type, and
+// bump "updated".
+package cost
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// SchemaVersion is the prices.json format this package understands.
+const SchemaVersion = 1
+
+const dateLayout = "2006-01-02"
+
+//go:embed prices.json
+var embedded []byte
+
+// RegionPrices are the hourly USD rates in one region.
+type RegionPrices struct {
+	EKSClusterHour         float64            `json:"eks_cluster_hour"`
+	EKSExtendedClusterHour float64            `json:"eks_extended_support_cluster_hour"`
+	NATGatewayHour         float64            `json:"nat_gateway_hour"`
+	InstanceHour           map[string]float64 `json:"ec2_instance_hour"`
+}
+
+// PriceTable is the parsed price table.
+type PriceTable struct {
+	Updated  time.Time
+	Currency string
+	Source   string
+	Regions  map[string]RegionPrices
+}
+
+type priceFile struct {
+	SchemaVersion int                     `json:"schema_version"`
+	Updated       string                  `json:"updated"`
+	Currency      string                  `json:"currency"`
+	Source        string                  `json:"source"`
+	Regions       map[string]RegionPrices `json:"regions"`
+}
+
+// Load returns the price table embedded from prices.json.
+func Load() (*PriceTable, error) {
+	return Parse(embedded)
+}
+
+// LoadFile reads a price table from disk.
+func LoadFile(path string) (*PriceTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price table: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse decodes and checks a price table: the schema version must match,
+// prices must be in USD, and every rate must be positive.
+func Parse(data []byte) (*PriceTable, error) {
+	var raw priceFile
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode price table: %w", err)
+	}
+
+	if raw.SchemaVersion != SchemaVersion {
+		return nil, fmt.Errorf("unsupported price table schema_version %d, want %d", raw.SchemaVersion, SchemaVersion)
+	}
+	if raw.Currency != "USD" {
+		return nil, fmt.Errorf("unsupported price table currency %q, want USD", raw.Currency)
+	}
+
+	updated, err := time.Parse(dateLayout, raw.Updated)
+	if err != nil {
+		return nil, fmt.Errorf("invalid price table updated date: %w", err)
+	}
+
+	for name, r := range raw.Regions {
+		rates := map[string]float64{
+			"eks_cluster_hour":                  r.EKSClusterHour,
+			"eks_extended_support_cluster_hour": r.EKSExtendedClusterHour,
+			"nat_gateway_hour":                  r.NATGatewayHour,
+		}
+		for instanceType, rate := range r.InstanceHour {
+			rates["ec2_instance_hour."+instanceType] = rate
+		}
+		for field, rate := range rates {
+			if rate <= 0 {
+				return nil, fmt.Errorf("price table region %s has non-positive %s", name, field)
+			}
+		}
+	}
+
+	return &PriceTable{
+		Updated:  updated,
+		Currency: raw.Currency,
+		Source:   raw.Source,
+		Regions:  raw.Regions,
+	}, nil
+}
+
+// Region returns the rates for region.
+func (p *PriceTable) Region(region string) (RegionPrices, error) {
+	r, ok := p.Regions[region]
+	if !ok {
+		return RegionPrices{}, fmt.Errorf("no prices for region %s (updated %s)", region, p.Updated.Format(dateLayout))
+	}
+	return r, nil
+}
+
+// Line is one priced resource: Count units at HourlyUSD each for Duration.
+// Cell names the matrix cell it belongs to, e.g. "EKS_1_33" or "VPC".
+type Line struct {
+	Cell      string
+	Item      string
+	Count     int
+	HourlyUSD float64
+	Duration  time.Duration
+}
+
+// Cost is the estimated USD cost of the line.
+func (l Line) Cost() float64 {
+	return float64(l.Count) * l.HourlyUSD * l.Duration.Hours()
+}
+
+// ClusterLines prices one EKS deployment: the control plane (at the extended
+// support rate if extended) and the node instances. A node group may launch any
+// of its instanceTypes, so the most expensive one is used.
+func (r RegionPrices) ClusterLines(cell string, extended bool, instanceTypes []string, nodes int, d time.Duration) ([]Line, error) {
+	controlPlane := Line{Cell: cell, Item: "EKS control plane", Count: 1, HourlyUSD: r.EKSClusterHour, Duration: d}
+	if extended {
+		controlPlane.Item = "EKS control plane (extended support)"
+		controlPlane.HourlyUSD = r.EKSExtendedClusterHour
+	}
+
+	if len(instanceTypes) == 0 {
+		return nil, fmt.Errorf("%s: no node instance types", cell)
+	}
+	var nodeType string
+	var nodeRate float64
+	for _, it := range instanceTypes {
+		rate, ok := r.InstanceHour[it]
+		if !ok {
+			return nil, fmt.Errorf("%s: no price for instance type %s", cell, it)
+		}
+		if rate > nodeRate {
+			nodeType, nodeRate = it, rate
+		}
+	}
+
+	return []Line{
+		controlPlane,
+		{Cell: cell, Item: "EC2 " + nodeType, Count: nodes, HourlyUSD: nodeRate, Duration: d},
+	}, nil
+}
+
+// VPCLines prices the shared VPC's NAT gateways.
+func (r RegionPrices) VPCLines(cell string, natGateways int, d time.Duration) []Line {
+	return []Line{{Cell: cell, Item: "NAT gateway", Count: natGateways, HourlyUSD: r.NATGatewayHour, Duration: d}}
+}
+
+// Ledger accumulates priced lines from parallel subtests.
+type Ledger struct {
+	mu    sync.Mutex
+	lines []Line
+}
+
+// Add records lines.
+func (l *Ledger) Add(lines ...Line) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, lines...)
+}
+
+// Total is the estimated cost of every line.
+func (l *Ledger) Total() float64 {
+	return l.sum(func(Line) bool { return true })
+}
+
+// CellTotal is the estimated cost of the lines for cell.
+func (l *Ledger) CellTotal(cell string) float64 {
+	return l.sum(func(line Line) bool { return line.Cell == cell })
+}
+
+func (l *Ledger) sum(keep func(Line) bool) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var total float64
+	for _, line := range l.lines {
+		if keep(line) {
+			total += line.Cost()
+		}
+	}
+	return total
+}
+
+// Summary renders the lines as a table sorted by cell, with a total.
+func (l *Ledger) Summary() string {
+	l.mu.Lock()
+	lines := append([]Line(nil), l.lines...)
+	l.mu.Unlock()
+
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Cell < lines[j].Cell })
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Cell\tItem\tCount\tHours\t$/hour\tCost\t")
+	var total float64
+	for _, line := range lines {
+		total += line.Cost()
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.2f\t%.4f\t$%.4f\t\n",
+			line.Cell, line.Item, line.Count, line.Duration.Hours(), line.HourlyUSD, line.Cost())
+	}
+	fmt.Fprintf(w, "Total\t\t\t\t\t$%.4f\t\n", total)
+	_ = w.Flush()
+	return b.String()
+}
+
+// CheckBudget returns an error if total exceeds budget. A budget of zero or
+// less means no limit.
+func CheckBudget(total, budget float64) error {
+	if budget > 0 && total > budget {
+		return fmt.Errorf("estimated cost $%.2f exceeds budget $%.2f", total, budget)
+	}
+	return nil
+}
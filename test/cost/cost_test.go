@@ -0,0 +1,96 @@
+package cost
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	prices, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "USD", prices.Currency)
+
+	// The matrix defaults to us-west-1 with t3.small nodes.
+	r, err := prices.Region("us-west-1")
+	require.NoError(t, err)
+	assert.Contains(t, r.InstanceHour, "t3.small")
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"bad json":       `{`,
+		"wrong schema":   `{"schema_version": 2, "updated": "2025-01-01", "currency": "USD"}`,
+		"wrong currency": `{"schema_version": 1, "updated": "2025-01-01", "currency": "EUR"}`,
+		"bad date":       `{"schema_version": 1, "updated": "January", "currency": "USD"}`,
+		"zero rate":      `{"schema_version": 1, "updated": "2025-01-01", "currency": "USD", "regions": {"x": {"eks_cluster_hour": 0.1, "eks_extended_support_cluster_hour": 0.6}}}`,
+		"negative ec2":   `{"schema_version": 1, "updated": "2025-01-01", "currency": "USD", "regions": {"x": {"eks_cluster_hour": 0.1, "eks_extended_support_cluster_hour": 0.6, "nat_gateway_hour": 0.045, "ec2_instance_hour": {"t3.small": -1}}}}`,
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func testPrices() RegionPrices {
+	return RegionPrices{
+		EKSClusterHour:         0.10,
+		EKSExtendedClusterHour: 0.60,
+		NATGatewayHour:         0.045,
+		InstanceHour:           map[string]float64{"t3.small": 0.02, "t3.medium": 0.04},
+	}
+}
+
+func TestClusterLines(t *testing.T) {
+	r := testPrices()
+
+	lines, err := r.ClusterLines("EKS_1_33", false, []string{"t3.small", "t3.medium"}, 2, 30*time.Minute)
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "EKS control plane", lines[0].Item)
+	assert.InDelta(t, 0.05, lines[0].Cost(), 1e-9)
+	assert.Equal(t, "EC2 t3.medium", lines[1].Item, "the most expensive instance type is used")
+	assert.InDelta(t, 0.04, lines[1].Cost(), 1e-9)
+
+	lines, err = r.ClusterLines("EKS_1_29", true, []string{"t3.small"}, 1, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "EKS control plane (extended support)", lines[0].Item)
+	assert.InDelta(t, 0.60, lines[0].Cost(), 1e-9)
+
+	_, err = r.ClusterLines("EKS_1_33", false, []string{"p5.48xlarge"}, 1, time.Hour)
+	assert.ErrorContains(t, err, "no price for instance type p5.48xlarge")
+
+	_, err = r.ClusterLines("EKS_1_33", false, nil, 1, time.Hour)
+	assert.Error(t, err)
+}
+
+func TestLedger(t *testing.T) {
+	r := testPrices()
+	var ledger Ledger
+
+	lines, err := r.ClusterLines("EKS_1_33", false, []string{"t3.small"}, 1, time.Hour)
+	require.NoError(t, err)
+	ledger.Add(lines...)
+	ledger.Add(r.VPCLines("VPC", 1, 2*time.Hour)...)
+
+	assert.InDelta(t, 0.12, ledger.CellTotal("EKS_1_33"), 1e-9)
+	assert.InDelta(t, 0.09, ledger.CellTotal("VPC"), 1e-9)
+	assert.InDelta(t, 0.21, ledger.Total(), 1e-9)
+
+	summary := ledger.Summary()
+	assert.Contains(t, summary, "NAT gateway")
+	assert.Contains(t, summary, "$0.2100")
+	assert.Less(t, strings.Index(summary, "EKS_1_33"), strings.Index(summary, "VPC"), "sorted by cell")
+}
+
+func TestCheckBudget(t *testing.T) {
+	assert.NoError(t, CheckBudget(5, 0), "zero budget means no limit")
+	assert.NoError(t, CheckBudget(5, 5))
+	assert.EqualError(t, CheckBudget(5.5, 5), "estimated cost $5.50 exceeds budget $5.00")
+}
@@ -0,0 +1,544 @@
+// Package fakeaws provides in-process HTTP stand-ins for the AWS APIs the
+// integration helpers call, so their polling and discovery logic can be
+// tested without an AWS account. The contract tests also point the Terraform
+// AWS provider at them to plan examples/eks offline.
+//
+// Point an aws-sdk-go client at a fake by setting aws.Config.Endpoint to its
+// URL. Any static credentials work; requests are not signature-checked.
+package fakeaws
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EKS operation names, used to inject errors and count calls.
+const (
+	OpListClusters          = "ListClusters"
+	OpDescribeCluster       = "DescribeCluster"
+	OpDeleteCluster         = "DeleteCluster"
+	OpDescribeAddonVersions = "DescribeAddonVersions"
+	OpListNodegroups        = "ListNodegroups"
+	OpDescribeNodegroup     = "DescribeNodegroup"
+	OpDeleteNodegroup       = "DeleteNodegroup"
+	OpDescribeAddon         = "DescribeAddon"
+)
+
+// APIError is an error response in the AWS REST-JSON format.
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+// ThrottlingError is the error EKS returns when a caller exceeds its request rate.
+var ThrottlingError = APIError{
+	Status:  http.StatusTooManyRequests,
+	Code:    "ThrottlingException",
+	Message: "Rate exceeded",
+}
+
+// Cluster is a scripted EKS cluster. Each DescribeCluster call returns the
+// next entry of Statuses; the last entry repeats once the script runs out.
+// An empty script means ACTIVE. Deleting a cluster removes it at once.
+// A non-empty KeyARN encrypts EncryptedResources (secrets if empty) with
+// that KMS key. LogTypes are the control plane log types enabled. A nil
+// EndpointAccess means EKS's defaults.
+type Cluster struct {
+	Name               string
+	Version            string
+	Endpoint           string
+	Statuses           []string
+	Tags               map[string]string
+	KeyARN             string
+	EncryptedResources []string
+	LogTypes           []string
+	EndpointAccess     *EndpointAccess
+}
+
+// EndpointAccess is who can reach a cluster's API server endpoint, as in
+// DescribeCluster's resourcesVpcConfig.
+type EndpointAccess struct {
+	Public      bool
+	Private     bool
+	PublicCIDRs []string
+}
+
+// defaultEndpointAccess is what EKS sets up when a cluster is created without
+// endpoint settings.
+var defaultEndpointAccess = EndpointAccess{Public: true, PublicCIDRs: []string{"0.0.0.0/0"}}
+
+// logTypes are the control plane log types EKS offers.
+var logTypes = []string{"api", "audit", "authenticator", "controllerManager", "scheduler"}
+
+// Nodegroup is a scripted managed node group, with Statuses consumed by
+// DescribeNodegroup the same way as Cluster.Statuses. An empty Version means
+// the cluster's.
+type Nodegroup struct {
+	Name          string
+	Version       string
+	InstanceTypes []string
+	MinSize       int64
+	MaxSize       int64
+	DesiredSize   int64
+	Statuses      []string
+	Tags          map[string]string
+}
+
+// Addon is an installed EKS addon, as returned by DescribeAddon. An empty
+// Status means ACTIVE.
+type Addon struct {
+	Name    string
+	Version string
+	Status  string
+}
+
+type clusterState struct {
+	Cluster
+	calls      int
+	nodegroups map[string]*nodegroupState
+	addons     map[string]Addon
+}
+
+type nodegroupState struct {
+	Nodegroup
+	calls int
+}
+
+type addonVersion struct {
+	version         string
+	clusterVersions []string
+	defaultFor      map[string]bool
+}
+
+// EKS is a fake EKS API server. It is safe for concurrent use.
+type EKS struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	clusters map[string]*clusterState
+	addons   map[string][]addonVersion
+	faults   map[string][]APIError
+	calls    map[string]int
+}
+
+// NewEKS starts a fake EKS API server. Call Close when done.
+func NewEKS() *EKS {
+	f := &EKS{
+		clusters: make(map[string]*clusterState),
+		addons:   make(map[string][]addonVersion),
+		faults:   make(map[string][]APIError),
+		calls:    make(map[string]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /clusters", f.handle(OpListClusters, f.listClusters))
+	mux.HandleFunc("GET /clusters/{name}", f.handle(OpDescribeCluster, f.describeCluster))
+	mux.HandleFunc("DELETE /clusters/{name}", f.handle(OpDeleteCluster, f.deleteCluster))
+	mux.HandleFunc("GET /clusters/{name}/node-groups", f.handle(OpListNodegroups, f.listNodegroups))
+	mux.HandleFunc("GET /clusters/{name}/node-groups/{nodegroup}", f.handle(OpDescribeNodegroup, f.describeNodegroup))
+	mux.HandleFunc("DELETE /clusters/{name}/node-groups/{nodegroup}", f.handle(OpDeleteNodegroup, f.deleteNodegroup))
+	mux.HandleFunc("GET /clusters/{name}/addons/{addon}", f.handle(OpDescribeAddon, f.describeAddon))
+	mux.HandleFunc("GET /addons/supported-versions", f.handle(OpDescribeAddonVersions, f.describeAddonVersions))
+	f.server = httptest.NewServer(mux)
+
+	return f
+}
+
+// URL is the endpoint to use as aws.Config.Endpoint.
+func (f *EKS) URL() string {
+	return f.server.URL
+}
+
+// Close shuts the server down.
+func (f *EKS) Close() {
+	f.server.Close()
+}
+
+// AddCluster adds or replaces a cluster.
+func (f *EKS) AddCluster(c Cluster) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clusters[c.Name] = &clusterState{Cluster: c, nodegroups: make(map[string]*nodegroupState), addons: make(map[string]Addon)}
+}
+
+// AddNodegroup adds or replaces a node group on an existing cluster.
+func (f *EKS) AddNodegroup(clusterName string, ng Nodegroup) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.clusters[clusterName]
+	if !ok {
+		return fmt.Errorf("no cluster %q", clusterName)
+	}
+	c.nodegroups[ng.Name] = &nodegroupState{Nodegroup: ng}
+	return nil
+}
+
+// AddAddon installs or replaces an addon on an existing cluster.
+func (f *EKS) AddAddon(clusterName string, a Addon) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.clusters[clusterName]
+	if !ok {
+		return fmt.Errorf("no cluster %q", clusterName)
+	}
+	c.addons[a.Name] = a
+	return nil
+}
+
+// AddAddonVersion registers a version of an addon compatible with the given
+// cluster versions, as returned by DescribeAddonVersions.
+func (f *EKS) AddAddonVersion(addon, version string, clusterVersions ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.addons[addon] = append(f.addons[addon], addonVersion{version: version, clusterVersions: clusterVersions})
+}
+
+// SetDefaultAddonVersion marks a registered addon version as the one EKS
+// installs by default on clusterVersion, clearing any previous default.
+func (f *EKS) SetDefaultAddonVersion(addon, version, clusterVersion string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	versions := f.addons[addon]
+	found := false
+	for _, av := range versions {
+		found = found || av.version == version
+	}
+	if !found {
+		return fmt.Errorf("no %s version %q", addon, version)
+	}
+	for i := range versions {
+		if versions[i].defaultFor == nil {
+			versions[i].defaultFor = make(map[string]bool)
+		}
+		versions[i].defaultFor[clusterVersion] = versions[i].version == version
+	}
+	return nil
+}
+
+// Clusters returns the names of the clusters that exist, sorted.
+func (f *EKS) Clusters() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return sortedKeys(f.clusters)
+}
+
+// InjectError makes the next n calls to op fail with e before the operation runs.
+func (f *EKS) InjectError(op string, n int, e APIError) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := 0; i < n; i++ {
+		f.faults[op] = append(f.faults[op], e)
+	}
+}
+
+// Throttle makes the next n calls to op fail with ThrottlingError.
+func (f *EKS) Throttle(op string, n int) {
+	f.InjectError(op, n, ThrottlingError)
+}
+
+// Calls returns how many requests op has received, including failed ones.
+func (f *EKS) Calls(op string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[op]
+}
+
+// handle counts the call, serves any injected fault, then runs fn under the lock.
+func (f *EKS) handle(op string, fn func(r *http.Request) (interface{}, *APIError)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.calls[op]++
+
+		var body interface{}
+		var apiErr *APIError
+		if faults := f.faults[op]; len(faults) > 0 {
+			apiErr = &faults[0]
+			f.faults[op] = faults[1:]
+		} else {
+			body, apiErr = fn(r)
+		}
+		f.mu.Unlock()
+
+		if apiErr != nil {
+			writeError(w, *apiErr)
+			return
+		}
+		writeJSON(w, http.StatusOK, body)
+	}
+}
+
+func (f *EKS) listClusters(*http.Request) (interface{}, *APIError) {
+	return map[string]interface{}{"clusters": sortedKeys(f.clusters)}, nil
+}
+
+func (f *EKS) describeCluster(r *http.Request) (interface{}, *APIError) {
+	c, apiErr := f.cluster(r.PathValue("name"))
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	status := nextStatus(c.Statuses, c.calls)
+	c.calls++
+
+	return map[string]interface{}{"cluster": clusterBody(c, status)}, nil
+}
+
+// deleteCluster fails while the cluster has node groups, as EKS does.
+func (f *EKS) deleteCluster(r *http.Request) (interface{}, *APIError) {
+	c, apiErr := f.cluster(r.PathValue("name"))
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if len(c.nodegroups) > 0 {
+		return nil, &APIError{
+			Status:  http.StatusConflict,
+			Code:    "ResourceInUseException",
+			Message: fmt.Sprintf("Cluster has nodegroups attached: %s", c.Name),
+		}
+	}
+
+	delete(f.clusters, c.Name)
+	return map[string]interface{}{"cluster": clusterBody(c, "DELETING")}, nil
+}
+
+func clusterBody(c *clusterState, status string) map[string]interface{} {
+	body := map[string]interface{}{
+		"name":      c.Name,
+		"arn":       "arn:aws:eks:us-east-1:123456789012:cluster/" + c.Name,
+		"version":   c.Version,
+		"status":    status,
+		"endpoint":  c.Endpoint,
+		"tags":      c.Tags,
+		"createdAt": time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).Unix(),
+		"logging":   clusterLogging(c.LogTypes),
+	}
+	access := defaultEndpointAccess
+	if c.EndpointAccess != nil {
+		access = *c.EndpointAccess
+	}
+	body["resourcesVpcConfig"] = map[string]interface{}{
+		"endpointPublicAccess":  access.Public,
+		"endpointPrivateAccess": access.Private,
+		"publicAccessCidrs":     access.PublicCIDRs,
+	}
+	if c.KeyARN != "" {
+		resources := c.EncryptedResources
+		if len(resources) == 0 {
+			resources = []string{"secrets"}
+		}
+		body["encryptionConfig"] = []map[string]interface{}{{
+			"resources": resources,
+			"provider":  map[string]string{"keyArn": c.KeyARN},
+		}}
+	}
+	return body
+}
+
+// clusterLogging lists the enabled log types and the rest as disabled, as
+// DescribeCluster does.
+func clusterLogging(enabled []string) map[string]interface{} {
+	on := make(map[string]bool)
+	for _, t := range enabled {
+		on[t] = true
+	}
+	disabled := []string{}
+	for _, t := range logTypes {
+		if !on[t] {
+			disabled = append(disabled, t)
+		}
+	}
+
+	var setups []map[string]interface{}
+	if len(enabled) > 0 {
+		setups = append(setups, map[string]interface{}{"types": enabled, "enabled": true})
+	}
+	if len(disabled) > 0 {
+		setups = append(setups, map[string]interface{}{"types": disabled, "enabled": false})
+	}
+	return map[string]interface{}{"clusterLogging": setups}
+}
+
+func (f *EKS) listNodegroups(r *http.Request) (interface{}, *APIError) {
+	c, apiErr := f.cluster(r.PathValue("name"))
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return map[string]interface{}{"nodegroups": sortedKeys(c.nodegroups)}, nil
+}
+
+func (f *EKS) describeNodegroup(r *http.Request) (interface{}, *APIError) {
+	c, ng, apiErr := f.nodegroup(r.PathValue("name"), r.PathValue("nodegroup"))
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	status := nextStatus(ng.Statuses, ng.calls)
+	ng.calls++
+
+	return map[string]interface{}{"nodegroup": nodegroupBody(c, ng, status)}, nil
+}
+
+func (f *EKS) deleteNodegroup(r *http.Request) (interface{}, *APIError) {
+	c, ng, apiErr := f.nodegroup(r.PathValue("name"), r.PathValue("nodegroup"))
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	delete(c.nodegroups, ng.Name)
+	return map[string]interface{}{"nodegroup": nodegroupBody(c, ng, "DELETING")}, nil
+}
+
+func nodegroupBody(c *clusterState, ng *nodegroupState, status string) map[string]interface{} {
+	version := ng.Version
+	if version == "" {
+		version = c.Version
+	}
+	return map[string]interface{}{
+		"nodegroupName": ng.Name,
+		"nodegroupArn":  "arn:aws:eks:us-east-1:123456789012:nodegroup/" + c.Name + "/" + ng.Name,
+		"clusterName":   c.Name,
+		"version":       version,
+		"status":        status,
+		"instanceTypes": ng.InstanceTypes,
+		"tags":          ng.Tags,
+		"scalingConfig": map[string]int64{
+			"minSize":     ng.MinSize,
+			"maxSize":     ng.MaxSize,
+			"desiredSize": ng.DesiredSize,
+		},
+	}
+}
+
+func (f *EKS) describeAddon(r *http.Request) (interface{}, *APIError) {
+	c, apiErr := f.cluster(r.PathValue("name"))
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	a, ok := c.addons[r.PathValue("addon")]
+	if !ok {
+		return nil, notFound("No addon: %s found in cluster: %s", r.PathValue("addon"), c.Name)
+	}
+
+	status := a.Status
+	if status == "" {
+		status = "ACTIVE"
+	}
+	return map[string]interface{}{"addon": map[string]interface{}{
+		"addonName":    a.Name,
+		"addonArn":     "arn:aws:eks:us-east-1:123456789012:addon/" + c.Name + "/" + a.Name,
+		"clusterName":  c.Name,
+		"addonVersion": a.Version,
+		"status":       status,
+	}}, nil
+}
+
+func (f *EKS) describeAddonVersions(r *http.Request) (interface{}, *APIError) {
+	query := r.URL.Query()
+	wantAddon := query.Get("addonName")
+	wantCluster := query.Get("kubernetesVersion")
+
+	names := make([]string, 0, len(f.addons))
+	for name := range f.addons {
+		if wantAddon == "" || name == wantAddon {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	addons := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		var versions []interface{}
+		for _, av := range f.addons[name] {
+			var compat []interface{}
+			for _, cv := range av.clusterVersions {
+				if wantCluster == "" || cv == wantCluster {
+					compat = append(compat, map[string]interface{}{
+						"clusterVersion": cv,
+						"defaultVersion": av.defaultFor[cv],
+					})
+				}
+			}
+			if len(compat) > 0 {
+				versions = append(versions, map[string]interface{}{
+					"addonVersion":    av.version,
+					"compatibilities": compat,
+				})
+			}
+		}
+		addons = append(addons, map[string]interface{}{
+			"addonName":     name,
+			"addonVersions": versions,
+		})
+	}
+
+	return map[string]interface{}{"addons": addons}, nil
+}
+
+func (f *EKS) cluster(name string) (*clusterState, *APIError) {
+	c, ok := f.clusters[name]
+	if !ok {
+		return nil, notFound("No cluster found for name: %s.", name)
+	}
+	return c, nil
+}
+
+func (f *EKS) nodegroup(clusterName, name string) (*clusterState, *nodegroupState, *APIError) {
+	c, apiErr := f.cluster(clusterName)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+	ng, ok := c.nodegroups[name]
+	if !ok {
+		return nil, nil, notFound("No node group found for name: %s.", name)
+	}
+	return c, ng, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// nextStatus returns step i of a status script, repeating the last entry.
+func nextStatus(script []string, i int) string {
+	if len(script) == 0 {
+		return "ACTIVE"
+	}
+	if i >= len(script) {
+		i = len(script) - 1
+	}
+	return script[i]
+}
+
+func notFound(format string, args ...interface{}) *APIError {
+	return &APIError{
+		Status:  http.StatusNotFound,
+		Code:    "ResourceNotFoundException",
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func writeError(w http.ResponseWriter, e APIError) {
+	w.Header().Set("X-Amzn-Errortype", e.Code)
+	writeJSON(w, e.Status, map[string]string{"message": e.Message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
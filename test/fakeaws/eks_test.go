@@ -0,0 +1,267 @@
+package fakeaws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newClient(t *testing.T, fake *EKS) *eks.EKS {
+	t.Helper()
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(fake.URL()),
+		Credentials: credentials.NewStaticCredentials("AKIDTEST", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})
+	require.NoError(t, err)
+	return eks.New(sess)
+}
+
+func TestDescribeClusterFollowsScript(t *testing.T) {
+	fake := NewEKS()
+	defer fake.Close()
+	fake.AddCluster(Cluster{Name: "demo", Version: "1.33", Endpoint: "https://demo.eks.amazonaws.com", Statuses: []string{"CREATING", "ACTIVE"}})
+	client := newClient(t, fake)
+
+	var statuses []string
+	for i := 0; i < 3; i++ {
+		out, err := client.DescribeCluster(&eks.DescribeClusterInput{Name: aws.String("demo")})
+		require.NoError(t, err)
+		assert.Equal(t, "1.33", aws.StringValue(out.Cluster.Version))
+		assert.Equal(t, "https://demo.eks.amazonaws.com", aws.StringValue(out.Cluster.Endpoint))
+		statuses = append(statuses, aws.StringValue(out.Cluster.Status))
+	}
+
+	assert.Equal(t, []string{"CREATING", "ACTIVE", "ACTIVE"}, statuses)
+	assert.Equal(t, 3, fake.Calls(OpDescribeCluster))
+}
+
+func TestDescribeClusterEncryption(t *testing.T) {
+	fake := NewEKS()
+	defer fake.Close()
+	const key = "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	fake.AddCluster(Cluster{Name: "encrypted", Version: "1.33", KeyARN: key})
+	fake.AddCluster(Cluster{Name: "plain", Version: "1.33"})
+	client := newClient(t, fake)
+
+	out, err := client.DescribeCluster(&eks.DescribeClusterInput{Name: aws.String("encrypted")})
+	require.NoError(t, err)
+	require.Len(t, out.Cluster.EncryptionConfig, 1)
+	assert.Equal(t, []string{"secrets"}, aws.StringValueSlice(out.Cluster.EncryptionConfig[0].Resources))
+	assert.Equal(t, key, aws.StringValue(out.Cluster.EncryptionConfig[0].Provider.KeyArn))
+
+	out, err = client.DescribeCluster(&eks.DescribeClusterInput{Name: aws.String("plain")})
+	require.NoError(t, err)
+	assert.Empty(t, out.Cluster.EncryptionConfig)
+}
+
+func TestDescribeClusterLogging(t *testing.T) {
+	fake := NewEKS()
+	defer fake.Close()
+	fake.AddCluster(Cluster{Name: "demo", Version: "1.33", LogTypes: []string{"api", "audit"}})
+
+	out, err := newClient(t, fake).DescribeCluster(&eks.DescribeClusterInput{Name: aws.String("demo")})
+	require.NoError(t, err)
+	setups := out.Cluster.Logging.ClusterLogging
+	require.Len(t, setups, 2)
+	assert.True(t, aws.BoolValue(setups[0].Enabled))
+	assert.Equal(t, []string{"api", "audit"}, aws.StringValueSlice(setups[0].Types))
+	assert.False(t, aws.BoolValue(setups[1].Enabled))
+	assert.Equal(t, []string{"authenticator", "controllerManager", "scheduler"}, aws.StringValueSlice(setups[1].Types))
+}
+
+func TestDescribeClusterEndpointAccess(t *testing.T) {
+	fake := NewEKS()
+	defer fake.Close()
+	fake.AddCluster(Cluster{Name: "default", Version: "1.33"})
+	fake.AddCluster(Cluster{Name: "private", Version: "1.33", EndpointAccess: &EndpointAccess{Private: true, PublicCIDRs: []string{"0.0.0.0/0"}}})
+	client := newClient(t, fake)
+
+	out, err := client.DescribeCluster(&eks.DescribeClusterInput{Name: aws.String("default")})
+	require.NoError(t, err)
+	vpc := out.Cluster.ResourcesVpcConfig
+	assert.True(t, aws.BoolValue(vpc.EndpointPublicAccess))
+	assert.False(t, aws.BoolValue(vpc.EndpointPrivateAccess))
+	assert.Equal(t, []string{"0.0.0.0/0"}, aws.StringValueSlice(vpc.PublicAccessCidrs))
+
+	out, err = client.DescribeCluster(&eks.DescribeClusterInput{Name: aws.String("private")})
+	require.NoError(t, err)
+	vpc = out.Cluster.ResourcesVpcConfig
+	assert.False(t, aws.BoolValue(vpc.EndpointPublicAccess))
+	assert.True(t, aws.BoolValue(vpc.EndpointPrivateAccess))
+}
+
+func TestDescribeClusterNotFound(t *testing.T) {
+	fake := NewEKS()
+	defer fake.Close()
+
+	_, err := newClient(t, fake).DescribeCluster(&eks.DescribeClusterInput{Name: aws.String("missing")})
+	var aerr awserr.Error
+	require.ErrorAs(t, err, &aerr)
+	assert.Equal(t, eks.ErrCodeResourceNotFoundException, aerr.Code())
+}
+
+func TestInjectedThrottling(t *testing.T) {
+	fake := NewEKS()
+	defer fake.Close()
+	fake.AddCluster(Cluster{Name: "demo", Version: "1.33"})
+	fake.Throttle(OpDescribeCluster, 1)
+	client := newClient(t, fake)
+
+	_, err := client.DescribeCluster(&eks.DescribeClusterInput{Name: aws.String("demo")})
+	var aerr awserr.RequestFailure
+	require.ErrorAs(t, err, &aerr)
+	assert.Equal(t, "ThrottlingException", aerr.Code())
+	assert.Equal(t, 429, aerr.StatusCode())
+
+	out, err := client.DescribeCluster(&eks.DescribeClusterInput{Name: aws.String("demo")})
+	require.NoError(t, err)
+	assert.Equal(t, "ACTIVE", aws.StringValue(out.Cluster.Status))
+	assert.Equal(t, 2, fake.Calls(OpDescribeCluster))
+}
+
+func TestNodegroups(t *testing.T) {
+	fake := NewEKS()
+	defer fake.Close()
+	fake.AddCluster(Cluster{Name: "demo", Version: "1.33"})
+	require.NoError(t, fake.AddNodegroup("demo", Nodegroup{Name: "workers", InstanceTypes: []string{"t3.small"}, MinSize: 1, MaxSize: 3, DesiredSize: 2, Statuses: []string{"CREATING", "CREATE_FAILED"}}))
+	require.NoError(t, fake.AddNodegroup("demo", Nodegroup{Name: "system"}))
+	assert.Error(t, fake.AddNodegroup("missing", Nodegroup{Name: "workers"}))
+	client := newClient(t, fake)
+
+	list, err := client.ListNodegroups(&eks.ListNodegroupsInput{ClusterName: aws.String("demo")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"system", "workers"}, aws.StringValueSlice(list.Nodegroups))
+
+	var statuses []string
+	for i := 0; i < 2; i++ {
+		out, err := client.DescribeNodegroup(&eks.DescribeNodegroupInput{ClusterName: aws.String("demo"), NodegroupName: aws.String("workers")})
+		require.NoError(t, err)
+		statuses = append(statuses, aws.StringValue(out.Nodegroup.Status))
+		assert.Equal(t, []string{"t3.small"}, aws.StringValueSlice(out.Nodegroup.InstanceTypes))
+		assert.Equal(t, int64(2), aws.Int64Value(out.Nodegroup.ScalingConfig.DesiredSize))
+	}
+	assert.Equal(t, []string{"CREATING", "CREATE_FAILED"}, statuses)
+
+	_, err = client.DescribeNodegroup(&eks.DescribeNodegroupInput{ClusterName: aws.String("demo"), NodegroupName: aws.String("gpu")})
+	var aerr awserr.Error
+	require.ErrorAs(t, err, &aerr)
+	assert.Equal(t, eks.ErrCodeResourceNotFoundException, aerr.Code())
+}
+
+func TestNodegroupVersion(t *testing.T) {
+	fake := NewEKS()
+	defer fake.Close()
+	fake.AddCluster(Cluster{Name: "demo", Version: "1.33"})
+	require.NoError(t, fake.AddNodegroup("demo", Nodegroup{Name: "current"}))
+	require.NoError(t, fake.AddNodegroup("demo", Nodegroup{Name: "behind", Version: "1.32"}))
+	client := newClient(t, fake)
+
+	for name, want := range map[string]string{"current": "1.33", "behind": "1.32"} {
+		out, err := client.DescribeNodegroup(&eks.DescribeNodegroupInput{ClusterName: aws.String("demo"), NodegroupName: aws.String(name)})
+		require.NoError(t, err)
+		assert.Equal(t, want, aws.StringValue(out.Nodegroup.Version), name)
+	}
+}
+
+func TestDescribeAddon(t *testing.T) {
+	fake := NewEKS()
+	defer fake.Close()
+	fake.AddCluster(Cluster{Name: "demo", Version: "1.33"})
+	require.NoError(t, fake.AddAddon("demo", Addon{Name: "coredns", Version: "v1.12.1-eksbuild.2"}))
+	require.NoError(t, fake.AddAddon("demo", Addon{Name: "vpc-cni", Version: "v1.19.5-eksbuild.1", Status: "DEGRADED"}))
+	assert.Error(t, fake.AddAddon("missing", Addon{Name: "coredns"}))
+	client := newClient(t, fake)
+
+	out, err := client.DescribeAddon(&eks.DescribeAddonInput{ClusterName: aws.String("demo"), AddonName: aws.String("coredns")})
+	require.NoError(t, err)
+	assert.Equal(t, "v1.12.1-eksbuild.2", aws.StringValue(out.Addon.AddonVersion))
+	assert.Equal(t, "ACTIVE", aws.StringValue(out.Addon.Status))
+
+	out, err = client.DescribeAddon(&eks.DescribeAddonInput{ClusterName: aws.String("demo"), AddonName: aws.String("vpc-cni")})
+	require.NoError(t, err)
+	assert.Equal(t, "DEGRADED", aws.StringValue(out.Addon.Status))
+
+	_, err = client.DescribeAddon(&eks.DescribeAddonInput{ClusterName: aws.String("demo"), AddonName: aws.String("kube-proxy")})
+	var aerr awserr.Error
+	require.ErrorAs(t, err, &aerr)
+	assert.Equal(t, eks.ErrCodeResourceNotFoundException, aerr.Code())
+	assert.Equal(t, 3, fake.Calls(OpDescribeAddon))
+}
+
+func TestDeleteCluster(t *testing.T) {
+	fake := NewEKS()
+	defer fake.Close()
+	fake.AddCluster(Cluster{Name: "demo", Version: "1.33", Tags: map[string]string{"Pipeline": "eks-cluster"}})
+	require.NoError(t, fake.AddNodegroup("demo", Nodegroup{Name: "default"}))
+	client := newClient(t, fake)
+
+	list, err := client.ListClusters(&eks.ListClustersInput{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"demo"}, aws.StringValueSlice(list.Clusters))
+
+	desc, err := client.DescribeCluster(&eks.DescribeClusterInput{Name: aws.String("demo")})
+	require.NoError(t, err)
+	assert.Equal(t, "eks-cluster", aws.StringValue(desc.Cluster.Tags["Pipeline"]))
+
+	_, err = client.DeleteCluster(&eks.DeleteClusterInput{Name: aws.String("demo")})
+	var aerr awserr.Error
+	require.ErrorAs(t, err, &aerr)
+	assert.Equal(t, eks.ErrCodeResourceInUseException, aerr.Code(), "node groups must be deleted first")
+
+	_, err = client.DeleteNodegroup(&eks.DeleteNodegroupInput{ClusterName: aws.String("demo"), NodegroupName: aws.String("default")})
+	require.NoError(t, err)
+	out, err := client.DeleteCluster(&eks.DeleteClusterInput{Name: aws.String("demo")})
+	require.NoError(t, err)
+	assert.Equal(t, "DELETING", aws.StringValue(out.Cluster.Status))
+	assert.Empty(t, fake.Clusters())
+}
+
+func TestDescribeAddonVersions(t *testing.T) {
+	fake := NewEKS()
+	defer fake.Close()
+	fake.AddAddonVersion("vpc-cni", "v1.19.0-eksbuild.1", "1.31", "1.32")
+	fake.AddAddonVersion("vpc-cni", "v1.20.0-eksbuild.1", "1.32", "1.33")
+	fake.AddAddonVersion("coredns", "v1.11.4-eksbuild.2", "1.31")
+	client := newClient(t, fake)
+
+	out, err := client.DescribeAddonVersions(&eks.DescribeAddonVersionsInput{AddonName: aws.String("vpc-cni")})
+	require.NoError(t, err)
+	require.Len(t, out.Addons, 1)
+	assert.Equal(t, "vpc-cni", aws.StringValue(out.Addons[0].AddonName))
+	require.Len(t, out.Addons[0].AddonVersions, 2)
+	assert.Equal(t, "1.33", aws.StringValue(out.Addons[0].AddonVersions[1].Compatibilities[1].ClusterVersion))
+
+	out, err = client.DescribeAddonVersions(&eks.DescribeAddonVersionsInput{KubernetesVersion: aws.String("1.31")})
+	require.NoError(t, err)
+	require.Len(t, out.Addons, 2)
+	assert.Equal(t, "coredns", aws.StringValue(out.Addons[0].AddonName))
+	require.Len(t, out.Addons[1].AddonVersions, 1)
+	assert.Equal(t, "v1.19.0-eksbuild.1", aws.StringValue(out.Addons[1].AddonVersions[0].AddonVersion))
+	assert.False(t, aws.BoolValue(out.Addons[1].AddonVersions[0].Compatibilities[0].DefaultVersion))
+}
+
+func TestSetDefaultAddonVersion(t *testing.T) {
+	fake := NewEKS()
+	defer fake.Close()
+	fake.AddAddonVersion("vpc-cni", "v1.19.0-eksbuild.1", "1.32")
+	fake.AddAddonVersion("vpc-cni", "v1.20.0-eksbuild.1", "1.32")
+	require.NoError(t, fake.SetDefaultAddonVersion("vpc-cni", "v1.20.0-eksbuild.1", "1.32"))
+	require.NoError(t, fake.SetDefaultAddonVersion("vpc-cni", "v1.19.0-eksbuild.1", "1.32"))
+	assert.Error(t, fake.SetDefaultAddonVersion("vpc-cni", "v9.9.9", "1.32"))
+	client := newClient(t, fake)
+
+	out, err := client.DescribeAddonVersions(&eks.DescribeAddonVersionsInput{AddonName: aws.String("vpc-cni"), KubernetesVersion: aws.String("1.32")})
+	require.NoError(t, err)
+	versions := out.Addons[0].AddonVersions
+	require.Len(t, versions, 2)
+	assert.True(t, aws.BoolValue(versions[0].Compatibilities[0].DefaultVersion))
+	assert.False(t, aws.BoolValue(versions[1].Compatibilities[0].DefaultVersion), "the previous default is cleared")
+}
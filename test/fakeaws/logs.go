@@ -0,0 +1,200 @@
+package fakeaws
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CloudWatch Logs operation names, used to count calls.
+const (
+	OpDescribeLogGroups = "DescribeLogGroups"
+	OpFilterLogEvents   = "FilterLogEvents"
+)
+
+// logsTargetPrefix prefixes the X-Amz-Target header of every CloudWatch Logs
+// request.
+const logsTargetPrefix = "Logs_20140328."
+
+// LogGroup is a CloudWatch Logs log group. Zero RetentionInDays means the
+// events never expire.
+type LogGroup struct {
+	Name            string
+	RetentionInDays int64
+}
+
+// LogStream is a log stream with its events. FilterLogEvents doesn't return
+// the events until it has been called HiddenFor times on the group, as if
+// they arrived late.
+type LogStream struct {
+	Name      string
+	Messages  []string
+	HiddenFor int
+}
+
+type logGroupState struct {
+	LogGroup
+	streams []LogStream
+	filters int
+}
+
+// Logs is a fake CloudWatch Logs API server. It is safe for concurrent use.
+type Logs struct {
+	server *httptest.Server
+
+	mu     sync.Mutex
+	groups map[string]*logGroupState
+	calls  map[string]int
+}
+
+// NewLogs starts a fake CloudWatch Logs API server. Call Close when done.
+func NewLogs() *Logs {
+	f := &Logs{
+		groups: make(map[string]*logGroupState),
+		calls:  make(map[string]int),
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+// URL is the endpoint to use as aws.Config.Endpoint.
+func (f *Logs) URL() string {
+	return f.server.URL
+}
+
+// Close shuts the server down.
+func (f *Logs) Close() {
+	f.server.Close()
+}
+
+// AddLogGroup adds or replaces a log group, dropping its streams.
+func (f *Logs) AddLogGroup(g LogGroup) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.groups[g.Name] = &logGroupState{LogGroup: g}
+}
+
+// AddLogStream adds a stream to an existing log group.
+func (f *Logs) AddLogStream(group string, s LogStream) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	g, ok := f.groups[group]
+	if !ok {
+		return fmt.Errorf("no log group %q", group)
+	}
+	g.streams = append(g.streams, s)
+	return nil
+}
+
+// Calls returns how many requests op has received, including failed ones.
+func (f *Logs) Calls(op string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[op]
+}
+
+// serve handles the JSON 1.1 protocol: a POST naming the operation in the
+// X-Amz-Target header.
+func (f *Logs) serve(w http.ResponseWriter, r *http.Request) {
+	op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), logsTargetPrefix)
+
+	f.mu.Lock()
+	f.calls[op]++
+	var body interface{}
+	var apiErr *APIError
+	switch op {
+	case OpDescribeLogGroups:
+		body, apiErr = f.describeLogGroups(r)
+	case OpFilterLogEvents:
+		body, apiErr = f.filterLogEvents(r)
+	default:
+		apiErr = &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    "UnknownOperationException",
+			Message: "The fake doesn't serve " + op,
+		}
+	}
+	f.mu.Unlock()
+
+	if apiErr != nil {
+		w.Header().Set("X-Amzn-Errortype", apiErr.Code)
+		writeJSON(w, apiErr.Status, map[string]string{"__type": apiErr.Code, "message": apiErr.Message})
+		return
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+func (f *Logs) describeLogGroups(r *http.Request) (interface{}, *APIError) {
+	var in struct {
+		LogGroupNamePrefix string `json:"logGroupNamePrefix"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		return nil, invalidParameter(err)
+	}
+
+	groups := []map[string]interface{}{}
+	for _, name := range sortedKeys(f.groups) {
+		if !strings.HasPrefix(name, in.LogGroupNamePrefix) {
+			continue
+		}
+		g := map[string]interface{}{
+			"logGroupName": name,
+			"arn":          "arn:aws:logs:us-east-1:123456789012:log-group:" + name + ":*",
+			"creationTime": time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli(),
+		}
+		if days := f.groups[name].RetentionInDays; days != 0 {
+			g["retentionInDays"] = days
+		}
+		groups = append(groups, g)
+	}
+	return map[string]interface{}{"logGroups": groups}, nil
+}
+
+func (f *Logs) filterLogEvents(r *http.Request) (interface{}, *APIError) {
+	var in struct {
+		LogGroupName        string `json:"logGroupName"`
+		LogStreamNamePrefix string `json:"logStreamNamePrefix"`
+		Limit               int    `json:"limit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		return nil, invalidParameter(err)
+	}
+	g, ok := f.groups[in.LogGroupName]
+	if !ok {
+		return nil, notFound("The specified log group does not exist.")
+	}
+	calls := g.filters
+	g.filters++
+
+	events := []map[string]interface{}{}
+	for _, s := range g.streams {
+		if !strings.HasPrefix(s.Name, in.LogStreamNamePrefix) || calls < s.HiddenFor {
+			continue
+		}
+		for i, msg := range s.Messages {
+			if in.Limit > 0 && len(events) == in.Limit {
+				break
+			}
+			events = append(events, map[string]interface{}{
+				"logStreamName": s.Name,
+				"eventId":       fmt.Sprintf("%s-%d", s.Name, i),
+				"message":       msg,
+				"timestamp":     time.Date(2025, 1, 1, 0, 0, i, 0, time.UTC).UnixMilli(),
+			})
+		}
+	}
+	return map[string]interface{}{"events": events}, nil
+}
+
+func invalidParameter(err error) *APIError {
+	return &APIError{
+		Status:  http.StatusBadRequest,
+		Code:    "InvalidParameterException",
+		Message: err.Error(),
+	}
+}
@@ -0,0 +1,74 @@
+package fakeaws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLogsClient(t *testing.T, fake *Logs) *cloudwatchlogs.CloudWatchLogs {
+	t.Helper()
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(fake.URL()),
+		Credentials: credentials.NewStaticCredentials("AKIDTEST", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})
+	require.NoError(t, err)
+	return cloudwatchlogs.New(sess)
+}
+
+func TestDescribeLogGroups(t *testing.T) {
+	fake := NewLogs()
+	defer fake.Close()
+	fake.AddLogGroup(LogGroup{Name: "/aws/eks/demo/cluster", RetentionInDays: 7})
+	fake.AddLogGroup(LogGroup{Name: "/aws/eks/demo-2/cluster"})
+	fake.AddLogGroup(LogGroup{Name: "/aws/lambda/other"})
+	client := newLogsClient(t, fake)
+
+	out, err := client.DescribeLogGroups(&cloudwatchlogs.DescribeLogGroupsInput{LogGroupNamePrefix: aws.String("/aws/eks/demo")})
+	require.NoError(t, err)
+	require.Len(t, out.LogGroups, 2)
+	assert.Equal(t, "/aws/eks/demo-2/cluster", aws.StringValue(out.LogGroups[0].LogGroupName))
+	assert.Nil(t, out.LogGroups[0].RetentionInDays, "never expires")
+	assert.Equal(t, "/aws/eks/demo/cluster", aws.StringValue(out.LogGroups[1].LogGroupName))
+	assert.Equal(t, int64(7), aws.Int64Value(out.LogGroups[1].RetentionInDays))
+	assert.Equal(t, 1, fake.Calls(OpDescribeLogGroups))
+}
+
+func TestFilterLogEvents(t *testing.T) {
+	fake := NewLogs()
+	defer fake.Close()
+	fake.AddLogGroup(LogGroup{Name: "/aws/eks/demo/cluster"})
+	require.NoError(t, fake.AddLogStream("/aws/eks/demo/cluster", LogStream{Name: "kube-apiserver-abc", Messages: []string{"api"}}))
+	require.NoError(t, fake.AddLogStream("/aws/eks/demo/cluster", LogStream{Name: "kube-apiserver-audit-abc", Messages: []string{"a1", "a2"}, HiddenFor: 1}))
+	assert.Error(t, fake.AddLogStream("/aws/eks/missing/cluster", LogStream{Name: "s"}))
+	client := newLogsClient(t, fake)
+
+	filter := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName:        aws.String("/aws/eks/demo/cluster"),
+		LogStreamNamePrefix: aws.String("kube-apiserver-audit-"),
+		Limit:               aws.Int64(1),
+	}
+	out, err := client.FilterLogEvents(filter)
+	require.NoError(t, err)
+	assert.Empty(t, out.Events, "events are hidden on the first call")
+
+	out, err = client.FilterLogEvents(filter)
+	require.NoError(t, err)
+	require.Len(t, out.Events, 1)
+	assert.Equal(t, "kube-apiserver-audit-abc", aws.StringValue(out.Events[0].LogStreamName))
+	assert.Equal(t, "a1", aws.StringValue(out.Events[0].Message))
+
+	_, err = client.FilterLogEvents(&cloudwatchlogs.FilterLogEventsInput{LogGroupName: aws.String("/aws/eks/missing/cluster")})
+	var aerr awserr.Error
+	require.ErrorAs(t, err, &aerr)
+	assert.Equal(t, cloudwatchlogs.ErrCodeResourceNotFoundException, aerr.Code())
+	assert.Equal(t, 3, fake.Calls(OpFilterLogEvents))
+}
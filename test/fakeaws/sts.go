@@ -0,0 +1,116 @@
+package fakeaws
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// OpGetCallerIdentity is the only STS operation the fake serves.
+const OpGetCallerIdentity = "GetCallerIdentity"
+
+// Identity is the caller STS reports. The zero value is an IAM user in
+// account 123456789012, which the AWS provider accepts without calling IAM.
+type Identity struct {
+	Account string
+	Arn     string
+	UserID  string
+}
+
+// STS is a fake STS API server answering GetCallerIdentity, enough for the
+// AWS provider and aws_caller_identity data sources to plan offline.
+type STS struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	identity Identity
+	calls    int
+}
+
+// NewSTS starts a fake STS API server. Call Close when done.
+func NewSTS() *STS {
+	f := &STS{identity: Identity{
+		Account: "123456789012",
+		Arn:     "arn:aws:iam::123456789012:user/terratest",
+		UserID:  "AIDATERRATEST",
+	}}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+// URL is the endpoint to use as aws.Config.Endpoint.
+func (f *STS) URL() string {
+	return f.server.URL
+}
+
+// Close shuts the server down.
+func (f *STS) Close() {
+	f.server.Close()
+}
+
+// SetIdentity changes the caller returned by GetCallerIdentity.
+func (f *STS) SetIdentity(id Identity) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.identity = id
+}
+
+// Calls returns how many GetCallerIdentity requests the fake has received.
+func (f *STS) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+type getCallerIdentityResponse struct {
+	XMLName xml.Name `xml:"https://sts.amazonaws.com/doc/2011-06-15/ GetCallerIdentityResponse"`
+	Result  struct {
+		Arn     string
+		UserId  string
+		Account string
+	} `xml:"GetCallerIdentityResult"`
+	RequestID string `xml:"ResponseMetadata>RequestId"`
+}
+
+type stsErrorResponse struct {
+	XMLName xml.Name `xml:"ErrorResponse"`
+	Error   struct {
+		Type    string
+		Code    string
+		Message string
+	}
+	RequestID string `xml:"RequestId"`
+}
+
+// serve handles the STS query protocol: a form POST naming the Action.
+func (f *STS) serve(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Form.Get("Action") != OpGetCallerIdentity {
+		var body stsErrorResponse
+		body.Error.Type = "Sender"
+		body.Error.Code = "InvalidAction"
+		body.Error.Message = "The fake only serves " + OpGetCallerIdentity
+		body.RequestID = "fake"
+		writeXML(w, http.StatusBadRequest, body)
+		return
+	}
+
+	f.mu.Lock()
+	f.calls++
+	id := f.identity
+	f.mu.Unlock()
+
+	var body getCallerIdentityResponse
+	body.Result.Arn = id.Arn
+	body.Result.UserId = id.UserID
+	body.Result.Account = id.Account
+	body.RequestID = "fake"
+	writeXML(w, http.StatusOK, body)
+}
+
+func writeXML(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,37 @@
+package fakeaws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCallerIdentity(t *testing.T) {
+	fake := NewSTS()
+	defer fake.Close()
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(fake.URL()),
+		Credentials: credentials.NewStaticCredentials("AKIDTEST", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})
+	require.NoError(t, err)
+	client := sts.New(sess)
+
+	out, err := client.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	require.NoError(t, err)
+	assert.Equal(t, "123456789012", aws.StringValue(out.Account))
+	assert.Equal(t, "arn:aws:iam::123456789012:user/terratest", aws.StringValue(out.Arn))
+
+	fake.SetIdentity(Identity{Account: "210987654321", Arn: "arn:aws:iam::210987654321:user/ci", UserID: "AIDACI"})
+	out, err = client.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	require.NoError(t, err)
+	assert.Equal(t, "210987654321", aws.StringValue(out.Account))
+	assert.Equal(t, "AIDACI", aws.StringValue(out.UserId))
+	assert.Equal(t, 2, fake.Calls())
+}
@@ -0,0 +1,260 @@
+// Offline tests for the addon health check, run against the fakeaws EKS
+// server and client-go's fake clientset.
+package test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/apex/terratest-eks/fakeaws"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Addon versions registered with the fake, compatible with 1.33.
+const (
+	corednsDefault   = "v1.12.1-eksbuild.2"
+	corednsLatest    = "v1.12.2-eksbuild.1"
+	kubeProxyDefault = "v1.33.0-eksbuild.2"
+	vpcCNIDefault    = "v1.20.0-eksbuild.1"
+)
+
+// fakeAddonEKS returns a fake EKS with cluster demo at 1.33 running addons,
+// and the 1.33 versions of coredns, kube-proxy and vpc-cni registered.
+func fakeAddonEKS(t *testing.T, addons ...fakeaws.Addon) *fakeaws.EKS {
+	t.Helper()
+
+	fake := useFakeEKS(t)
+	fake.AddCluster(fakeaws.Cluster{Name: "demo", Version: "1.33"})
+	for name, versions := range map[string][]string{
+		"coredns":    {corednsDefault, corednsLatest},
+		"kube-proxy": {kubeProxyDefault},
+		"vpc-cni":    {vpcCNIDefault},
+	} {
+		for _, v := range versions {
+			fake.AddAddonVersion(name, v, "1.33")
+		}
+		require.NoError(t, fake.SetDefaultAddonVersion(name, versions[0], "1.33"))
+	}
+	for _, a := range addons {
+		require.NoError(t, fake.AddAddon("demo", a))
+	}
+	return fake
+}
+
+// daemonSet returns a kube-system DaemonSet with available of desired pods
+// updated and available.
+func daemonSet(name string, desired, available int32) *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system", Generation: 1},
+		Status: appsv1.DaemonSetStatus{
+			ObservedGeneration:     1,
+			DesiredNumberScheduled: desired,
+			UpdatedNumberScheduled: available,
+			NumberAvailable:        available,
+		},
+	}
+}
+
+// corednsDeployment returns the coredns Deployment with available of two
+// replicas available.
+func corednsDeployment(available int32) *appsv1.Deployment {
+	replicas := int32(2)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system", Generation: 1},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 1,
+			UpdatedReplicas:    available,
+			AvailableReplicas:  available,
+		},
+	}
+}
+
+func TestParseClusterAddons(t *testing.T) {
+	addons, err := parseClusterAddons(`{
+		"coredns": {"addon_name": "coredns", "addon_version": "v1.12.1-eksbuild.2", "arn": "arn:aws:eks:us-west-1:123456789012:addon/demo/coredns/x"},
+		"vpc-cni": {"addon_name": "vpc-cni", "addon_version": "v1.20.0-eksbuild.1"}
+	}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]clusterAddon{
+		"coredns": {AddonName: "coredns", AddonVersion: "v1.12.1-eksbuild.2"},
+		"vpc-cni": {AddonName: "vpc-cni", AddonVersion: "v1.20.0-eksbuild.1"},
+	}, addons)
+
+	_, err = parseClusterAddons("not json")
+	assert.ErrorContains(t, err, "failed to parse cluster_addons output")
+}
+
+func TestValidateAddonHealth(t *testing.T) {
+	outputs := map[string]clusterAddon{
+		"coredns":    {AddonName: "coredns", AddonVersion: corednsLatest},
+		"kube-proxy": {AddonName: "kube-proxy", AddonVersion: kubeProxyDefault},
+		"vpc-cni":    {AddonName: "vpc-cni", AddonVersion: vpcCNIDefault},
+	}
+	healthy := []fakeaws.Addon{
+		{Name: "coredns", Version: corednsLatest},
+		{Name: "kube-proxy", Version: kubeProxyDefault},
+		{Name: "vpc-cni", Version: vpcCNIDefault},
+	}
+	rolledOut := []runtime.Object{corednsDeployment(2), daemonSet("kube-proxy", 3, 3), daemonSet("aws-node", 3, 3)}
+
+	tests := []struct {
+		name    string
+		addons  []fakeaws.Addon
+		objects []runtime.Object
+		wantErr []string // substrings of the recorded failures, in order
+	}{
+		{name: "healthy", addons: healthy, objects: rolledOut},
+		{
+			name: "degraded",
+			addons: []fakeaws.Addon{
+				{Name: "coredns", Version: corednsLatest},
+				{Name: "kube-proxy", Version: kubeProxyDefault},
+				{Name: "vpc-cni", Version: vpcCNIDefault, Status: "DEGRADED"},
+			},
+			objects: rolledOut,
+			wantErr: []string{"addon vpc-cni v1.20.0-eksbuild.1 is DEGRADED"},
+		},
+		{
+			name: "drifted from the Terraform state",
+			addons: []fakeaws.Addon{
+				{Name: "coredns", Version: corednsDefault},
+				{Name: "kube-proxy", Version: kubeProxyDefault},
+				{Name: "vpc-cni", Version: vpcCNIDefault},
+			},
+			objects: rolledOut,
+			wantErr: []string{"Addon coredns version in EKS should match the cluster_addons output"},
+		},
+		{
+			name:    "DaemonSet still rolling",
+			addons:  healthy,
+			objects: []runtime.Object{corednsDeployment(2), daemonSet("kube-proxy", 3, 3), daemonSet("aws-node", 3, 2)},
+			wantErr: []string{"Addon vpc-cni DaemonSet aws-node should be rolled out"},
+		},
+		{
+			name:    "Deployment missing",
+			addons:  healthy,
+			objects: []runtime.Object{daemonSet("kube-proxy", 3, 3), daemonSet("aws-node", 3, 3)},
+			wantErr: []string{"Addon coredns Deployment coredns should be rolled out"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := fakeAddonEKS(t, tt.addons...)
+
+			rt := &recordingT{TB: t}
+			found := validateAddonHealth(rt, newFakeClusterClients(t, fake, tt.objects...), "demo", "1.33", outputs)
+			require.Len(t, found, 3)
+			assert.Equal(t, []string{"coredns", "kube-proxy", "vpc-cni"}, []string{found[0].Name, found[1].Name, found[2].Name})
+
+			require.Len(t, rt.errors, len(tt.wantErr))
+			for i, want := range tt.wantErr {
+				assert.Contains(t, rt.errors[i], want)
+			}
+		})
+	}
+}
+
+func TestValidateAddonHealthFlagsNonDefaultVersions(t *testing.T) {
+	fake := fakeAddonEKS(t,
+		fakeaws.Addon{Name: "coredns", Version: corednsLatest},
+		fakeaws.Addon{Name: "vpc-cni", Version: vpcCNIDefault},
+	)
+	clients := newFakeClusterClients(t, fake, corednsDeployment(2), daemonSet("aws-node", 3, 3))
+
+	found := validateAddonHealth(t, clients, "demo", "1.33", map[string]clusterAddon{
+		"coredns": {AddonName: "coredns", AddonVersion: corednsLatest},
+		"vpc-cni": {AddonName: "vpc-cni", AddonVersion: vpcCNIDefault},
+	})
+	assert.Equal(t, []addonHealth{
+		{Name: "coredns", Version: corednsLatest, DefaultVersion: corednsDefault},
+		{Name: "vpc-cni", Version: vpcCNIDefault, DefaultVersion: vpcCNIDefault},
+	}, found)
+	assert.Equal(t, []string{"coredns " + corednsLatest + " (default " + corednsDefault + ")"}, nonDefaultAddons(found))
+}
+
+func TestValidateAddonHealthUnknownAddon(t *testing.T) {
+	fake := fakeAddonEKS(t, fakeaws.Addon{Name: "aws-ebs-csi-driver", Version: "v1.40.0-eksbuild.1"})
+	fake.AddAddonVersion("aws-ebs-csi-driver", "v1.40.0-eksbuild.1", "1.33")
+
+	found := validateAddonHealth(t, newFakeClusterClients(t, fake), "demo", "1.33", map[string]clusterAddon{
+		"aws-ebs-csi-driver": {AddonName: "aws-ebs-csi-driver", AddonVersion: "v1.40.0-eksbuild.1"},
+	})
+	require.Len(t, found, 1)
+	assert.Equal(t, "", found[0].DefaultVersion, "EKS marks no default")
+}
+
+func TestDescribeAddonVersions(t *testing.T) {
+	fake := useFakeEKS(t)
+	fake.AddAddonVersion("kube-proxy", "v1.32.0-eksbuild.2", "1.32")
+	fake.AddAddonVersion("kube-proxy", "v1.33.0-eksbuild.2", "1.33")
+	fake.AddAddonVersion("kube-proxy", "v1.33.1-eksbuild.1", "1.33")
+	require.NoError(t, fake.SetDefaultAddonVersion("kube-proxy", "v1.33.0-eksbuild.2", "1.33"))
+
+	clients := newFakeClusterClients(t, fake)
+	available, err := describeAddonVersions(clients.EKS, "kube-proxy", "1.33")
+	require.NoError(t, err)
+	assert.Equal(t, &addonVersions{
+		Compatible: map[string]bool{"v1.33.0-eksbuild.2": true, "v1.33.1-eksbuild.1": true},
+		Default:    "v1.33.0-eksbuild.2",
+	}, available)
+
+	_, err = describeAddonVersions(clients.EKS, "kube-proxy", "1.34")
+	assert.ErrorContains(t, err, "EKS lists no kube-proxy version compatible with 1.34")
+}
+
+func TestWaitForAddonActive(t *testing.T) {
+	serverError := fakeaws.APIError{Status: http.StatusInternalServerError, Code: "ServerException", Message: "internal error"}
+
+	tests := []struct {
+		name      string
+		addon     string
+		status    string
+		fault     *fakeaws.APIError
+		faults    int
+		wantErr   string
+		wantCalls int
+	}{
+		{name: "active", addon: "coredns", wantCalls: 1},
+		{name: "throttled then active", addon: "coredns", fault: &fakeaws.ThrottlingError, faults: 2, wantCalls: 3},
+		{name: "server error then active", addon: "coredns", fault: &serverError, faults: 1, wantCalls: 2},
+		{name: "degraded stops polling", addon: "coredns", status: "DEGRADED", wantErr: "addon coredns " + corednsLatest + " is DEGRADED", wantCalls: 1},
+		{name: "missing addon stops polling", addon: "aws-ebs-csi-driver", wantErr: "failed to describe addon aws-ebs-csi-driver", wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := fakeAddonEKS(t, fakeaws.Addon{Name: "coredns", Version: corednsLatest, Status: tt.status})
+			if tt.fault != nil {
+				fake.InjectError(fakeaws.OpDescribeAddon, tt.faults, *tt.fault)
+			}
+
+			// The SDK's own retries are off, so every fault reaches waitForAddonActive.
+			sess, err := session.NewSession(&aws.Config{
+				Region:      aws.String("us-east-1"),
+				Endpoint:    aws.String(fake.URL()),
+				Credentials: credentials.NewStaticCredentials("AKIDTEST", "secret", ""),
+				MaxRetries:  aws.Int(0),
+			})
+			require.NoError(t, err, "Failed to create AWS session")
+
+			a, err := waitForAddonActive(t, eks.New(sess), "demo", tt.addon)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, corednsLatest, aws.StringValue(a.AddonVersion))
+			}
+			assert.Equal(t, tt.wantCalls, fake.Calls(fakeaws.OpDescribeAddon))
+		})
+	}
+}
@@ -0,0 +1,219 @@
+// Addon health check. Every addon in the cluster_addons output must be ACTIVE
+// in EKS at the version Terraform recorded, and its kube-system workload must
+// be fully rolled out. Versions other than EKS's default for the cluster
+// version are recorded on the check's report result as non_default_versions,
+// not failed: examples/eks installs most_recent, which is usually newer than
+// the default.
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	registerCheck(checkFunc{"addons", func(t testing.TB, env *CheckEnv) {
+		addons, err := parseClusterAddons(terraform.OutputJson(t, env.Options, "cluster_addons"))
+		require.NoError(t, err)
+		if len(addons) == 0 {
+			t.Skip("The cluster_addons output is empty")
+		}
+		found := validateAddonHealth(t, env.Clients, env.ClusterName, env.Version, addons)
+		if nonDefault := nonDefaultAddons(found); len(nonDefault) > 0 {
+			env.SetProperty("non_default_versions", strings.Join(nonDefault, ", "))
+		}
+	}}, false)
+}
+
+// clusterAddon is the part of a cluster_addons output entry the check uses.
+type clusterAddon struct {
+	AddonName    string `json:"addon_name"`
+	AddonVersion string `json:"addon_version"`
+}
+
+// parseClusterAddons decodes the cluster_addons output, keyed by addon name.
+func parseClusterAddons(raw string) (map[string]clusterAddon, error) {
+	var addons map[string]clusterAddon
+	if err := json.Unmarshal([]byte(raw), &addons); err != nil {
+		return nil, fmt.Errorf("failed to parse cluster_addons output: %w", err)
+	}
+	return addons, nil
+}
+
+// addonWorkload is the kube-system workload an addon runs as.
+type addonWorkload struct {
+	kind string // "Deployment" or "DaemonSet"
+	name string
+}
+
+// addonWorkloads maps the addons examples/eks installs to their workloads.
+// Addons not listed are only checked in EKS.
+var addonWorkloads = map[string]addonWorkload{
+	"coredns":    {kind: "Deployment", name: "coredns"},
+	"kube-proxy": {kind: "DaemonSet", name: "kube-proxy"},
+	"vpc-cni":    {kind: "DaemonSet", name: "aws-node"},
+}
+
+// addonHealth is what validateAddonHealth found for one addon.
+type addonHealth struct {
+	Name           string
+	Version        string
+	DefaultVersion string
+}
+
+// validateAddonHealth checks each addon in EKS and in the cluster, and
+// returns what it found, sorted by name.
+func validateAddonHealth(t testing.TB, clients *ClusterClients, clusterName, clusterVersion string, addons map[string]clusterAddon) []addonHealth {
+	t.Helper()
+
+	names := make([]string, 0, len(addons))
+	for name := range addons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var found []addonHealth
+	for _, name := range names {
+		h := addonHealth{Name: name}
+
+		a, err := waitForAddonActive(t, clients.EKS, clusterName, name)
+		if a != nil {
+			h.Version = aws.StringValue(a.AddonVersion)
+		}
+		if assert.NoError(t, err, "Addon %s should be ACTIVE", name) {
+			assert.Equal(t, addons[name].AddonVersion, h.Version,
+				"Addon %s version in EKS should match the cluster_addons output", name)
+		}
+
+		switch w, ok := addonWorkloads[name]; {
+		case !ok:
+			t.Logf("No known workload for addon %s; checked in EKS only", name)
+		case w.kind == "DaemonSet":
+			_, err := waitForDaemonSetRolledOut(t, clients.Kubernetes, "kube-system", w.name)
+			assert.NoError(t, err, "Addon %s DaemonSet %s should be rolled out", name, w.name)
+		default:
+			_, err := waitForDeploymentAvailable(t, clients.Kubernetes, "kube-system", w.name)
+			assert.NoError(t, err, "Addon %s Deployment %s should be rolled out", name, w.name)
+		}
+
+		available, err := describeAddonVersions(clients.EKS, name, clusterVersion)
+		if assert.NoError(t, err) {
+			h.DefaultVersion = available.Default
+		}
+		found = append(found, h)
+	}
+	return found
+}
+
+// nonDefaultAddons describes each addon running a version other than EKS's
+// default, e.g. "vpc-cni v1.19.2-eksbuild.1 (default v1.19.0-eksbuild.1)".
+// Addons whose versions couldn't be read are left out.
+func nonDefaultAddons(found []addonHealth) []string {
+	var nonDefault []string
+	for _, h := range found {
+		if h.Version != "" && h.DefaultVersion != "" && h.Version != h.DefaultVersion {
+			nonDefault = append(nonDefault, fmt.Sprintf("%s %s (default %s)", h.Name, h.Version, h.DefaultVersion))
+		}
+	}
+	return nonDefault
+}
+
+// waitForAddonActive polls DescribeAddon until the addon is ACTIVE and
+// returns it. A failed or degraded addon stops polling, since EKS doesn't
+// repair it on its own. Throttling and other retryable errors are polled
+// through; any other DescribeAddon error stops polling.
+func waitForAddonActive(t testing.TB, eksSvc eksiface.EKSAPI, clusterName, addon string) (*eks.Addon, error) {
+	t.Helper()
+
+	var a *eks.Addon
+	_, err := retry.DoWithRetryE(t, "Wait for addon "+addon, sharedMaxRetries, sharedRetryInterval, func() (string, error) {
+		out, err := eksSvc.DescribeAddon(&eks.DescribeAddonInput{
+			ClusterName: aws.String(clusterName),
+			AddonName:   aws.String(addon),
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to describe addon %s: %w", addon, err)
+			if retryableAWSError(err) {
+				return "", err
+			}
+			return "", retry.FatalError{Underlying: err}
+		}
+
+		a = out.Addon
+		v, status := aws.StringValue(a.AddonVersion), aws.StringValue(a.Status)
+		switch status {
+		case eks.AddonStatusActive:
+			return v, nil
+		case eks.AddonStatusCreateFailed, eks.AddonStatusUpdateFailed, eks.AddonStatusDegraded:
+			return "", retry.FatalError{Underlying: fmt.Errorf("addon %s %s is %s", addon, v, status)}
+		}
+		return "", fmt.Errorf("addon %s %s is %s, waiting for ACTIVE", addon, v, status)
+	})
+	return a, err
+}
+
+// retryableAWSError reports whether an AWS call may succeed if repeated:
+// throttling, a 5xx, or a transient network failure.
+func retryableAWSError(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+	if reqErr, ok := aerr.(awserr.RequestFailure); ok && reqErr.StatusCode() >= 500 {
+		return true
+	}
+	return request.IsErrorThrottle(aerr) || request.IsErrorRetryable(aerr)
+}
+
+// addonVersions are the versions of an addon EKS offers for one cluster
+// version.
+type addonVersions struct {
+	Compatible map[string]bool
+	Default    string
+}
+
+// describeAddonVersions returns the versions of addon that EKS supports on
+// clusterVersion, and which one it installs by default.
+func describeAddonVersions(eksSvc eksiface.EKSAPI, addon, clusterVersion string) (*addonVersions, error) {
+	found := &addonVersions{Compatible: make(map[string]bool)}
+	err := eksSvc.DescribeAddonVersionsPages(&eks.DescribeAddonVersionsInput{
+		AddonName:         aws.String(addon),
+		KubernetesVersion: aws.String(clusterVersion),
+	}, func(page *eks.DescribeAddonVersionsOutput, _ bool) bool {
+		for _, info := range page.Addons {
+			for _, av := range info.AddonVersions {
+				for _, compat := range av.Compatibilities {
+					if aws.StringValue(compat.ClusterVersion) != clusterVersion {
+						continue
+					}
+					found.Compatible[aws.StringValue(av.AddonVersion)] = true
+					if aws.BoolValue(compat.DefaultVersion) {
+						found.Default = aws.StringValue(av.AddonVersion)
+					}
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe %s versions: %w", addon, err)
+	}
+	if len(found.Compatible) == 0 {
+		return nil, fmt.Errorf("EKS lists no %s version compatible with %s", addon, clusterVersion)
+	}
+	return found, nil
+}
@@ -0,0 +1,117 @@
+// Offline tests for the check registry and runner.
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/apex/terratest-eks/report"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withChecks replaces the registry for the test.
+func withChecks(t *testing.T, checks ...registeredCheck) {
+	t.Helper()
+	registry := checkRegistry
+	checkRegistry = checks
+	t.Cleanup(func() { checkRegistry = registry })
+}
+
+func namesOf(checks []Check) []string {
+	out := make([]string, len(checks))
+	for i, c := range checks {
+		out[i] = c.Name()
+	}
+	return out
+}
+
+func TestSelectChecks(t *testing.T) {
+	noop := func(testing.TB, *CheckEnv) {}
+	withChecks(t,
+		registeredCheck{awsOnlyCheck{checkFunc{"status", noop}}, true},
+		registeredCheck{checkFunc{"irsa", noop}, false},
+		registeredCheck{checkFunc{"nodes", noop}, true},
+		registeredCheck{awsOnlyCheck{checkFunc{"logging", noop}}, false},
+	)
+
+	tests := []struct {
+		spec    string
+		private bool
+		want    []string
+		wantErr string
+	}{
+		{spec: "", want: []string{"status", "nodes"}},
+		{spec: "all", want: []string{"status", "irsa", "nodes", "logging"}},
+		{spec: "nodes, irsa", want: []string{"irsa", "nodes"}},
+		{spec: "irsa,irsa", want: []string{"irsa"}},
+		{spec: "dns,nodes,ingress", wantErr: "unknown check(s) dns, ingress; registered: status, irsa, nodes, logging"},
+		{spec: " , ", wantErr: "no checks selected"},
+		{spec: "", private: true, want: []string{"status"}},
+		{spec: "all", private: true, want: []string{"status", "logging"}},
+		{spec: "status,logging", private: true, want: []string{"status", "logging"}},
+		{spec: "status,nodes,irsa", private: true, wantErr: "check(s) irsa, nodes use the Kubernetes API"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q private=%t", tt.spec, tt.private), func(t *testing.T) {
+			got, err := selectChecks(tt.spec, tt.private)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, namesOf(got))
+		})
+	}
+}
+
+func TestDefaultChecks(t *testing.T) {
+	checks, err := selectChecks("", false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"endpoint", "status", "nodegroups", "nodes", "workload"}, namesOf(checks))
+
+	checks, err = selectChecks("all", true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"endpoint", "status", "nodegroups", "encryption", "logging"}, namesOf(checks),
+		"every check a private-only cluster can run")
+}
+
+func TestRegisterCheckRejectsDuplicates(t *testing.T) {
+	withChecks(t)
+	registerCheck(checkFunc{"status", func(testing.TB, *CheckEnv) {}}, true)
+	assert.PanicsWithValue(t, `check "status" registered twice`, func() {
+		registerCheck(checkFunc{"status", func(testing.TB, *CheckEnv) {}}, false)
+	})
+}
+
+func TestRunChecks(t *testing.T) {
+	fake := useFakeEKS(t)
+	env := &CheckEnv{Clients: newFakeClusterClients(t, fake, node("node-1", "True")), ClusterName: "demo", Version: "1.33"}
+
+	var ran []string
+	checks := []Check{
+		checkFunc{"skipped", func(t testing.TB, _ *CheckEnv) {
+			ran = append(ran, "skipped")
+			t.Skip("not applicable")
+		}},
+		checkFunc{"nodes", func(t testing.TB, env *CheckEnv) {
+			ran = append(ran, "nodes")
+			validateNodeReadiness(t, env.Clients)
+			env.SetProperty("ready", "1")
+		}},
+	}
+
+	rc := report.New(t.Name()).Case("EKS_1_33")
+	runChecks(t, rc, checks, env)
+
+	assert.Equal(t, []string{"skipped", "nodes"}, ran, "a check stopping early doesn't skip the rest")
+	require.Len(t, rc.Checks, 2)
+	assert.Equal(t, report.CheckResult{Name: "skipped", Status: report.StatusSkipped}, withoutDuration(rc.Checks[0]))
+	assert.Equal(t, report.CheckResult{Name: "nodes", Status: report.StatusPassed, Properties: map[string]string{"ready": "1"}},
+		withoutDuration(rc.Checks[1]), "properties are recorded on the check that set them")
+}
+
+func withoutDuration(r report.CheckResult) report.CheckResult {
+	r.Duration = 0
+	return r
+}
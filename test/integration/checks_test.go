@@ -0,0 +1,172 @@
+// Post-deploy checks run against each matrix cluster. A check is registered
+// by name from an init function, so adding one (DNS, IRSA, storage, ingress)
+// means adding a file here, not editing eks_version_test.go. EKS_CHECKS picks
+// which run.
+package test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/apex/terratest-eks/report"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// Check is one named post-deploy validation. Run reports failures through t;
+// it may stop with FailNow or Skip without affecting other checks.
+type Check interface {
+	Name() string
+	Run(t testing.TB, env *CheckEnv)
+}
+
+// CheckEnv is what a check gets to inspect: the deployed cluster, clients for
+// it, and the Terraform options it was applied with (for extra outputs).
+type CheckEnv struct {
+	Config      *testConfig
+	Options     *terraform.Options
+	Outputs     *eksOutputs
+	Clients     *ClusterClients
+	ClusterName string
+	Version     string
+
+	check  string       // the running check, set by runChecks
+	report *report.Case // where SetProperty records; nil offline
+}
+
+// SetProperty records a finding on the running check's report result, for
+// what a check notes without failing.
+func (e *CheckEnv) SetProperty(key, value string) {
+	if e.report != nil {
+		e.report.SetCheckProperty(e.check, key, value)
+	}
+}
+
+// checkFunc adapts a function to the Check interface.
+type checkFunc struct {
+	name string
+	fn   func(t testing.TB, env *CheckEnv)
+}
+
+func (c checkFunc) Name() string                    { return c.name }
+func (c checkFunc) Run(t testing.TB, env *CheckEnv) { c.fn(t, env) }
+
+// awsOnlyCheck marks a check that only calls AWS APIs, so it can run against
+// a private-only cluster whose Kubernetes API the runner can't reach.
+type awsOnlyCheck struct{ Check }
+
+// usesKubernetes reports whether c needs the cluster's Kubernetes API.
+func usesKubernetes(c Check) bool {
+	_, awsOnly := c.(awsOnlyCheck)
+	return !awsOnly
+}
+
+type registeredCheck struct {
+	check     Check
+	byDefault bool
+}
+
+// checkRegistry holds every registered check in registration order: the
+// built-in checks below, then those registered from init functions, in file
+// name order.
+var checkRegistry = []registeredCheck{
+	{awsOnlyCheck{checkFunc{"endpoint", func(t testing.TB, env *CheckEnv) {
+		validateClusterEndpoint(t, env.Clients, env.ClusterName, env.Outputs.ClusterEndpoint, endpointAccessFromOptions(env.Options))
+	}}}, true},
+	{awsOnlyCheck{checkFunc{"status", func(t testing.TB, env *CheckEnv) {
+		validateClusterStatus(t, env.Clients, env.ClusterName, env.Version)
+	}}}, true},
+	{awsOnlyCheck{checkFunc{"nodegroups", func(t testing.TB, env *CheckEnv) {
+		validateNodegroupsActive(t, env.Clients, env.ClusterName)
+	}}}, true},
+	{checkFunc{"nodes", func(t testing.TB, env *CheckEnv) {
+		validateNodeReadiness(t, env.Clients)
+	}}, true},
+	{checkFunc{"workload", func(t testing.TB, env *CheckEnv) {
+		validateWorkloadDeployment(t, env.Clients)
+	}}, true},
+}
+
+// registerCheck adds c to the registry. Checks with byDefault run when
+// EKS_CHECKS is unset; the others only when named. It panics on a duplicate
+// name, since it runs from init.
+func registerCheck(c Check, byDefault bool) {
+	for _, r := range checkRegistry {
+		if r.check.Name() == c.Name() {
+			panic(fmt.Sprintf("check %q registered twice", c.Name()))
+		}
+	}
+	checkRegistry = append(checkRegistry, registeredCheck{check: c, byDefault: byDefault})
+}
+
+// selectChecks resolves a comma-separated list of check names, in registry
+// order. An empty spec selects the default checks and "all" every check.
+// Unknown names are an error listing the registered ones. With
+// privateEndpoint, checks that use the Kubernetes API are left out of the
+// defaults and "all", and naming one is an error.
+func selectChecks(spec string, privateEndpoint bool) ([]Check, error) {
+	spec = strings.TrimSpace(spec)
+	want := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			want[name] = true
+		}
+	}
+
+	var selected []Check
+	var unreachable []string
+	for _, r := range checkRegistry {
+		name := r.check.Name()
+		switch {
+		case privateEndpoint && usesKubernetes(r.check):
+			if want[name] {
+				unreachable = append(unreachable, name)
+			}
+		case (spec == "" && r.byDefault) || want["all"] || want[name]:
+			selected = append(selected, r.check)
+		}
+		delete(want, name)
+	}
+	delete(want, "all")
+
+	if len(want) > 0 {
+		unknown := make([]string, 0, len(want))
+		for name := range want {
+			unknown = append(unknown, name)
+		}
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown check(s) %s; registered: %s", strings.Join(unknown, ", "), strings.Join(checkNames(), ", "))
+	}
+	if len(unreachable) > 0 {
+		return nil, fmt.Errorf("check(s) %s use the Kubernetes API, which a private-only endpoint doesn't expose to the runner", strings.Join(unreachable, ", "))
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no checks selected by %q", spec)
+	}
+	return selected, nil
+}
+
+// checkNames returns the names of every registered check.
+func checkNames() []string {
+	names := make([]string, len(checkRegistry))
+	for i, r := range checkRegistry {
+		names[i] = r.check.Name()
+	}
+	return names
+}
+
+// runChecks runs each check as a subtest of t named after it, so one check
+// stopping with FailNow doesn't skip the rest, and records each result on rc.
+func runChecks(t *testing.T, rc *report.Case, checks []Check, env *CheckEnv) {
+	t.Helper()
+
+	for _, c := range checks {
+		t.Run(c.Name(), func(ct *testing.T) {
+			tracked := rc.Track(ct)
+			checkEnv := *env
+			checkEnv.check, checkEnv.report = c.Name(), rc
+			rc.Check(tracked, c.Name(), func() { c.Run(tracked, &checkEnv) })
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/apex/terratest-eks/fakeaws"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+// ClusterClients bundles the API clients the validation helpers use for one
+// cluster, so the same helpers run against real AWS or against fakes.
+type ClusterClients struct {
+	Kubernetes kubernetes.Interface
+	EKS        eksiface.EKSAPI
+	EC2        ec2iface.EC2API
+	IAM        iamiface.IAMAPI
+	KMS        kmsiface.KMSAPI
+	Logs       cloudwatchlogsiface.CloudWatchLogsAPI
+}
+
+// newAWSSession creates an AWS session for region from the shared config
+// (AWS_PROFILE) or environment credentials.
+func newAWSSession(region string) (*session.Session, error) {
+	return session.NewSessionWithOptions(session.Options{
+		Config:            aws.Config{Region: aws.String(region)},
+		SharedConfigState: session.SharedConfigEnable,
+	})
+}
+
+// newEKSClient creates an EKS client for region. A non-empty endpoint overrides
+// the default AWS endpoint (e.g. a fakeaws server or AWS_ENDPOINT_URL_EKS).
+func newEKSClient(region, endpoint string) (eksiface.EKSAPI, error) {
+	sess, err := newAWSSession(region)
+	if err != nil {
+		return nil, err
+	}
+	return eksClientFromSession(sess, endpoint), nil
+}
+
+func eksClientFromSession(sess *session.Session, endpoint string) eksiface.EKSAPI {
+	if endpoint == "" {
+		return eks.New(sess)
+	}
+	return eks.New(sess, &aws.Config{Endpoint: aws.String(endpoint)})
+}
+
+// newClusterClients creates real AWS and Kubernetes clients for a deployed cluster.
+func newClusterClients(t testing.TB, cfg *testConfig, out *eksOutputs) *ClusterClients {
+	t.Helper()
+
+	sess, err := newAWSSession(cfg.AWSRegion)
+	require.NoError(t, err, "Failed to create AWS session")
+
+	return &ClusterClients{
+		Kubernetes: getKubernetesClient(t, cfg.AWSRegion, out.ClusterName, out.ClusterEndpoint, out.ClusterCAData),
+		EKS:        eksClientFromSession(sess, cfg.EKSEndpoint),
+		EC2:        ec2.New(sess),
+		IAM:        iam.New(sess),
+		KMS:        kms.New(sess),
+		Logs:       cloudwatchlogs.New(sess),
+	}
+}
+
+// newFakeClusterClients returns clients backed by a fakeaws EKS server and a
+// fake Kubernetes clientset seeded with objects. The other clients are left
+// nil for tests to fill in with their own stubs or fakes.
+func newFakeClusterClients(t testing.TB, fakeEKS *fakeaws.EKS, objects ...runtime.Object) *ClusterClients {
+	t.Helper()
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("AKIDTEST", "secret", ""),
+	})
+	require.NoError(t, err, "Failed to create AWS session")
+
+	return &ClusterClients{
+		Kubernetes: k8sfake.NewClientset(objects...),
+		EKS:        eksClientFromSession(sess, fakeEKS.URL()),
+	}
+}
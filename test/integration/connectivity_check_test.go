@@ -0,0 +1,275 @@
+// Offline tests for the connectivity check, run against client-go's fake
+// clientset standing in for the kubelet, kube-proxy, CoreDNS and the VPC CNI.
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const (
+	testServerPodIP = "10.0.1.25"
+	testClusterIP   = "172.20.14.7"
+)
+
+// fakeNetwork is how the simulated cluster network answers probe pods.
+type fakeNetwork struct {
+	Unreachable   map[string]bool // probe names whose traffic is dropped
+	Refused       map[string]bool // probe names whose connections are refused
+	RejectPods    map[string]bool // probe names whose pods the API server won't create
+	DNSAnswer     string          // address CoreDNS answers with; the ClusterIP if empty
+	EnforcePolicy bool            // whether a NetworkPolicy blocks pod-to-pod traffic
+}
+
+// withFakeNetwork gives Services the ClusterIP testClusterIP and makes pod
+// GETs report the server as Ready on testServerPodIP and each probe pod as
+// completed per net.
+func withFakeNetwork(t *testing.T, clients *ClusterClients, net fakeNetwork) {
+	t.Helper()
+
+	cs, ok := clients.Kubernetes.(*k8sfake.Clientset)
+	require.True(t, ok, "expected a fake clientset")
+
+	cs.PrependReactor("create", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		svc := action.(k8stesting.CreateAction).GetObject().(*corev1.Service)
+		svc.Spec.ClusterIP = testClusterIP
+		return false, nil, nil
+	})
+	cs.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		if net.RejectPods[pod.Labels["probe"]] {
+			return true, nil, errors.New(`pods "` + pod.Name + `" is forbidden: exceeded quota`)
+		}
+		return false, nil, nil
+	})
+	cs.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		get := action.(k8stesting.GetAction)
+		obj, err := cs.Tracker().Get(get.GetResource(), get.GetNamespace(), get.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		pod := obj.(*corev1.Pod).DeepCopy()
+
+		if pod.Labels["role"] == "server" {
+			pod.Status.Phase = corev1.PodRunning
+			pod.Status.PodIP = testServerPodIP
+			pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+			return true, pod, nil
+		}
+
+		// The clientset is locked while reactors run, so read the tracker directly.
+		blocked := false
+		probe := pod.Labels["probe"]
+		if probe == probePodToPod || probe == probeNetworkPol {
+			policies, err := cs.Tracker().List(networkingv1.SchemeGroupVersion.WithResource("networkpolicies"),
+				networkingv1.SchemeGroupVersion.WithKind("NetworkPolicy"), pod.Namespace)
+			require.NoError(t, err)
+			blocked = net.EnforcePolicy && len(policies.(*networkingv1.NetworkPolicyList).Items) > 0
+		}
+
+		pod.Status.Phase = corev1.PodSucceeded
+		msg := ""
+		switch {
+		case net.Refused[probe]:
+			pod.Status.Phase = corev1.PodFailed
+			msg = "wget: can't connect to remote host (" + testServerPodIP + "): Connection refused"
+		case net.Unreachable[probe] || blocked:
+			pod.Status.Phase = corev1.PodFailed
+			msg = "wget: download timed out"
+		case probe == probeDNS:
+			answer := net.DNSAnswer
+			if answer == "" {
+				answer = testClusterIP
+			}
+			msg = "Server:\t\t172.20.0.10\nAddress:\t172.20.0.10:53\n\nName:\tsvc.default.svc.cluster.local\nAddress: " + answer + "\n"
+		}
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:  "probe",
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: msg}},
+		}}
+		return true, pod, nil
+	})
+}
+
+func zonedNode(name, zone string) *corev1.Node {
+	n := node(name, corev1.ConditionTrue)
+	n.Labels = map[string]string{zoneLabel: zone, hostnameLabel: name}
+	return n
+}
+
+// awsNode returns the VPC CNI DaemonSet, with the network policy agent
+// enforcing policies if enforce.
+func awsNode(enforce bool) *appsv1.DaemonSet {
+	arg := "--enable-network-policy=false"
+	if enforce {
+		arg = networkPolicyEnabled
+	}
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws-node", Namespace: "kube-system"},
+		Spec: appsv1.DaemonSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "aws-node"},
+				{Name: networkPolicyAgent, Args: []string{"--enable-ipv6=false", arg}},
+			},
+		}}},
+	}
+}
+
+func TestValidateConnectivity(t *testing.T) {
+	tests := []struct {
+		name       string
+		awsNode    *appsv1.DaemonSet
+		net        fakeNetwork
+		wantProbes []string
+		wantErr    []string // substrings of the recorded failures, in order
+	}{
+		{
+			name:       "policies enforced",
+			awsNode:    awsNode(true),
+			net:        fakeNetwork{EnforcePolicy: true},
+			wantProbes: []string{probePodToPod, probePodToService, probeDNS, probeNetworkPol},
+		},
+		{
+			name:       "policies not enabled",
+			awsNode:    awsNode(false),
+			wantProbes: []string{probePodToPod, probePodToService, probeDNS},
+		},
+		{
+			name:       "no aws-node",
+			wantProbes: []string{probePodToPod, probePodToService, probeDNS},
+		},
+		{
+			name:       "service unreachable",
+			awsNode:    awsNode(false),
+			net:        fakeNetwork{Unreachable: map[string]bool{probePodToService: true}},
+			wantProbes: []string{probePodToPod, probePodToService, probeDNS},
+			wantErr:    []string{"Connectivity pod-to-service from node-b to " + testClusterIP},
+		},
+		{
+			name:       "wrong DNS answer",
+			awsNode:    awsNode(false),
+			net:        fakeNetwork{DNSAnswer: "172.20.99.99"},
+			wantProbes: []string{probePodToPod, probePodToService, probeDNS},
+			wantErr:    []string{"Connectivity dns from node-b"},
+		},
+		{
+			name:       "policy not enforced",
+			awsNode:    awsNode(true),
+			wantProbes: []string{probePodToPod, probePodToService, probeDNS, probeNetworkPol},
+			wantErr:    []string{"Connectivity network-policy from node-b to " + testServerPodIP},
+		},
+		{
+			name:       "policy probe pod not created",
+			awsNode:    awsNode(true),
+			net:        fakeNetwork{EnforcePolicy: true, RejectPods: map[string]bool{probeNetworkPol: true}},
+			wantProbes: []string{probePodToPod, probePodToService, probeDNS, probeNetworkPol},
+			wantErr:    []string{"failed to create pod"},
+		},
+		{
+			name:       "policy probe refused rather than dropped",
+			awsNode:    awsNode(true),
+			net:        fakeNetwork{EnforcePolicy: true, Refused: map[string]bool{probeNetworkPol: true}},
+			wantProbes: []string{probePodToPod, probePodToService, probeDNS, probeNetworkPol},
+			wantErr:    []string{"Connection refused"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := []runtime.Object{zonedNode("node-a", "us-west-2a"), zonedNode("node-b", "us-west-2b")}
+			if tt.awsNode != nil {
+				objects = append(objects, tt.awsNode)
+			}
+			fake := useFakeEKS(t)
+			clients := newFakeClusterClients(t, fake, objects...)
+			withFakeNetwork(t, clients, tt.net)
+
+			rt := &recordingT{TB: t}
+			results := validateConnectivity(rt, clients)
+
+			var probes []string
+			for _, r := range results {
+				probes = append(probes, r.Probe)
+				assert.Equal(t, "node-b", r.From, r.Probe)
+				assert.Equal(t, "node-a", r.To, r.Probe)
+			}
+			assert.Equal(t, tt.wantProbes, probes)
+
+			require.Len(t, rt.errors, len(tt.wantErr), "%v", rt.errors)
+			for i, want := range tt.wantErr {
+				assert.Contains(t, rt.errors[i], want)
+			}
+
+			ctx := context.Background()
+			pods, err := clients.Kubernetes.CoreV1().Pods(connectivityNamespace).List(ctx, metav1.ListOptions{})
+			require.NoError(t, err)
+			assert.Empty(t, pods.Items, "every pod is deleted afterwards")
+			svcs, err := clients.Kubernetes.CoreV1().Services(connectivityNamespace).List(ctx, metav1.ListOptions{})
+			require.NoError(t, err)
+			assert.Empty(t, svcs.Items, "the Service is deleted afterwards")
+			policies, err := clients.Kubernetes.NetworkingV1().NetworkPolicies(connectivityNamespace).List(ctx, metav1.ListOptions{})
+			require.NoError(t, err)
+			assert.Empty(t, policies.Items, "the NetworkPolicy is deleted afterwards")
+		})
+	}
+}
+
+func TestPickConnectivityNodes(t *testing.T) {
+	tests := []struct {
+		name       string
+		nodes      []runtime.Object
+		wantServer string
+		wantClient string
+		wantErr    string
+	}{
+		{
+			name: "prefers another zone",
+			nodes: []runtime.Object{
+				zonedNode("node-a", "us-west-2a"), zonedNode("node-b", "us-west-2a"), zonedNode("node-c", "us-west-2b"),
+			},
+			wantServer: "node-a",
+			wantClient: "node-c",
+		},
+		{
+			name:       "same zone",
+			nodes:      []runtime.Object{zonedNode("node-b", "us-west-2a"), zonedNode("node-a", "us-west-2a")},
+			wantServer: "node-a",
+			wantClient: "node-b",
+		},
+		{
+			name:       "one Ready node",
+			nodes:      []runtime.Object{zonedNode("node-a", "us-west-2a"), node("node-b", corev1.ConditionFalse)},
+			wantServer: "node-a",
+			wantClient: "node-a",
+		},
+		{
+			name:    "no Ready nodes",
+			nodes:   []runtime.Object{node("node-a", corev1.ConditionUnknown)},
+			wantErr: "no Ready nodes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client, err := pickConnectivityNodes(k8sfake.NewClientset(tt.nodes...))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantServer, server.Name)
+			assert.Equal(t, tt.wantClient, client.Name)
+		})
+	}
+}
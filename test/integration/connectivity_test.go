@@ -0,0 +1,347 @@
+// Connectivity check. A server pod and a ClusterIP Service in front of it are
+// deployed on one node, and probe pods on another, in a different zone (and
+// so private subnet) when the cluster has one. The probes must reach the
+// server directly and through the Service, and resolve the Service through
+// CoreDNS. When the VPC CNI enforces network policies, a deny-all ingress
+// NetworkPolicy must then cut the server off.
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+func init() {
+	registerCheck(checkFunc{"connectivity", func(t testing.TB, env *CheckEnv) {
+		validateConnectivity(t, env.Clients)
+	}}, false)
+}
+
+// Connectivity fixtures.
+const (
+	connectivityNamespace = "default"
+	connectivityApp       = "terratest-connectivity"
+	serverImage           = "nginx:alpine"
+	probeImage            = "busybox:1.36"
+	probeTimeoutSeconds   = 5
+	policyProbeAttempts   = 6
+	zoneLabel             = "topology.kubernetes.io/zone"
+	hostnameLabel         = "kubernetes.io/hostname"
+	networkPolicyAgent    = "aws-network-policy-agent"
+	networkPolicyEnabled  = "--enable-network-policy=true"
+)
+
+// Probe names, also set as the probe label of each probe pod.
+const (
+	probePodToPod     = "pod-to-pod"
+	probePodToService = "pod-to-service"
+	probeDNS          = "dns"
+	probeNetworkPol   = "network-policy"
+)
+
+// connectivityResult is the outcome of one probe.
+type connectivityResult struct {
+	Probe    string
+	From, To string // node names
+	Target   string
+	Err      error
+}
+
+// validateConnectivity runs the connectivity probes, asserts each one and
+// returns the results.
+func validateConnectivity(t testing.TB, clients *ClusterClients) []connectivityResult {
+	t.Helper()
+	k8s := clients.Kubernetes
+
+	serverNode, clientNode, err := pickConnectivityNodes(k8s)
+	require.NoError(t, err)
+	if serverNode.Name == clientNode.Name {
+		t.Logf("Only one Ready node; pod-to-pod traffic stays on %s", serverNode.Name)
+	} else {
+		t.Logf("Server on %s (%s), probes on %s (%s)",
+			serverNode.Name, serverNode.Labels[zoneLabel], clientNode.Name, clientNode.Labels[zoneLabel])
+	}
+
+	suffix := strings.ToLower(random.UniqueId())
+	server, svc, err := startConnectivityServer(t, k8s, suffix, serverNode.Name)
+	defer deleteConnectivityServer(k8s, suffix)
+	require.NoError(t, err, "Connectivity server should be running")
+
+	fqdn := fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace)
+	probes := []struct {
+		name, target, command string
+		verify                func(msg string) error
+	}{
+		{probePodToPod, server.Status.PodIP, wgetCommand(server.Status.PodIP), nil},
+		{probePodToService, svc.Spec.ClusterIP, wgetCommand(svc.Spec.ClusterIP), nil},
+		{probeDNS, fqdn, "nslookup " + fqdn + " > /dev/termination-log", func(msg string) error {
+			if !strings.Contains(msg, svc.Spec.ClusterIP) {
+				return fmt.Errorf("%s resolved without the Service's ClusterIP %s: %q", fqdn, svc.Spec.ClusterIP, msg)
+			}
+			return nil
+		}},
+	}
+
+	var results []connectivityResult
+	for _, p := range probes {
+		r := connectivityResult{Probe: p.name, From: clientNode.Name, To: serverNode.Name, Target: p.target}
+		msg, err := runCommandPod(t, k8s, probePod(suffix, p.name, clientNode.Name, p.command))
+		if err == nil && p.verify != nil {
+			err = p.verify(msg)
+		}
+		r.Err = err
+		results = append(results, r)
+	}
+
+	enforced, err := networkPolicyEnforced(k8s)
+	switch {
+	case err != nil:
+		t.Logf("Skipping the network policy probe: %v", err)
+	case !enforced:
+		t.Logf("Skipping the network policy probe: the VPC CNI doesn't enforce network policies")
+	default:
+		results = append(results, probeNetworkPolicy(t, k8s, suffix, server, clientNode.Name))
+	}
+
+	for _, r := range results {
+		if r.Err == nil {
+			t.Logf("Connectivity %s from %s to %s (%s): ok", r.Probe, r.From, r.Target, r.To)
+		}
+		assert.NoError(t, r.Err, "Connectivity %s from %s to %s (%s)", r.Probe, r.From, r.Target, r.To)
+	}
+	return results
+}
+
+// pickConnectivityNodes returns a Ready node for the server and another for
+// the probes, preferring one in a different zone. With a single Ready node
+// both are the same.
+func pickConnectivityNodes(k8s kubernetes.Interface) (*corev1.Node, *corev1.Node, error) {
+	list, err := k8s.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	var ready []*corev1.Node
+	for i := range list.Items {
+		n := &list.Items[i]
+		for _, c := range n.Status.Conditions {
+			if c.Type == corev1.NodeReady && c.Status == corev1.ConditionTrue {
+				ready = append(ready, n)
+			}
+		}
+	}
+	if len(ready) == 0 {
+		return nil, nil, fmt.Errorf("no Ready nodes to run connectivity probes on")
+	}
+	sort.Slice(ready, func(i, j int) bool { return ready[i].Name < ready[j].Name })
+
+	server, client := ready[0], ready[0]
+	for _, n := range ready[1:] {
+		if client == server || (n.Labels[zoneLabel] != server.Labels[zoneLabel] && client.Labels[zoneLabel] == server.Labels[zoneLabel]) {
+			client = n
+		}
+	}
+	return server, client, nil
+}
+
+// connectivityLabels are the labels of the connectivity server pod.
+func connectivityLabels(suffix string) map[string]string {
+	return map[string]string{"app": connectivityApp, "run": suffix, "role": "server", "test": "true"}
+}
+
+// startConnectivityServer creates the server pod on nodeName and a ClusterIP
+// Service for it, and waits for the pod to be Ready.
+func startConnectivityServer(t testing.TB, k8s kubernetes.Interface, suffix, nodeName string) (*corev1.Pod, *corev1.Service, error) {
+	t.Helper()
+
+	name := connectivityApp + "-" + suffix
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: connectivityNamespace, Labels: connectivityLabels(suffix)},
+		Spec: corev1.PodSpec{
+			NodeSelector: map[string]string{hostnameLabel: nodeName},
+			Containers: []corev1.Container{
+				{
+					Name:           "nginx",
+					Image:          serverImage,
+					Ports:          []corev1.ContainerPort{{ContainerPort: 80}},
+					ReadinessProbe: &corev1.Probe{ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/", Port: intstr.FromInt32(80)}}},
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("50m"),
+							corev1.ResourceMemory: resource.MustParse("64Mi"),
+						},
+					},
+				},
+			},
+		},
+	}
+	if _, err := k8s.CoreV1().Pods(connectivityNamespace).Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+		return nil, nil, fmt.Errorf("failed to create server pod: %w", err)
+	}
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: connectivityNamespace, Labels: map[string]string{"app": connectivityApp, "test": "true"}},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: connectivityLabels(suffix),
+			Ports:    []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt32(80)}},
+		},
+	}
+	svc, err := k8s.CoreV1().Services(connectivityNamespace).Create(context.Background(), svc, metav1.CreateOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create service: %w", err)
+	}
+
+	var ready *corev1.Pod
+	_, err = retry.DoWithRetryE(t, "Wait for connectivity server to be Ready", sharedMaxRetries, sharedRetryInterval, func() (string, error) {
+		p, err := k8s.CoreV1().Pods(connectivityNamespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get pod: %w", err)
+		}
+		if p.Status.Phase == corev1.PodFailed || p.Status.Phase == corev1.PodSucceeded {
+			return "", retry.FatalError{Underlying: fmt.Errorf("server pod stopped in %s state", p.Status.Phase)}
+		}
+		if p.Status.PodIP == "" || !podReady(p) {
+			return "", fmt.Errorf("server pod is %s, waiting for it to be Ready", p.Status.Phase)
+		}
+		ready = p
+		return p.Status.PodIP, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return ready, svc, nil
+}
+
+// deleteConnectivityServer deletes the server pod and Service.
+func deleteConnectivityServer(k8s kubernetes.Interface, suffix string) {
+	name := connectivityApp + "-" + suffix
+	_ = k8s.CoreV1().Services(connectivityNamespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+	_ = k8s.CoreV1().Pods(connectivityNamespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+}
+
+func podReady(p *corev1.Pod) bool {
+	for _, c := range p.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// wgetCommand fetches / from host on port 80, failing after the probe timeout.
+func wgetCommand(host string) string {
+	return fmt.Sprintf("wget -q -T %d -O /dev/null http://%s/", probeTimeoutSeconds, host)
+}
+
+// wgetTimedOut reports whether msg, a failed wgetCommand's output, says the
+// request timed out: the connection was dropped rather than refused or
+// answered.
+func wgetTimedOut(msg string) bool {
+	return strings.Contains(msg, "timed out")
+}
+
+// probePod returns a pod on nodeName that runs command once. Its output on
+// failure becomes the termination message.
+func probePod(suffix, probe, nodeName, command string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s-%s-%s", connectivityApp, probe, suffix, strings.ToLower(random.UniqueId())),
+			Namespace: connectivityNamespace,
+			Labels:    map[string]string{"app": connectivityApp, "run": suffix, "probe": probe, "test": "true"},
+		},
+		Spec: corev1.PodSpec{
+			NodeSelector:  map[string]string{hostnameLabel: nodeName},
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:                     "probe",
+					Image:                    probeImage,
+					Command:                  []string{"/bin/sh", "-c", command},
+					TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("10m"),
+							corev1.ResourceMemory: resource.MustParse("16Mi"),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// networkPolicyEnforced reports whether the VPC CNI's network policy agent
+// runs with enforcement on, which the vpc-cni addon sets up when configured
+// with enableNetworkPolicy.
+func networkPolicyEnforced(k8s kubernetes.Interface) (bool, error) {
+	ds, err := k8s.AppsV1().DaemonSets("kube-system").Get(context.Background(), "aws-node", metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get the aws-node DaemonSet: %w", err)
+	}
+	for _, c := range ds.Spec.Template.Spec.Containers {
+		if c.Name != networkPolicyAgent {
+			continue
+		}
+		for _, arg := range c.Args {
+			if arg == networkPolicyEnabled {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// probeNetworkPolicy applies a deny-all ingress NetworkPolicy to the server
+// and expects the pod-to-pod probe to fail. The agent needs a few seconds to
+// program the policy, so a probe that still connects is retried.
+func probeNetworkPolicy(t testing.TB, k8s kubernetes.Interface, suffix string, server *corev1.Pod, clientNode string) connectivityResult {
+	t.Helper()
+
+	r := connectivityResult{Probe: probeNetworkPol, From: clientNode, To: server.Spec.NodeSelector[hostnameLabel], Target: server.Status.PodIP}
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: connectivityApp + "-" + suffix, Namespace: connectivityNamespace},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: connectivityLabels(suffix)},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+	policies := k8s.NetworkingV1().NetworkPolicies(connectivityNamespace)
+	if _, err := policies.Create(context.Background(), policy, metav1.CreateOptions{}); err != nil {
+		r.Err = fmt.Errorf("failed to create NetworkPolicy: %w", err)
+		return r
+	}
+	defer func() {
+		_ = policies.Delete(context.Background(), policy.Name, metav1.DeleteOptions{})
+	}()
+
+	_, r.Err = retry.DoWithRetryE(t, "Wait for the NetworkPolicy to block traffic", policyProbeAttempts, sharedRetryInterval, func() (string, error) {
+		_, err := runCommandPod(t, k8s, probePod(suffix, probeNetworkPol, clientNode, wgetCommand(server.Status.PodIP)))
+		var failed *podFailedError
+		switch {
+		case errors.As(err, &failed) && wgetTimedOut(failed.Message):
+			return failed.Message, nil
+		case err != nil:
+			// The probe didn't run, or failed for another reason than the
+			// connection being dropped, which says nothing about the policy.
+			return "", retry.FatalError{Underlying: err}
+		}
+		return "", fmt.Errorf("%s is still reachable with a deny-all NetworkPolicy", server.Status.PodIP)
+	})
+	return r
+}
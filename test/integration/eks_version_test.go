@@ -1,17 +1,37 @@
-// Self-contained EKS version matrix test. Deploys a shared VPC, discovers
-// supported EKS versions from AWS, then runs parallel subtests — one per version.
+// Self-contained EKS version matrix test. Discovers supported EKS versions from
+// AWS, selects which to run (EKS_VERSION_STRATEGY), deploys a shared VPC, then
+// runs parallel subtests — one per version. With VPC_POOL set, a pre-existing
+// VPC is leased from that pool instead of deploying one.
 // All cleanup is handled via defer (VPC destroy runs after all subtests complete).
+// Each destroy is followed by a leak check: anything still tagged with the
+// run's RunID fails the test with its ARN.
+//
+// Progress is saved to .task/run-state.json and Terraform working dirs live
+// under .task/runs/<id>/, so an interrupted run can be continued with -resume
+// (MATRIX_RESUME=true): the VPC is re-applied from its existing state and only
+// failed or unfinished versions run again.
 //
 // Remove this file if your project doesn't test multiple EKS versions.
 package test
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/apex/terratest-eks/catalog"
+	"github.com/apex/terratest-eks/cost"
+	"github.com/apex/terratest-eks/matrix"
+	"github.com/apex/terratest-eks/report"
+	"github.com/apex/terratest-eks/version"
+	"github.com/apex/terratest-eks/vpcpool"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -19,92 +39,492 @@ const (
 	versionTestVPCName   = "terratest-vpc"
 )
 
+// Node group each matrix cluster runs, also used to project the run's cost.
+var (
+	matrixNodeInstanceTypes = []string{"t3.small"}
+	matrixNodeCount         = 1
+)
+
 // TestEksClusterVersionMatrix deploys a shared VPC, discovers EKS versions,
 // and tests each version in parallel. No external env vars required (AWS creds only).
 func TestEksClusterVersionMatrix(t *testing.T) {
 	cfg := newTestConfig(t)
 
+	// ── Step 1: Discover and select EKS versions (or resume) ───────────────
+	// Selection runs before the VPC deploy so a run with nothing to test, or
+	// one that could go over MATRIX_BUDGET_USD, costs nothing.
+	costs := newMatrixCosts(t, cfg)
+	state, versions := startOrResumeRun(t, cfg, costs)
+	leftovers := state.Leftovers()
+	inventory := newResourceInventory(t, cfg)
+
 	vpcName := fmt.Sprintf("%s-%s", versionTestVPCName, cfg.UniqueID)
+	runDir := filepath.Join(cfg.RunsDir, cfg.UniqueID)
 
-	t.Logf("VPC: %s | Region: %s | Profile: %s | MinVersion: %s", vpcName, cfg.AWSRegion, cfg.AWSProfile, cfg.MinVersion)
-	t.Logf("Pipeline tags: %v", cfg.PipelineTags)
-
-	// ── Step 1: Deploy shared VPC ──────────────────────────────────────────
-	vpcDir := copyFixtureToTemp(t, "examples/vpc")
-	vpcOpts := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: vpcDir,
-		Vars: map[string]interface{}{
-			"vpc_name":      vpcName,
-			"aws_region":    cfg.AWSRegion,
-			"environment":   "terratest",
-			"pipeline_tags": cfg.PipelineTags,
-		},
-		NoColor:     true,
-		Parallelism: 20,
-	})
+	t.Logf("VPC: %s | Region: %s | Profile: %s | Versions: %s | Strategy: %s",
+		vpcName, cfg.AWSRegion, cfg.AWSProfile, cfg.VersionConstraint, cfg.VersionSelector.Name())
+	t.Logf("Pipeline tags: %v | Run state: %s", cfg.PipelineTags, cfg.RunStatePath)
+
+	// Registered first so the reports are written last, after every destroy.
+	rep := report.New(t.Name())
+	rep.SetProperty("run_id", cfg.PipelineTags["RunID"])
+	rep.SetProperty("region", cfg.AWSRegion)
+	rep.SetProperty("strategy", cfg.VersionSelector.Name())
+	if cfg.PrivateEndpoint {
+		rep.SetProperty("endpoint_access", "private")
+	}
+	checks := make([]string, len(cfg.Checks))
+	for i, c := range cfg.Checks {
+		checks[i] = c.Name()
+	}
+	rep.SetProperty("checks", strings.Join(checks, ","))
+	t.Logf("Checks: %s", strings.Join(checks, ", "))
+	defer func() {
+		total := costs.ledger.Total()
+		rep.SetProperty("estimated_cost_usd", fmt.Sprintf("%.4f", total))
+		t.Logf("Estimated cost (offline price table):\n%s", costs.ledger.Summary())
+		writeReports(t, rep, cfg.ReportDir, "matrix")
+
+		if err := cost.CheckBudget(total, cfg.BudgetUSD); err != nil {
+			t.Errorf("%v (MATRIX_BUDGET_USD)", err)
+		}
+	}()
+
+	// ── Step 2: Lease a pool VPC (VPC_POOL) or deploy a shared VPC ─────────
+	var vpcID string
+	var privateSubnets []string
+	if pool, lease := leasePoolVPC(t, cfg, state); lease != nil {
+		// Released after the "versions" barrier subtest completes, like the
+		// VPC destroy below. A pool VPC costs the run nothing extra.
+		defer func() {
+			releasePoolVPC(t, pool, lease)
+			saveVPCState(t, state, func(vpc *matrix.VPCState) { vpc.Phase = matrix.PhaseDestroyed })
+			assertNoLeaks(t, inventory, "run "+cfg.PipelineTags["RunID"], allResources)
+			if state.Complete() {
+				_ = os.RemoveAll(runDir)
+			}
+		}()
 
-	// VPC destroy runs after the "versions" barrier subtest completes (all EKS subtests done)
-	defer terraform.Destroy(t, vpcOpts)
-	terraform.InitAndApply(t, vpcOpts)
+		vpcID, privateSubnets = lease.VPCID, lease.PrivateSubnets
+		saveVPCState(t, state, func(vpc *matrix.VPCState) {
+			vpc.Phase = matrix.PhaseApplied
+			vpc.Pool = lease.Pool
+			vpc.VPCID = vpcID
+			vpc.PrivateSubnets = privateSubnets
+		})
+		rep.SetProperty("vpc_pool", lease.Pool)
+		t.Logf("VPC leased from pool %s until %s", lease.Pool, lease.ExpiresAt.Format(time.RFC3339))
+	} else {
+		vpcDir := copyFixture(t, "examples/vpc", filepath.Join(runDir, "vpc"))
+		vpcOpts := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			TerraformDir: vpcDir,
+			Vars: map[string]interface{}{
+				"vpc_name":      vpcName,
+				"aws_region":    cfg.AWSRegion,
+				"environment":   "terratest",
+				"pipeline_tags": cfg.PipelineTags,
+			},
+			NoColor:     true,
+			Parallelism: 20,
+		})
 
-	vpcID := terraform.Output(t, vpcOpts, "vpc_id")
-	privateSubnets := terraform.OutputList(t, vpcOpts, "private_subnets")
+		// VPC destroy runs after the "versions" barrier subtest completes (all EKS subtests done).
+		// If destroy fails the test stops here, so the state keeps the VPC as deployed.
+		vpcStart := time.Now()
+		defer func() {
+			defer func() { costs.addVPC(t, time.Since(vpcStart)) }()
+			terraform.Destroy(t, vpcOpts)
+			saveVPCState(t, state, func(vpc *matrix.VPCState) { vpc.Phase = matrix.PhaseDestroyed })
+			// Every version is destroyed by now, so nothing of this run may remain.
+			assertNoLeaks(t, inventory, "VPC "+vpcName, allResources)
+			if state.Complete() {
+				_ = os.RemoveAll(runDir)
+			}
+		}()
 
-	t.Logf("VPC deployed: %s | Subnets: %v", vpcID, privateSubnets)
+		if state.VPC.Phase == matrix.PhaseApplied {
+			t.Logf("Resuming: re-applying VPC %s from existing state in %s", state.VPC.VPCID, vpcDir)
+		}
+		saveVPCState(t, state, func(vpc *matrix.VPCState) {
+			vpc.Phase = matrix.PhaseApplying
+			vpc.Dir = vpcDir
+		})
+		terraform.InitAndApply(t, vpcOpts)
+
+		vpcID = terraform.Output(t, vpcOpts, "vpc_id")
+		privateSubnets = terraform.OutputList(t, vpcOpts, "private_subnets")
+		inventory.addVPC(vpcID)
+		saveVPCState(t, state, func(vpc *matrix.VPCState) {
+			vpc.Phase = matrix.PhaseApplied
+			vpc.VPCID = vpcID
+			vpc.PrivateSubnets = privateSubnets
+		})
+	}
+
+	rep.SetProperty("vpc_id", vpcID)
+	rep.SetProperty("private_subnets", strings.Join(privateSubnets, ","))
+	t.Logf("VPC ready: %s | Subnets: %v", vpcID, privateSubnets)
 
-	// ── Step 2: Discover EKS versions ──────────────────────────────────────
-	versions := discoverEKSVersions(t, cfg.AWSRegion, cfg.MinVersion)
-	t.Logf("Discovered EKS versions: %v", versions)
+	eksOptions := func(t testing.TB, v version.KubeVersion) *terraform.Options {
+		slug := strings.ReplaceAll(v.String(), ".", "-")
+		return terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			// Each version gets its own dir (avoids state lock conflicts)
+			TerraformDir: copyFixture(t, "examples/eks", filepath.Join(runDir, "eks-"+slug)),
+			Vars: map[string]interface{}{
+				"cluster_name":                   fmt.Sprintf("test-eks-%s-%s", slug, cfg.UniqueID),
+				"cluster_version":                v.String(),
+				"aws_region":                     cfg.AWSRegion,
+				"vpc_id":                         vpcID,
+				"private_subnet_ids":             privateSubnets,
+				"environment":                    "terratest",
+				"node_instance_types":            matrixNodeInstanceTypes,
+				"node_desired_size":              matrixNodeCount,
+				"node_min_size":                  matrixNodeCount,
+				"node_max_size":                  matrixNodeCount,
+				"pipeline_tags":                  cfg.PipelineTags,
+				"cluster_endpoint_public_access": !cfg.PrivateEndpoint,
+				"pipeline_run_hash":              "",
+			},
+			NoColor:     true,
+			Parallelism: 20,
+		})
+	}
 
 	// ── Step 3: Parallel subtests per version ──────────────────────────────
 	// Barrier subtest: t.Run blocks until all parallel children complete.
 	// Without this, the parent function returns, defers fire (destroying the
 	// VPC), while parallel subtests are still deploying EKS clusters.
 	t.Run("versions", func(t *testing.T) {
+		// Clusters that passed in an interrupted run but were never destroyed.
+		for _, v := range leftovers {
+			name := "cleanup_EKS_" + strings.ReplaceAll(v.String(), ".", "_")
+			t.Run(name, func(st *testing.T) {
+				st.Parallel()
+
+				rc := rep.Case(name)
+				rc.Version, rc.Region = v.String(), cfg.AWSRegion
+				defer rc.Finish(st)
+				t := rc.Track(st)
+
+				eksOpts := eksOptions(t, v)
+				clusterName := eksOpts.Vars["cluster_name"].(string)
+				inventory.addCluster(clusterName)
+				rc.Phase(t, report.PhaseDestroy, func() {
+					terraform.Destroy(t, eksOpts)
+					assertNoLeaks(t, inventory, "EKS "+v.String(), versionResources(v.String(), clusterName))
+				})
+				saveVersionState(t, state, v, func(vs *matrix.VersionState) { vs.Phase = matrix.PhaseDestroyed })
+			})
+		}
+
 		for _, v := range versions {
-			version := v // capture loop variable
-			t.Run("EKS_"+strings.ReplaceAll(version, ".", "_"), func(t *testing.T) {
-				t.Parallel()
+			name := "EKS_" + strings.ReplaceAll(v.String(), ".", "_")
+			t.Run(name, func(st *testing.T) {
+				st.Parallel()
 
-				versionSlug := strings.ReplaceAll(version, ".", "-")
-				clusterName := fmt.Sprintf("test-eks-%s-%s", versionSlug, cfg.UniqueID)
+				// Deferred first so it runs last, after destroy. Failures reported
+				// through t are recorded on the report case.
+				rc := rep.Case(name)
+				defer rc.Finish(st)
+				t := rc.Track(st)
+
+				eksOpts := eksOptions(t, v)
+				clusterName := eksOpts.Vars["cluster_name"].(string)
+				version := v.String()
+				rc.Version, rc.ClusterName, rc.Region = version, clusterName, cfg.AWSRegion
+				inventory.addCluster(clusterName)
 
 				t.Logf("Testing EKS %s → cluster: %s", version, clusterName)
 
-				// Each version gets its own temp dir (avoids state lock conflicts)
-				eksDir := copyFixtureToTemp(t, "examples/eks")
-				eksOpts := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-					TerraformDir: eksDir,
-					Vars: map[string]interface{}{
-						"cluster_name":        clusterName,
-						"cluster_version":     version,
-						"aws_region":          cfg.AWSRegion,
-						"vpc_id":              vpcID,
-						"private_subnet_ids":  privateSubnets,
-						"environment":         "terratest",
-						"node_instance_types": []string{"t3.small"},
-						"node_desired_size":   1,
-						"node_min_size":       1,
-						"node_max_size":       1,
-						"pipeline_tags":       cfg.PipelineTags,
-						"pipeline_run_hash":   "",
-					},
-					NoColor:     true,
-					Parallelism: 20,
+				// Runs after destroy, so a failed destroy also marks the version failed.
+				defer func() {
+					saveVersionState(t, state, v, func(vs *matrix.VersionState) { vs.Failed = t.Failed() })
+				}()
+
+				saveVersionState(t, state, v, func(vs *matrix.VersionState) {
+					vs.Phase = matrix.PhaseApplying
+					vs.Failed = false
+					vs.ClusterName = clusterName
+					vs.Dir = eksOpts.TerraformDir
 				})
 
-				defer terraform.Destroy(t, eksOpts)
-				terraform.InitAndApply(t, eksOpts)
+				deployStart := time.Now()
+				defer func() {
+					defer func() { costs.addCluster(t, rc, v, eksOpts, time.Since(deployStart)) }()
+					rc.Phase(t, report.PhaseDestroy, func() {
+						terraform.Destroy(t, eksOpts)
+						assertNoLeaks(t, inventory, "cluster "+clusterName, versionResources(version, clusterName))
+					})
+					saveVersionState(t, state, v, func(vs *matrix.VersionState) { vs.Phase = matrix.PhaseDestroyed })
+				}()
+				rc.Phase(t, report.PhaseInit, func() { terraform.Init(t, eksOpts) })
+				rc.Phase(t, report.PhaseApply, func() {
+					defer recordEKSResources(t, rc, eksOpts)
+					terraform.Apply(t, eksOpts)
+				})
+				saveVersionState(t, state, v, func(vs *matrix.VersionState) { vs.Phase = matrix.PhaseApplied })
 
-				out := getEKSOutputs(t, eksOpts)
-				out.validate(t, clusterName, version)
+				rc.Phase(t, report.PhaseValidate, func() {
+					out := getEKSOutputs(t, eksOpts)
+					out.validate(t, clusterName, version)
 
-				validateClusterEndpoint(t, out.ClusterEndpoint)
-				validateClusterStatus(t, cfg.AWSRegion, out.ClusterName, version)
+					// Each check in cfg.Checks (EKS_CHECKS) runs as a named subtest.
+					runChecks(st, rc, cfg.Checks, &CheckEnv{
+						Config:      cfg,
+						Options:     eksOpts,
+						Outputs:     out,
+						Clients:     newClusterClients(t, cfg, out),
+						ClusterName: out.ClusterName,
+						Version:     version,
+					})
+				})
 
-				clientset := getKubernetesClient(t, cfg.AWSRegion, out.ClusterName, out.ClusterEndpoint, out.ClusterCAData)
-				validateNodeReadiness(t, clientset)
+				if !t.Failed() {
+					saveVersionState(t, state, v, func(vs *matrix.VersionState) {
+						vs.Phase = matrix.PhaseValidated
+						vs.Passed = true
+					})
+				}
 			})
 		}
 	})
+
+	// Versions that passed feed EKS_VERSION_STRATEGY=changed on the next run.
+	if err := matrix.RecordGreen(cfg.LastGreenPath, cfg.PipelineTags["RunID"], cfg.CodeFingerprint, state.Passed(), time.Now()); err != nil {
+		t.Logf("Failed to record green versions: %v", err)
+	}
+}
+
+// startOrResumeRun returns the run state and the versions to test. With
+// cfg.Resume and an unfinished run on disk, it adopts that run's IDs so
+// resources keep their names and tags, and returns only the versions that
+// haven't passed. Otherwise it discovers and selects versions and starts a
+// new run, failing the test if nothing was selected. Either way the test
+// fails before anything is deployed if the versions could cost more than
+// cfg.BudgetUSD.
+func startOrResumeRun(t testing.TB, cfg *testConfig, costs *matrixCosts) (*matrix.RunState, []version.KubeVersion) {
+	t.Helper()
+
+	previous, err := matrix.LoadRunState(cfg.RunStatePath)
+	require.NoError(t, err, "Failed to load run state")
+
+	if cfg.Resume {
+		require.NotNil(t, previous, "Nothing to resume: %s does not exist", cfg.RunStatePath)
+		require.False(t, previous.Complete(), "Nothing to resume: run %s already completed", previous.RunID)
+
+		cfg.UniqueID = previous.UniqueID
+		cfg.PipelineTags["RunID"] = previous.RunID
+
+		pending := previous.Pending()
+		t.Logf("Resuming run %s | Passed: %v | Retrying: %v", previous.RunID, previous.Passed(), pending)
+		costs.checkProjected(t, cfg.BudgetUSD, pending, previous.VPC.Pool == "")
+		return previous, pending
+	}
+
+	if previous != nil && !previous.Complete() {
+		t.Logf("WARNING: run %s in %s did not finish and may have left resources behind; "+
+			"starting a new run (use -resume to continue it, or task cleanup-run)", previous.RunID, cfg.RunStatePath)
+	}
+
+	discovered := discoverEKSVersions(t, cfg.AWSRegion, cfg.EKSEndpoint, cfg.VersionConstraint)
+	versions, err := cfg.VersionSelector.Select(discovered)
+	require.NoError(t, err, "Failed to select EKS versions")
+	t.Logf("Discovered EKS versions: %v | Selected: %v", discovered, versions)
+
+	require.NotEmpty(t, versions, "Strategy %s selected no versions to test", cfg.VersionSelector.Name())
+	costs.checkProjected(t, cfg.BudgetUSD, versions, cfg.VPCPool == "")
+
+	state, err := matrix.NewRunState(cfg.RunStatePath, cfg.PipelineTags["RunID"], cfg.UniqueID, versions)
+	require.NoError(t, err, "Failed to save run state")
+	return state, versions
+}
+
+// vpcNATGateways is the number of NAT gateways examples/vpc creates (single_nat_gateway).
+const vpcNATGateways = 1
+
+// matrixCosts estimates what the matrix spends from the offline price table.
+type matrixCosts struct {
+	ledger  cost.Ledger
+	prices  *cost.RegionPrices // nil if the region isn't in the price table
+	catalog *catalog.Catalog
+}
+
+// newMatrixCosts loads the price table for cfg.AWSRegion. A region without
+// prices is only fatal when a budget is set, since the budget can't be enforced.
+func newMatrixCosts(t testing.TB, cfg *testConfig) *matrixCosts {
+	t.Helper()
+
+	table, err := cost.Load()
+	require.NoError(t, err, "Failed to load price table")
+	cat, err := catalog.Load()
+	require.NoError(t, err, "Failed to load EKS version catalog")
+
+	m := &matrixCosts{catalog: cat}
+	prices, err := table.Region(cfg.AWSRegion)
+	if err != nil {
+		require.Zero(t, cfg.BudgetUSD, "MATRIX_BUDGET_USD is set but costs can't be estimated: %v", err)
+		t.Logf("Cost estimates disabled: %v", err)
+		return m
+	}
+	m.prices = &prices
+	return m
+}
+
+// addVPC prices the shared VPC for d.
+func (m *matrixCosts) addVPC(t testing.TB, d time.Duration) {
+	if m.prices != nil {
+		m.ledger.Add(m.prices.VPCLines("VPC", vpcNATGateways, d)...)
+	}
+}
+
+// addCluster prices one version's cluster and nodes for d and records the
+// cell's cost on rc.
+func (m *matrixCosts) addCluster(t testing.TB, rc *report.Case, v version.KubeVersion, opts *terraform.Options, d time.Duration) {
+	t.Helper()
+	if m.prices == nil {
+		return
+	}
+
+	instanceTypes, _ := opts.Vars["node_instance_types"].([]string)
+	nodes, _ := opts.Vars["node_desired_size"].(int)
+	lines, err := m.clusterLines(rc.Name, v, instanceTypes, nodes, d)
+	if err != nil {
+		t.Errorf("Failed to estimate cost: %v (add the price to test/cost/prices.json)", err)
+		return
+	}
+	m.ledger.Add(lines...)
+	rc.SetCost(m.ledger.CellTotal(rc.Name))
+}
+
+// clusterLines prices one version's cluster and nodes for d. EKS charges the
+// extended support rate for versions past standard support.
+func (m *matrixCosts) clusterLines(cell string, v version.KubeVersion, instanceTypes []string, nodes int, d time.Duration) ([]cost.Line, error) {
+	entry, ok := m.catalog.Lookup(v)
+	extended := ok && entry.Phase(time.Now()) == catalog.PhaseExtended
+	return m.prices.ClusterLines(cell, extended, instanceTypes, nodes, d)
+}
+
+// project prices the most the versions can cost: a cluster per version and,
+// with deployVPC, the shared VPC, each up for the whole of d.
+func (m *matrixCosts) project(versions []version.KubeVersion, deployVPC bool, d time.Duration) (*cost.Ledger, error) {
+	projected := &cost.Ledger{}
+	if m.prices == nil {
+		return projected, nil
+	}
+	if deployVPC {
+		projected.Add(m.prices.VPCLines("VPC", vpcNATGateways, d)...)
+	}
+	for _, v := range versions {
+		cell := "EKS_" + strings.ReplaceAll(v.String(), ".", "_")
+		lines, err := m.clusterLines(cell, v, matrixNodeInstanceTypes, matrixNodeCount, d)
+		if err != nil {
+			return nil, fmt.Errorf("%w (add the price to test/cost/prices.json)", err)
+		}
+		projected.Add(lines...)
+	}
+	return projected, nil
+}
+
+// checkProjected fails the test if the versions could cost more than budget
+// within versionMatrixTimeout, the longest the run can keep them up.
+func (m *matrixCosts) checkProjected(t testing.TB, budget float64, versions []version.KubeVersion, deployVPC bool) {
+	t.Helper()
+
+	projected, err := m.project(versions, deployVPC, versionMatrixTimeout)
+	require.NoError(t, err, "Failed to project the run's cost")
+	if err := cost.CheckBudget(projected.Total(), budget); err != nil {
+		t.Fatalf("Projected cost of %s over %s: %v (MATRIX_BUDGET_USD); test fewer versions or raise the budget\n%s",
+			strings.Join(version.Strings(versions), ", "), versionMatrixTimeout, err, projected.Summary())
+	}
+	if budget > 0 {
+		t.Logf("Projected cost: $%.4f of the $%.2f budget", projected.Total(), budget)
+	}
+}
+
+// eksResourceOutputs are the fixture outputs recorded in the report as the
+// AWS resources a version created.
+var eksResourceOutputs = []string{
+	"cluster_arn",
+	"cluster_security_group_id",
+	"node_security_group_id",
+	"oidc_provider_arn",
+}
+
+// recordEKSResources records the IDs of the resources in eksResourceOutputs.
+// It runs even after a failed apply, so missing outputs are skipped.
+func recordEKSResources(t testing.TB, rc *report.Case, opts *terraform.Options) {
+	for _, name := range eksResourceOutputs {
+		if id, err := terraform.OutputE(t, opts, name); err == nil {
+			rc.SetResource(name, id)
+		}
+	}
+}
+
+// writeReports writes the JSON summary and JUnit XML for the run to dir, as
+// <prefix>-report.json and <prefix>-junit.xml.
+func writeReports(t testing.TB, rep *report.Reporter, dir, prefix string) {
+	t.Helper()
+
+	jsonPath := filepath.Join(dir, prefix+"-report.json")
+	junitPath := filepath.Join(dir, prefix+"-junit.xml")
+	if err := rep.WriteJSON(jsonPath); err != nil {
+		t.Logf("Failed to write %s: %v", jsonPath, err)
+	}
+	if err := rep.WriteJUnit(junitPath); err != nil {
+		t.Logf("Failed to write %s: %v", junitPath, err)
+	}
+	t.Logf("Reports: %s, %s", jsonPath, junitPath)
+}
+
+// leasePoolVPC leases a VPC from the pool, if the run uses one. A run uses the
+// pool it leased from before being resumed; a new run uses cfg.VPCPool. It
+// returns a nil lease, so the caller deploys a VPC instead, when there is no
+// pool or nothing in it could be leased.
+func leasePoolVPC(t testing.TB, cfg *testConfig, state *matrix.RunState) (*vpcpool.Pool, *vpcpool.Lease) {
+	t.Helper()
+
+	name := state.VPC.Pool
+	if name == "" && state.VPC.Dir == "" {
+		name = cfg.VPCPool
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	sess, err := newAWSSession(cfg.AWSRegion)
+	require.NoError(t, err, "Failed to create AWS session")
+	pool := vpcpool.New(ec2.New(sess), name)
+
+	lease, err := pool.Acquire(context.Background(), cfg.PipelineTags["RunID"])
+	if err != nil {
+		t.Logf("Deploying a VPC instead of leasing from pool %s: %v", name, err)
+		return nil, nil
+	}
+	return pool, lease
+}
+
+// releasePoolVPC ends the run's lease. A failed release is logged rather than
+// failing the test: the lease expires on its own.
+func releasePoolVPC(t testing.TB, pool *vpcpool.Pool, lease *vpcpool.Lease) {
+	t.Helper()
+	if err := pool.Release(context.Background(), lease); err != nil {
+		t.Logf("Failed to release pool VPC %s (the lease expires at %s): %v",
+			lease.VPCID, lease.ExpiresAt.Format(time.RFC3339), err)
+	}
+}
+
+// saveVPCState and saveVersionState update the run state. A failed write is
+// logged rather than failing the test: the state only matters if the run dies.
+func saveVPCState(t testing.TB, state *matrix.RunState, fn func(*matrix.VPCState)) {
+	t.Helper()
+	if err := state.UpdateVPC(fn); err != nil {
+		t.Logf("Failed to save run state: %v", err)
+	}
+}
+
+func saveVersionState(t testing.TB, state *matrix.RunState, v version.KubeVersion, fn func(*matrix.VersionState)) {
+	t.Helper()
+	if err := state.UpdateVersion(v, fn); err != nil {
+		t.Logf("Failed to save run state: %v", err)
+	}
 }
@@ -0,0 +1,236 @@
+// Offline tests for the encryption check, run against the fakeaws EKS server
+// and a stub KMS client replaying recorded responses.
+package test
+
+import (
+	"testing"
+
+	"github.com/apex/terratest-eks/fakeaws"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testKeyARN         = "arn:aws:kms:us-west-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	testClusterRoleARN = "arn:aws:iam::123456789012:role/demo-cluster-20250101000000000000000001"
+)
+
+// recordedKeyPolicy is the policy terraform-aws-modules/eks v20 puts on the
+// key it creates, as returned by GetKeyPolicy.
+const recordedKeyPolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "Default",
+      "Effect": "Allow",
+      "Principal": {"AWS": "arn:aws:iam::123456789012:root"},
+      "Action": "kms:*",
+      "Resource": "*"
+    },
+    {
+      "Sid": "KeyAdministration",
+      "Effect": "Allow",
+      "Principal": {"AWS": "arn:aws:iam::123456789012:role/ci"},
+      "Action": ["kms:Update*", "kms:UntagResource", "kms:TagResource", "kms:ScheduleKeyDeletion", "kms:Revoke*", "kms:ReplicateKey", "kms:Put*", "kms:List*", "kms:ImportKeyMaterial", "kms:Get*", "kms:Enable*", "kms:Disable*", "kms:Describe*", "kms:Delete*", "kms:Create*", "kms:CancelKeyDeletion"],
+      "Resource": "*"
+    },
+    {
+      "Sid": "KeyUsage",
+      "Effect": "Allow",
+      "Principal": {"AWS": "` + testClusterRoleARN + `"},
+      "Action": ["kms:ReEncrypt*", "kms:GenerateDataKey*", "kms:Encrypt", "kms:DescribeKey", "kms:Decrypt"],
+      "Resource": "*"
+    }
+  ]
+}`
+
+// stubKMS answers for one key with recorded responses.
+type stubKMS struct {
+	kmsiface.KMSAPI
+
+	meta     kms.KeyMetadata
+	rotation bool
+	policy   string
+}
+
+// newStubKMS returns the key terraform-aws-modules/eks creates by default.
+func newStubKMS() *stubKMS {
+	return &stubKMS{
+		meta: kms.KeyMetadata{
+			Arn:        aws.String(testKeyARN),
+			KeyState:   aws.String(kms.KeyStateEnabled),
+			KeyManager: aws.String(kms.KeyManagerTypeCustomer),
+			KeySpec:    aws.String(kms.KeySpecSymmetricDefault),
+			KeyUsage:   aws.String(kms.KeyUsageTypeEncryptDecrypt),
+		},
+		rotation: true,
+		policy:   recordedKeyPolicy,
+	}
+}
+
+func (s *stubKMS) DescribeKey(*kms.DescribeKeyInput) (*kms.DescribeKeyOutput, error) {
+	meta := s.meta
+	return &kms.DescribeKeyOutput{KeyMetadata: &meta}, nil
+}
+
+func (s *stubKMS) GetKeyRotationStatus(*kms.GetKeyRotationStatusInput) (*kms.GetKeyRotationStatusOutput, error) {
+	return &kms.GetKeyRotationStatusOutput{KeyRotationEnabled: aws.Bool(s.rotation)}, nil
+}
+
+func (s *stubKMS) GetKeyPolicy(*kms.GetKeyPolicyInput) (*kms.GetKeyPolicyOutput, error) {
+	return &kms.GetKeyPolicyOutput{Policy: aws.String(s.policy)}, nil
+}
+
+func TestValidateEncryption(t *testing.T) {
+	enabled := encryptionExpectation{
+		Enabled:        true,
+		Resources:      []string{"secrets"},
+		KeyARN:         testKeyARN,
+		ClusterRoleARN: testClusterRoleARN,
+	}
+	otherKey := "arn:aws:kms:us-west-1:123456789012:key/other"
+
+	tests := []struct {
+		name    string
+		cluster fakeaws.Cluster
+		key     func(*stubKMS)
+		want    encryptionExpectation
+		wantErr []string // substrings of the recorded failures, in order
+	}{
+		{name: "encrypted", cluster: fakeaws.Cluster{KeyARN: testKeyARN}, want: enabled},
+		{name: "disabled as configured", want: encryptionExpectation{}},
+		{
+			name:    "unexpectedly disabled",
+			want:    enabled,
+			wantErr: []string{"Cluster should envelope-encrypt [secrets], but encryption is disabled"},
+		},
+		{
+			name:    "unexpectedly enabled",
+			cluster: fakeaws.Cluster{KeyARN: testKeyARN},
+			want:    encryptionExpectation{},
+			wantErr: []string{"Cluster should not be encrypted with create_kms_key = false"},
+		},
+		{
+			name:    "another key",
+			cluster: fakeaws.Cluster{KeyARN: otherKey},
+			want:    enabled,
+			wantErr: []string{"Cluster should encrypt secrets with the kms_key_arn output"},
+		},
+		{
+			name:    "secrets not encrypted",
+			cluster: fakeaws.Cluster{KeyARN: testKeyARN, EncryptedResources: []string{"configmaps"}},
+			want:    enabled,
+			wantErr: []string{"Cluster should encrypt secrets"},
+		},
+		{
+			name:    "rotation off",
+			cluster: fakeaws.Cluster{KeyARN: testKeyARN},
+			key:     func(k *stubKMS) { k.rotation = false },
+			want:    enabled,
+			wantErr: []string{"key " + testKeyARN + ": rotation is disabled"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeEKS(t)
+			tt.cluster.Name, tt.cluster.Version = "demo", "1.33"
+			fake.AddCluster(tt.cluster)
+			clients := newFakeClusterClients(t, fake)
+			key := newStubKMS()
+			if tt.key != nil {
+				tt.key(key)
+			}
+			clients.KMS = key
+
+			rt := &recordingT{TB: t}
+			validateEncryption(rt, clients, "demo", tt.want)
+
+			require.Len(t, rt.errors, len(tt.wantErr), "%v", rt.errors)
+			for i, want := range tt.wantErr {
+				assert.Contains(t, rt.errors[i], want)
+			}
+		})
+	}
+}
+
+func TestValidateKMSKey(t *testing.T) {
+	require.NoError(t, validateKMSKey(newStubKMS(), testKeyARN, testClusterRoleARN))
+
+	key := newStubKMS()
+	key.meta.KeyState = aws.String(kms.KeyStatePendingDeletion)
+	key.meta.KeyManager = aws.String(kms.KeyManagerTypeAws)
+	key.meta.KeySpec = aws.String(kms.KeySpecRsa2048)
+	key.rotation = false
+	assert.EqualError(t, validateKMSKey(key, testKeyARN, testClusterRoleARN),
+		"key "+testKeyARN+": key is PendingDeletion; key is managed by AWS; key spec is RSA_2048; rotation is disabled")
+
+	// A key from another module run doesn't let this cluster's role use it.
+	err := validateKMSKey(newStubKMS(), testKeyARN, "arn:aws:iam::123456789012:role/other-cluster")
+	assert.ErrorContains(t, err, "key policy doesn't allow arn:aws:iam::123456789012:role/other-cluster kms:Encrypt, kms:Decrypt")
+}
+
+func TestCheckKeyPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr string
+	}{
+		{name: "recorded", policy: recordedKeyPolicy},
+		{
+			name: "wildcard actions and a string principal list",
+			policy: `{"Statement": [{"Effect": "Allow", "Principal": {"AWS": ["arn:aws:iam::123456789012:root", "` +
+				testClusterRoleARN + `"]}, "Action": "KMS:*"}]}`,
+		},
+		{
+			name: "decrypt only",
+			policy: `{"Statement": [{"Effect": "Allow", "Principal": {"AWS": "` + testClusterRoleARN +
+				`"}, "Action": ["kms:Decrypt", "kms:DescribeKey"]}]}`,
+			wantErr: "key policy doesn't allow " + testClusterRoleARN + " kms:Encrypt",
+		},
+		{
+			name: "denied",
+			policy: `{"Statement": [{"Effect": "Deny", "Principal": {"AWS": "` + testClusterRoleARN +
+				`"}, "Action": "kms:*"}]}`,
+			wantErr: "kms:Encrypt, kms:Decrypt",
+		},
+		{
+			name:    "open to everyone",
+			policy:  `{"Statement": [{"Sid": "Open", "Effect": "Allow", "Principal": "*", "Action": "kms:*"}]}`,
+			wantErr: `key policy statement "Open" allows every principal`,
+		},
+		{
+			name:    "open to every AWS principal",
+			policy:  `{"Statement": [{"Sid": "Open", "Effect": "Allow", "Principal": {"AWS": "*"}, "Action": "kms:Decrypt"}]}`,
+			wantErr: `key policy statement "Open" allows every principal`,
+		},
+		{
+			name: "everyone in the account, via a condition",
+			policy: `{"Statement": [{"Effect": "Allow", "Principal": {"AWS": "*"}, "Action": ["kms:Encrypt", "kms:Decrypt"],
+				"Condition": {"StringEquals": {"kms:CallerAccount": "123456789012"}}}]}`,
+			wantErr: "key policy doesn't allow",
+		},
+		{name: "not JSON", policy: "{", wantErr: "failed to parse key policy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkKeyPolicy(tt.policy, testClusterRoleARN)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateKMSKey(t *testing.T) {
+	assert.True(t, createKMSKey(&terraform.Options{}), "defaults to true like the variable")
+	assert.True(t, createKMSKey(&terraform.Options{Vars: map[string]interface{}{"create_kms_key": true}}))
+	assert.False(t, createKMSKey(&terraform.Options{Vars: map[string]interface{}{"create_kms_key": false}}))
+}
@@ -0,0 +1,229 @@
+// Encryption check. With create_kms_key (the default) the cluster must
+// envelope-encrypt secrets with the key Terraform created: an enabled
+// symmetric customer managed key with rotation on, whose policy lets the
+// cluster role use it and doesn't open it to everyone. Without it, the
+// cluster must not be encrypted.
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	registerCheck(awsOnlyCheck{checkFunc{"encryption", func(t testing.TB, env *CheckEnv) {
+		want := encryptionExpectation{Enabled: createKMSKey(env.Options)}
+		if want.Enabled {
+			want.Resources = []string{"secrets"}
+			want.KeyARN = terraform.Output(t, env.Options, "kms_key_arn")
+			want.ClusterRoleARN = terraform.Output(t, env.Options, "cluster_iam_role_arn")
+		}
+		validateEncryption(t, env.Clients, env.ClusterName, want)
+	}}}, false)
+}
+
+// kmsDataActions are the key operations EKS needs to encrypt and decrypt
+// secrets with the cluster role.
+var kmsDataActions = []string{"kms:Encrypt", "kms:Decrypt"}
+
+// encryptionExpectation is the envelope encryption examples/eks was applied
+// with.
+type encryptionExpectation struct {
+	Enabled        bool
+	Resources      []string // resources EKS must encrypt, e.g. secrets
+	KeyARN         string
+	ClusterRoleARN string // role the key policy must let use the key
+}
+
+// createKMSKey returns the create_kms_key input, which defaults to true.
+func createKMSKey(opts *terraform.Options) bool {
+	enabled, ok := opts.Vars["create_kms_key"].(bool)
+	return !ok || enabled
+}
+
+// validateEncryption checks the cluster's encryption config against want
+// and, when encryption is expected, the key it uses.
+func validateEncryption(t testing.TB, clients *ClusterClients, clusterName string, want encryptionExpectation) {
+	t.Helper()
+
+	out, err := clients.EKS.DescribeCluster(&eks.DescribeClusterInput{Name: aws.String(clusterName)})
+	require.NoError(t, err, "Failed to describe cluster")
+	configs := out.Cluster.EncryptionConfig
+
+	if !want.Enabled {
+		assert.Empty(t, configs, "Cluster should not be encrypted with create_kms_key = false")
+		return
+	}
+	if !assert.NotEmpty(t, configs, "Cluster should envelope-encrypt %v, but encryption is disabled", want.Resources) {
+		return
+	}
+
+	encrypted := make(map[string]string) // resource -> key ARN
+	for _, c := range configs {
+		for _, r := range c.Resources {
+			if c.Provider != nil {
+				encrypted[aws.StringValue(r)] = aws.StringValue(c.Provider.KeyArn)
+			}
+		}
+	}
+	for _, r := range want.Resources {
+		keyARN, ok := encrypted[r]
+		if assert.True(t, ok, "Cluster should encrypt %s", r) {
+			assert.Equal(t, want.KeyARN, keyARN, "Cluster should encrypt %s with the kms_key_arn output", r)
+		}
+	}
+
+	assert.NoError(t, validateKMSKey(clients.KMS, want.KeyARN, want.ClusterRoleARN))
+}
+
+// validateKMSKey checks that keyARN is an enabled symmetric customer managed
+// key with rotation on, usable by clusterRoleARN and not by everyone.
+func validateKMSKey(kmsSvc kmsiface.KMSAPI, keyARN, clusterRoleARN string) error {
+	described, err := kmsSvc.DescribeKey(&kms.DescribeKeyInput{KeyId: aws.String(keyARN)})
+	if err != nil {
+		return fmt.Errorf("failed to describe key %s: %w", keyARN, err)
+	}
+	meta := described.KeyMetadata
+	var problems []string
+	if state := aws.StringValue(meta.KeyState); state != kms.KeyStateEnabled {
+		problems = append(problems, "key is "+state)
+	}
+	if manager := aws.StringValue(meta.KeyManager); manager != kms.KeyManagerTypeCustomer {
+		problems = append(problems, "key is managed by "+manager)
+	}
+	if spec := aws.StringValue(meta.KeySpec); spec != kms.KeySpecSymmetricDefault {
+		problems = append(problems, "key spec is "+spec)
+	}
+
+	rotation, err := kmsSvc.GetKeyRotationStatus(&kms.GetKeyRotationStatusInput{KeyId: aws.String(keyARN)})
+	if err != nil {
+		return fmt.Errorf("failed to get rotation status of key %s: %w", keyARN, err)
+	}
+	if !aws.BoolValue(rotation.KeyRotationEnabled) {
+		problems = append(problems, "rotation is disabled")
+	}
+
+	policy, err := kmsSvc.GetKeyPolicy(&kms.GetKeyPolicyInput{
+		KeyId:      aws.String(keyARN),
+		PolicyName: aws.String("default"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get policy of key %s: %w", keyARN, err)
+	}
+	if err := checkKeyPolicy(aws.StringValue(policy.Policy), clusterRoleARN); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("key %s: %s", keyARN, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// keyPolicy is the part of a KMS key policy the check reads.
+type keyPolicy struct {
+	Statement []struct {
+		Sid       string
+		Effect    string
+		Principal json.RawMessage
+		Action    stringOrList
+		Condition json.RawMessage
+	}
+}
+
+// stringOrList is a policy element that may be a string or a list of them.
+type stringOrList []string
+
+func (s *stringOrList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*s = []string{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*s = many
+	return nil
+}
+
+// principals returns the AWS principals of a statement; "*" for a wildcard.
+func principals(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var wildcard string
+	if err := json.Unmarshal(raw, &wildcard); err == nil {
+		return []string{wildcard}, nil
+	}
+	var byType map[string]stringOrList
+	if err := json.Unmarshal(raw, &byType); err != nil {
+		return nil, err
+	}
+	return byType["AWS"], nil
+}
+
+// checkKeyPolicy checks that policy allows clusterRoleARN the data actions
+// and has no unconditional Allow for every principal.
+func checkKeyPolicy(policy, clusterRoleARN string) error {
+	var doc keyPolicy
+	if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+		return fmt.Errorf("failed to parse key policy: %w", err)
+	}
+
+	granted := make(map[string]bool)
+	for _, s := range doc.Statement {
+		if s.Effect != "Allow" {
+			continue
+		}
+		who, err := principals(s.Principal)
+		if err != nil {
+			return fmt.Errorf("failed to parse principal of statement %q: %w", s.Sid, err)
+		}
+		for _, p := range who {
+			if p == "*" && len(s.Condition) == 0 {
+				return fmt.Errorf("key policy statement %q allows every principal", s.Sid)
+			}
+			if p != clusterRoleARN {
+				continue
+			}
+			for _, want := range kmsDataActions {
+				for _, a := range s.Action {
+					if actionMatches(a, want) {
+						granted[want] = true
+					}
+				}
+			}
+		}
+	}
+
+	var missing []string
+	for _, a := range kmsDataActions {
+		if !granted[a] {
+			missing = append(missing, a)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("key policy doesn't allow %s %s", clusterRoleARN, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// actionMatches reports whether a policy action, which may contain
+// wildcards, covers action. IAM actions are case-insensitive.
+func actionMatches(pattern, action string) bool {
+	ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(action))
+	return err == nil && ok
+}
@@ -0,0 +1,183 @@
+// Offline tests for the endpoint check, run against the fakeaws EKS server
+// with the runner's connection attempt stubbed out.
+package test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/apex/terratest-eks/fakeaws"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testEndpoint = "https://0123456789ABCDEF0123456789ABCDEF.gr7.us-west-1.eks.amazonaws.com"
+
+// withEndpointProbe makes the runner's connection attempts return got and err.
+func withEndpointProbe(t *testing.T, got string, err error) *[]string {
+	t.Helper()
+	var probed []string
+	probe := endpointProbe
+	endpointProbe = func(endpoint string) (string, error) {
+		probed = append(probed, endpoint)
+		return got, err
+	}
+	t.Cleanup(func() { endpointProbe = probe })
+	return &probed
+}
+
+func TestValidateClusterEndpoint(t *testing.T) {
+	defaults := endpointAccess{Public: true, Private: true, PublicCIDRs: []string{"0.0.0.0/0"}}
+	privateOnly := endpointAccess{Private: true, PublicCIDRs: []string{"0.0.0.0/0"}}
+	unresolved := errors.New("failed to resolve endpoint host: no such host")
+
+	tests := []struct {
+		name     string
+		endpoint string
+		access   fakeaws.EndpointAccess
+		want     endpointAccess
+		probeGot string // runnerConnected unless set
+		probeErr error
+		wantGot  []string // outcome of each setting, in order
+		wantErr  []string // substrings of the recorded failures, in order
+	}{
+		{
+			name:    "public and private",
+			access:  fakeaws.EndpointAccess{Public: true, Private: true, PublicCIDRs: []string{"0.0.0.0/0"}},
+			want:    defaults,
+			wantGot: []string{"true", "true", "0.0.0.0/0"},
+		},
+		{
+			name:    "restricted CIDRs in another order",
+			access:  fakeaws.EndpointAccess{Public: true, Private: true, PublicCIDRs: []string{"203.0.113.0/24", "198.51.100.7/32"}},
+			want:    endpointAccess{Public: true, Private: true, PublicCIDRs: []string{"198.51.100.7/32", "203.0.113.0/24"}},
+			wantGot: []string{"true", "true", "198.51.100.7/32,203.0.113.0/24"},
+		},
+		{
+			name:    "CIDRs left open",
+			access:  fakeaws.EndpointAccess{Public: true, Private: true, PublicCIDRs: []string{"0.0.0.0/0"}},
+			want:    endpointAccess{Public: true, Private: true, PublicCIDRs: []string{"203.0.113.0/24"}},
+			wantGot: []string{"true", "true", "0.0.0.0/0"},
+			wantErr: []string{"Endpoint setting cluster_endpoint_public_access_cidrs should match"},
+		},
+		{
+			name:    "private access off",
+			access:  fakeaws.EndpointAccess{Public: true, PublicCIDRs: []string{"0.0.0.0/0"}},
+			want:    defaults,
+			wantGot: []string{"true", "false", "0.0.0.0/0"},
+			wantErr: []string{"Endpoint setting cluster_endpoint_private_access should match"},
+		},
+		{
+			name:     "private only rejects the runner",
+			access:   fakeaws.EndpointAccess{Private: true, PublicCIDRs: []string{"0.0.0.0/0"}},
+			want:     privateOnly,
+			probeGot: runnerRejected,
+			wantGot:  []string{"false", "true", runnerRejected},
+		},
+		{
+			name:    "private only but the runner connects",
+			access:  fakeaws.EndpointAccess{Private: true, PublicCIDRs: []string{"0.0.0.0/0"}},
+			want:    privateOnly,
+			wantGot: []string{"false", "true", runnerConnected},
+			wantErr: []string{"Endpoint setting runner_access should match"},
+		},
+		{
+			name:     "private only but the host doesn't resolve",
+			access:   fakeaws.EndpointAccess{Private: true, PublicCIDRs: []string{"0.0.0.0/0"}},
+			want:     privateOnly,
+			probeErr: unresolved,
+			wantGot:  []string{"false", "true"},
+			wantErr:  []string{"Failed to probe the endpoint from the runner"},
+		},
+		{
+			name:     "public access left on",
+			access:   fakeaws.EndpointAccess{Public: true, Private: true, PublicCIDRs: []string{"0.0.0.0/0"}},
+			want:     privateOnly,
+			probeGot: runnerRejected,
+			wantGot:  []string{"true", "true", runnerRejected},
+			wantErr:  []string{"Endpoint setting cluster_endpoint_public_access should match"},
+		},
+		{
+			name:     "not an EKS endpoint",
+			endpoint: "http://localhost:8080",
+			access:   fakeaws.EndpointAccess{Public: true, Private: true, PublicCIDRs: []string{"0.0.0.0/0"}},
+			want:     defaults,
+			wantGot:  []string{"true", "true", "0.0.0.0/0"},
+			wantErr:  []string{"Endpoint should be HTTPS", "Endpoint should be an EKS endpoint"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeEKS(t)
+			fake.AddCluster(fakeaws.Cluster{Name: "demo", Version: "1.33", EndpointAccess: &tt.access})
+			probeGot := tt.probeGot
+			if probeGot == "" && tt.probeErr == nil {
+				probeGot = runnerConnected
+			}
+			probed := withEndpointProbe(t, probeGot, tt.probeErr)
+			endpoint := tt.endpoint
+			if endpoint == "" {
+				endpoint = testEndpoint
+			}
+
+			rt := &recordingT{TB: t}
+			settings := validateClusterEndpoint(rt, newFakeClusterClients(t, fake), "demo", endpoint, tt.want)
+
+			got := make([]string, len(settings))
+			for i, s := range settings {
+				got[i] = s.Got
+			}
+			assert.Equal(t, tt.wantGot, got)
+			if tt.want.Public {
+				assert.Empty(t, *probed, "the runner is only probed without public access")
+			} else {
+				assert.Equal(t, []string{endpoint}, *probed)
+			}
+
+			require.Len(t, rt.errors, len(tt.wantErr), "%v", rt.errors)
+			for i, want := range tt.wantErr {
+				assert.Contains(t, rt.errors[i], want)
+			}
+		})
+	}
+}
+
+func TestProbeEndpoint(t *testing.T) {
+	timeout := endpointDialTimeout
+	endpointDialTimeout = time.Second
+	t.Cleanup(func() { endpointDialTimeout = timeout })
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	got, err := probeEndpoint("https://" + ln.Addr().String())
+	require.NoError(t, err)
+	assert.Equal(t, runnerConnected, got)
+
+	// Nothing listens on a closed listener's port, so the dial is refused.
+	require.NoError(t, ln.Close())
+	got, err = probeEndpoint("https://" + ln.Addr().String())
+	require.NoError(t, err)
+	assert.Equal(t, runnerRejected, got)
+
+	// .invalid never resolves (RFC 2606), which says nothing about access.
+	got, err = probeEndpoint("https://cluster.invalid")
+	assert.ErrorContains(t, err, "failed to resolve endpoint host cluster.invalid")
+	assert.Empty(t, got, "a resolution error doesn't count as rejected")
+
+	_, err = probeEndpoint("://bad")
+	assert.ErrorContains(t, err, "invalid endpoint")
+}
+
+func TestEndpointAccessFromOptions(t *testing.T) {
+	assert.Equal(t, endpointAccess{Public: true, Private: true, PublicCIDRs: []string{"0.0.0.0/0"}},
+		endpointAccessFromOptions(&terraform.Options{}), "the variables' defaults")
+	assert.Equal(t, endpointAccess{Private: true, PublicCIDRs: []string{"203.0.113.0/24"}},
+		endpointAccessFromOptions(&terraform.Options{Vars: map[string]interface{}{
+			"cluster_endpoint_public_access":       false,
+			"cluster_endpoint_public_access_cidrs": []interface{}{"203.0.113.0/24"},
+		}}))
+}
@@ -0,0 +1,159 @@
+// Endpoint check. The cluster endpoint must be an HTTPS EKS endpoint whose
+// public and private access and public CIDRs match the examples/eks inputs.
+// A cluster without public access must also turn the test runner away.
+package test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Outcomes of the runner_access setting.
+const (
+	runnerConnected = "connected"
+	runnerRejected  = "rejected"
+)
+
+// endpointDialTimeout bounds the runner's connection attempt to an endpoint.
+// A private-only endpoint resolves to VPC addresses, so from outside the VPC
+// the attempt times out rather than being refused.
+var endpointDialTimeout = 10 * time.Second
+
+// endpointProbe tries to connect to an endpoint from the runner and returns
+// runnerConnected or runnerRejected. A var so offline tests can stand in for
+// the network.
+var endpointProbe = probeEndpoint
+
+// endpointAccess is who may reach the cluster endpoint, per the
+// cluster_endpoint_* inputs.
+type endpointAccess struct {
+	Public      bool
+	Private     bool
+	PublicCIDRs []string
+}
+
+// endpointAccessFromOptions reads the endpoint inputs from opts, falling back
+// to the variables' defaults.
+func endpointAccessFromOptions(opts *terraform.Options) endpointAccess {
+	want := endpointAccess{Public: true, Private: true, PublicCIDRs: []string{"0.0.0.0/0"}}
+	if public, ok := opts.Vars["cluster_endpoint_public_access"].(bool); ok {
+		want.Public = public
+	}
+	if private, ok := opts.Vars["cluster_endpoint_private_access"].(bool); ok {
+		want.Private = private
+	}
+	switch cidrs := opts.Vars["cluster_endpoint_public_access_cidrs"].(type) {
+	case []string:
+		want.PublicCIDRs = cidrs
+	case []interface{}:
+		want.PublicCIDRs = make([]string, len(cidrs))
+		for i, v := range cidrs {
+			want.PublicCIDRs[i] = fmt.Sprint(v)
+		}
+	}
+	return want
+}
+
+// endpointSetting is the outcome of one endpoint access setting.
+type endpointSetting struct {
+	Name string
+	Want string
+	Got  string
+}
+
+// validateClusterEndpoint checks that the endpoint is an HTTPS EKS endpoint
+// and that each access setting in EKS matches want, and returns the outcome
+// of each. Without public access the runner must fail to connect.
+func validateClusterEndpoint(t testing.TB, clients *ClusterClients, clusterName, endpoint string, want endpointAccess) []endpointSetting {
+	t.Helper()
+	assert.True(t, strings.HasPrefix(endpoint, "https://"), "Endpoint should be HTTPS")
+	assert.Contains(t, endpoint, ".eks.amazonaws.com", "Endpoint should be an EKS endpoint")
+
+	out, err := clients.EKS.DescribeCluster(&eks.DescribeClusterInput{Name: aws.String(clusterName)})
+	require.NoError(t, err, "Failed to describe cluster")
+	settings := compareEndpointAccess(want, out.Cluster.ResourcesVpcConfig)
+
+	if !want.Public {
+		got, err := endpointProbe(endpoint)
+		if assert.NoError(t, err, "Failed to probe the endpoint from the runner") {
+			settings = append(settings, endpointSetting{Name: "runner_access", Want: runnerRejected, Got: got})
+		}
+	}
+
+	for _, s := range settings {
+		t.Logf("Endpoint %s: want %s, got %s", s.Name, s.Want, s.Got)
+		assert.Equal(t, s.Want, s.Got, "Endpoint setting %s should match", s.Name)
+	}
+	return settings
+}
+
+// compareEndpointAccess pairs each wanted setting with what EKS reports. The
+// public CIDRs only count with public access.
+func compareEndpointAccess(want endpointAccess, got *eks.VpcConfigResponse) []endpointSetting {
+	if got == nil {
+		got = &eks.VpcConfigResponse{}
+	}
+	settings := []endpointSetting{
+		{
+			Name: "cluster_endpoint_public_access",
+			Want: strconv.FormatBool(want.Public),
+			Got:  strconv.FormatBool(aws.BoolValue(got.EndpointPublicAccess)),
+		},
+		{
+			Name: "cluster_endpoint_private_access",
+			Want: strconv.FormatBool(want.Private),
+			Got:  strconv.FormatBool(aws.BoolValue(got.EndpointPrivateAccess)),
+		},
+	}
+	if want.Public {
+		settings = append(settings, endpointSetting{
+			Name: "cluster_endpoint_public_access_cidrs",
+			Want: strings.Join(sortedCopy(want.PublicCIDRs), ","),
+			Got:  strings.Join(sortedCopy(aws.StringValueSlice(got.PublicAccessCidrs)), ","),
+		})
+	}
+	return settings
+}
+
+// probeEndpoint opens a TCP connection to endpoint's host, on 443 unless the
+// URL names a port. Only a timeout or a refused connection counts as
+// rejected; a host that doesn't resolve or any other failure is an error,
+// since it says nothing about the endpoint's access.
+func probeEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+
+	if _, err := net.LookupHost(u.Hostname()); err != nil {
+		return "", fmt.Errorf("failed to resolve endpoint host %s: %w", u.Hostname(), err)
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(u.Hostname(), port), endpointDialTimeout)
+	var netErr net.Error
+	switch {
+	case err == nil:
+		conn.Close()
+		return runnerConnected, nil
+	case errors.As(err, &netErr) && netErr.Timeout(), errors.Is(err, syscall.ECONNREFUSED):
+		return runnerRejected, nil
+	}
+	return "", fmt.Errorf("failed to connect to %s: %w", u.Host, err)
+}
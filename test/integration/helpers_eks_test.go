@@ -0,0 +1,181 @@
+// Offline tests for the EKS helpers, run against the fakeaws EKS server.
+package test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apex/terratest-eks/catalog"
+	"github.com/apex/terratest-eks/cost"
+	"github.com/apex/terratest-eks/fakeaws"
+	"github.com/apex/terratest-eks/version"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useFakeEKS starts a fake EKS server, isolates the AWS SDK from local
+// credentials and config, and shortens helper retries for the test.
+func useFakeEKS(t *testing.T) *fakeaws.EKS {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDTEST")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+
+	interval, retries := sharedRetryInterval, sharedMaxRetries
+	sharedRetryInterval, sharedMaxRetries = time.Millisecond, 5
+	t.Cleanup(func() { sharedRetryInterval, sharedMaxRetries = interval, retries })
+
+	fake := fakeaws.NewEKS()
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+func TestValidateClusterStatusWaitsForActive(t *testing.T) {
+	fake := useFakeEKS(t)
+	fake.AddCluster(fakeaws.Cluster{Name: "demo", Version: "1.33", Statuses: []string{"CREATING", "CREATING", "ACTIVE"}})
+
+	validateClusterStatus(t, newFakeClusterClients(t, fake), "demo", "1.33")
+	assert.Equal(t, 3, fake.Calls(fakeaws.OpDescribeCluster))
+}
+
+func TestWaitForClusterActive(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []string
+		throttles int
+		wantErr   string
+		wantCalls int
+	}{
+		{name: "active", statuses: []string{"ACTIVE"}, wantCalls: 1},
+		{name: "failed stops polling", statuses: []string{"CREATING", "FAILED"}, wantErr: "cluster demo is FAILED", wantCalls: 2},
+		{name: "never active", statuses: []string{"CREATING"}, wantErr: "unsuccessful after 5 retries", wantCalls: 6},
+		{name: "throttled then active", statuses: []string{"ACTIVE"}, throttles: 1, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeEKS(t)
+			fake.AddCluster(fakeaws.Cluster{Name: "demo", Version: "1.33", Statuses: tt.statuses})
+			fake.Throttle(fakeaws.OpDescribeCluster, tt.throttles)
+
+			clients := newFakeClusterClients(t, fake)
+			cluster, err := waitForClusterActive(t, clients.EKS, "demo")
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "1.33", aws.StringValue(cluster.Version))
+			}
+			assert.Equal(t, tt.wantCalls, fake.Calls(fakeaws.OpDescribeCluster))
+		})
+	}
+}
+
+func TestWaitForNodegroupsActive(t *testing.T) {
+	tests := []struct {
+		name       string
+		nodegroups []fakeaws.Nodegroup
+		wantErr    string
+	}{
+		{
+			name: "all active",
+			nodegroups: []fakeaws.Nodegroup{
+				{Name: "system", Statuses: []string{"ACTIVE"}},
+				{Name: "workers", Statuses: []string{"CREATING", "ACTIVE"}},
+			},
+		},
+		{
+			name:       "create failed",
+			nodegroups: []fakeaws.Nodegroup{{Name: "workers", Statuses: []string{"CREATING", "CREATE_FAILED"}}},
+			wantErr:    "node group workers is CREATE_FAILED",
+		},
+		{name: "none", wantErr: "has no managed node groups"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeEKS(t)
+			fake.AddCluster(fakeaws.Cluster{Name: "demo", Version: "1.33"})
+			for _, ng := range tt.nodegroups {
+				require.NoError(t, fake.AddNodegroup("demo", ng))
+			}
+
+			clients := newFakeClusterClients(t, fake)
+			err := waitForNodegroupsActive(t, clients.EKS, "demo")
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 3, fake.Calls(fakeaws.OpDescribeNodegroup))
+		})
+	}
+}
+
+func TestDiscoverEKSVersionsFromAWS(t *testing.T) {
+	fake := useFakeEKS(t)
+	t.Setenv("EKS_VERSION_SOURCE", "aws")
+	fake.AddAddonVersion("vpc-cni", "v1.19.0-eksbuild.1", "1.30", "1.31", "1.32")
+	fake.AddAddonVersion("vpc-cni", "v1.20.0-eksbuild.1", "1.32", "1.33", "1.34")
+
+	got := discoverEKSVersions(t, "us-east-1", fake.URL(), version.MustParseConstraint(">=1.31,<1.34"))
+	assert.Equal(t, []string{"1.31", "1.32", "1.33"}, version.Strings(got))
+}
+
+func TestDiscoverEKSVersionsFallsBackToCatalog(t *testing.T) {
+	fake := useFakeEKS(t)
+	t.Setenv("EKS_VERSION_SOURCE", "aws")
+	fake.InjectError(fakeaws.OpDescribeAddonVersions, 1, fakeaws.APIError{
+		Status:  http.StatusForbidden,
+		Code:    "AccessDeniedException",
+		Message: "not authorized",
+	})
+
+	cat, err := catalog.Load()
+	require.NoError(t, err)
+	constraint := version.MustParseConstraint(">=1.28")
+
+	got := discoverEKSVersions(t, "us-east-1", fake.URL(), constraint)
+	assert.Equal(t, version.Strings(constraint.Filter(cat.Available(time.Now()))), version.Strings(got))
+	assert.Equal(t, 1, fake.Calls(fakeaws.OpDescribeAddonVersions))
+}
+
+func TestProjectMatrixCost(t *testing.T) {
+	costs := newMatrixCosts(t, &testConfig{AWSRegion: "us-west-1"})
+	versions := []version.KubeVersion{version.MustParse("1.32"), version.MustParse("1.33")}
+
+	projected, err := costs.project(versions, true, time.Hour)
+	require.NoError(t, err)
+	want := projected.CellTotal("VPC")
+	assert.Positive(t, want, "the VPC is priced")
+	for _, v := range versions {
+		lines, err := costs.clusterLines("", v, matrixNodeInstanceTypes, matrixNodeCount, time.Hour)
+		require.NoError(t, err)
+		for _, l := range lines {
+			want += l.Cost()
+		}
+	}
+	assert.InDelta(t, want, projected.Total(), 1e-9)
+
+	pooled, err := costs.project(versions, false, time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, pooled.CellTotal("VPC"), "a pool VPC costs the run nothing")
+	assert.InDelta(t, projected.Total()-projected.CellTotal("VPC"), pooled.Total(), 1e-9)
+	assert.NoError(t, cost.CheckBudget(pooled.Total(), pooled.Total()))
+	assert.Error(t, cost.CheckBudget(projected.Total(), pooled.Total()), "the VPC tips it over")
+
+	unpriced := newMatrixCosts(t, &testConfig{AWSRegion: "mars-east-1"})
+	projected, err = unpriced.project(versions, true, time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, projected.Total(), "a region without prices projects nothing")
+}
@@ -0,0 +1,117 @@
+// Offline tests for the Kubernetes helpers, run against client-go's fake clientset.
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func node(name string, ready corev1.ConditionStatus) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+		},
+	}
+}
+
+// withPodPhases makes successive pod GETs report the given phases, repeating
+// the last one, in place of a kubelet updating pod status.
+func withPodPhases(t *testing.T, clients *ClusterClients, phases ...corev1.PodPhase) {
+	t.Helper()
+
+	cs, ok := clients.Kubernetes.(*k8sfake.Clientset)
+	require.True(t, ok, "expected a fake clientset")
+
+	var mu sync.Mutex
+	gets := 0
+	cs.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		get := action.(k8stesting.GetAction)
+		obj, err := cs.Tracker().Get(get.GetResource(), get.GetNamespace(), get.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+
+		mu.Lock()
+		phase := phases[min(gets, len(phases)-1)]
+		gets++
+		mu.Unlock()
+
+		pod := obj.(*corev1.Pod).DeepCopy()
+		pod.Status.Phase = phase
+		return true, pod, nil
+	})
+}
+
+func TestWaitForReadyNodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		nodes     []runtime.Object
+		wantReady int
+		wantErr   bool
+	}{
+		{name: "one of two ready", nodes: []runtime.Object{node("a", corev1.ConditionTrue), node("b", corev1.ConditionFalse)}, wantReady: 1},
+		{name: "all ready", nodes: []runtime.Object{node("a", corev1.ConditionTrue), node("b", corev1.ConditionTrue)}, wantReady: 2},
+		{name: "none ready", nodes: []runtime.Object{node("a", corev1.ConditionUnknown)}, wantErr: true},
+		{name: "no nodes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := newFakeClusterClients(t, useFakeEKS(t), tt.nodes...)
+
+			ready, err := waitForReadyNodes(t, clients.Kubernetes)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantReady, ready)
+		})
+	}
+}
+
+func TestValidateNodeReadiness(t *testing.T) {
+	clients := newFakeClusterClients(t, useFakeEKS(t), node("a", corev1.ConditionTrue))
+	validateNodeReadiness(t, clients)
+}
+
+func TestRunTestWorkload(t *testing.T) {
+	tests := []struct {
+		name    string
+		phases  []corev1.PodPhase
+		wantErr string
+	}{
+		{name: "pending then running", phases: []corev1.PodPhase{corev1.PodPending, corev1.PodPending, corev1.PodRunning}},
+		{name: "failed stops polling", phases: []corev1.PodPhase{corev1.PodPending, corev1.PodFailed}, wantErr: "terminated in Failed state"},
+		{name: "never scheduled", phases: []corev1.PodPhase{corev1.PodPending}, wantErr: "unsuccessful after 5 retries"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := newFakeClusterClients(t, useFakeEKS(t))
+			withPodPhases(t, clients, tt.phases...)
+
+			err := runTestWorkload(t, clients.Kubernetes)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			pods, err := clients.Kubernetes.CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{})
+			require.NoError(t, err)
+			assert.Empty(t, pods.Items, "test pod should be deleted")
+		})
+	}
+}
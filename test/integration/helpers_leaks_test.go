@@ -0,0 +1,232 @@
+// Offline tests for the post-destroy leak check, run against a fake inventory.
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apex/terratest-eks/cleanup"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeInventory returns successive snapshots from Find, repeating the last one,
+// in place of AWS finishing deletions over time.
+type fakeInventory struct {
+	snapshots [][]cleanup.Resource
+	err       error
+	calls     int
+}
+
+func (f *fakeInventory) Find(context.Context) ([]cleanup.Resource, error) {
+	found := f.snapshots[min(f.calls, len(f.snapshots)-1)]
+	f.calls++
+	return found, f.err
+}
+
+// recordingT collects Error calls instead of failing the test.
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Error(args ...interface{}) { t.errors = append(t.errors, fmt.Sprint(args...)) }
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertNoLeaks(t *testing.T) {
+	interval := sharedRetryInterval
+	sharedRetryInterval = time.Millisecond
+	t.Cleanup(func() { sharedRetryInterval = interval })
+
+	key := cleanup.Resource{Kind: cleanup.KindKMSKey, ID: "key-1", ARN: "arn:aws:kms:us-west-1:123456789012:key/key-1",
+		Tags: map[string]string{clusterVersionTag: "1.33"}}
+	logs := cleanup.Resource{Kind: cleanup.KindLogGroup, ID: "/aws/eks/demo/cluster", ARN: "arn:aws:logs:us-west-1:123456789012:log-group:/aws/eks/demo/cluster",
+		Tags: map[string]string{clusterVersionTag: "1.32"}}
+	vpc := cleanup.Resource{Kind: cleanup.KindVPC, ID: "vpc-1"}
+
+	tests := []struct {
+		name      string
+		inventory *fakeInventory
+		selects   func(cleanup.Resource) bool
+		want      []string
+		wantCalls int
+	}{
+		{
+			name:      "clean",
+			inventory: &fakeInventory{snapshots: [][]cleanup.Resource{nil}},
+			selects:   allResources,
+			wantCalls: 1,
+		},
+		{
+			name:      "deleted after a retry",
+			inventory: &fakeInventory{snapshots: [][]cleanup.Resource{{key}, nil}},
+			selects:   allResources,
+			wantCalls: 2,
+		},
+		{
+			name:      "other versions are not this version's leaks",
+			inventory: &fakeInventory{snapshots: [][]cleanup.Resource{{logs, vpc}}},
+			selects:   versionResources("1.33", "test-eks-1-33-abc"),
+			wantCalls: 1,
+		},
+		{
+			name:      "leaked",
+			inventory: &fakeInventory{snapshots: [][]cleanup.Resource{{key, logs, vpc}}},
+			selects:   allResources,
+			want: []string{"Destroying VPC demo leaked 3 resources:\n" +
+				"  arn:aws:kms:us-west-1:123456789012:key/key-1\n" +
+				"  arn:aws:logs:us-west-1:123456789012:log-group:/aws/eks/demo/cluster\n" +
+				"  VPC vpc-1"},
+			wantCalls: leakCheckRetries + 1,
+		},
+		{
+			name:      "leaked and partly listed",
+			inventory: &fakeInventory{snapshots: [][]cleanup.Resource{{key}}, err: errors.New("AccessDenied")},
+			selects:   versionResources("1.33", "test-eks-1-33-abc"),
+			want: []string{"Destroying VPC demo leaked 1 resources:\n" +
+				"  arn:aws:kms:us-west-1:123456789012:key/key-1\n" +
+				"Some resources could not be listed: AccessDenied"},
+			wantCalls: leakCheckRetries + 1,
+		},
+		{
+			name:      "unlistable",
+			inventory: &fakeInventory{snapshots: [][]cleanup.Resource{nil}, err: errors.New("AccessDenied")},
+			selects:   allResources,
+			want:      []string{"Could not check VPC demo for leaked resources: AccessDenied"},
+			wantCalls: leakCheckRetries + 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &recordingT{TB: t}
+			assertNoLeaks(rt, tt.inventory, "VPC demo", tt.selects)
+			assert.Equal(t, tt.want, rt.errors)
+			assert.Equal(t, tt.wantCalls, tt.inventory.calls)
+		})
+	}
+}
+
+// stubEC2 answers network interface and security group queries from fixed
+// lists, honouring vpc-id and tag filters.
+type stubEC2 struct {
+	ec2iface.EC2API
+
+	interfaces []*ec2.NetworkInterface
+	groups     []*ec2.SecurityGroup
+}
+
+// stubMatch reports whether a resource in vpcID with tags passes every filter.
+func stubMatch(filters []*ec2.Filter, vpcID string, tags []*ec2.Tag) bool {
+	for _, f := range filters {
+		name := aws.StringValue(f.Name)
+		got := ""
+		switch {
+		case name == "vpc-id":
+			got = vpcID
+		case strings.HasPrefix(name, "tag:"):
+			for _, tag := range tags {
+				if aws.StringValue(tag.Key) == strings.TrimPrefix(name, "tag:") {
+					got = aws.StringValue(tag.Value)
+				}
+			}
+		}
+		matched := false
+		for _, v := range f.Values {
+			matched = matched || got != "" && aws.StringValue(v) == got
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *stubEC2) DescribeNetworkInterfacesPagesWithContext(_ aws.Context, in *ec2.DescribeNetworkInterfacesInput, fn func(*ec2.DescribeNetworkInterfacesOutput, bool) bool, _ ...request.Option) error {
+	page := &ec2.DescribeNetworkInterfacesOutput{}
+	for _, eni := range s.interfaces {
+		if stubMatch(in.Filters, aws.StringValue(eni.VpcId), eni.TagSet) {
+			page.NetworkInterfaces = append(page.NetworkInterfaces, eni)
+		}
+	}
+	fn(page, true)
+	return nil
+}
+
+func (s *stubEC2) DescribeSecurityGroupsWithContext(_ aws.Context, in *ec2.DescribeSecurityGroupsInput, _ ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error) {
+	out := &ec2.DescribeSecurityGroupsOutput{}
+	for _, sg := range s.groups {
+		if stubMatch(in.Filters, aws.StringValue(sg.VpcId), sg.Tags) {
+			out.SecurityGroups = append(out.SecurityGroups, sg)
+		}
+	}
+	return out, nil
+}
+
+func TestLeakInventory(t *testing.T) {
+	clusterTag := func(key, name string) []*ec2.Tag {
+		return []*ec2.Tag{{Key: aws.String(key), Value: aws.String(name)}}
+	}
+	ec2Stub := &stubEC2{
+		interfaces: []*ec2.NetworkInterface{
+			// Left by the VPC CNI in a pool VPC the run doesn't own.
+			{NetworkInterfaceId: aws.String("eni-cni"), VpcId: aws.String("vpc-pool"), TagSet: clusterTag("cluster.k8s.aws/name", "test-eks-1-33-abc")},
+			{NetworkInterfaceId: aws.String("eni-legacy"), VpcId: aws.String("vpc-pool"), TagSet: clusterTag("cluster.k8s.amazonaws.com/name", "test-eks-1-32-abc")},
+			{NetworkInterfaceId: aws.String("eni-untagged"), VpcId: aws.String("vpc-run")},
+			{NetworkInterfaceId: aws.String("eni-nat"), VpcId: aws.String("vpc-run"), InterfaceType: aws.String(ec2.NetworkInterfaceTypeNatGateway)},
+			{NetworkInterfaceId: aws.String("eni-other"), VpcId: aws.String("vpc-pool"), TagSet: clusterTag("cluster.k8s.aws/name", "test-eks-1-33-xyz")},
+		},
+		groups: []*ec2.SecurityGroup{
+			{GroupId: aws.String("sg-eks"), GroupName: aws.String("eks-cluster-sg"), VpcId: aws.String("vpc-pool"), Tags: clusterTag("aws:eks:cluster-name", "test-eks-1-33-abc")},
+			{GroupId: aws.String("sg-default"), GroupName: aws.String("default"), VpcId: aws.String("vpc-run")},
+			{GroupId: aws.String("sg-node"), GroupName: aws.String("node"), VpcId: aws.String("vpc-run")},
+		},
+	}
+	tagged := cleanup.Resource{Kind: cleanup.KindSecurityGroup, ID: "sg-node", Tags: map[string]string{clusterVersionTag: "1.33"}}
+	inv := &leakInventory{
+		tagged:  &fakeInventory{snapshots: [][]cleanup.Resource{{tagged}}},
+		network: &cleanup.Cleaner{Clients: cleanup.Clients{EC2: ec2Stub}},
+	}
+
+	found, err := inv.Find(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []cleanup.Resource{tagged}, found, "nothing beyond the run's tags until VPCs or clusters are added")
+
+	inv.addVPC("vpc-run")
+	inv.addCluster("test-eks-1-33-abc")
+	inv.addCluster("test-eks-1-32-abc")
+	found, err = inv.Find(context.Background())
+	require.NoError(t, err)
+	var got []string
+	for _, r := range found {
+		got = append(got, r.String())
+	}
+	assert.Equal(t, []string{
+		"SecurityGroup sg-node",
+		"NetworkInterface eni-untagged",
+		"SecurityGroup sg-eks",
+		"NetworkInterface eni-cni",
+		"NetworkInterface eni-legacy",
+	}, got, "tagged resources, then the run VPC's and the clusters' interfaces and groups, each once")
+
+	selects := versionResources("1.33", "test-eks-1-33-abc")
+	var selected []string
+	for _, r := range found {
+		if selects(r) {
+			selected = append(selected, r.String())
+		}
+	}
+	assert.Equal(t, []string{"SecurityGroup sg-node", "SecurityGroup sg-eks", "NetworkInterface eni-cni"}, selected,
+		"a version's leaks include what EKS and the VPC CNI tagged for its cluster")
+}
@@ -4,24 +4,28 @@ package test
 import (
 	"context"
 	"encoding/base64"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/apex/terratest-eks/catalog"
+	"github.com/apex/terratest-eks/matrix"
+	"github.com/apex/terratest-eks/version"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -30,7 +34,12 @@ import (
 	"sigs.k8s.io/aws-iam-authenticator/pkg/token"
 )
 
-const (
+// resumeRun continues the run recorded in .task/run-state.json instead of
+// starting a new one. Pass with `go test ./integration -args -resume`, or set MATRIX_RESUME=true.
+var resumeRun = flag.Bool("resume", false, "resume the matrix run recorded in .task/run-state.json")
+
+// Retry settings for polling helpers. Vars so offline tests against fakeaws can shorten them.
+var (
 	sharedRetryInterval = 10 * time.Second
 	sharedMaxRetries    = 30
 )
@@ -44,7 +53,7 @@ func getEnvWithDefault(key, defaultValue string) string {
 }
 
 // getKubernetesClient creates a Kubernetes client for the given EKS cluster.
-func getKubernetesClient(t *testing.T, region, clusterName, endpoint, caData string) *kubernetes.Clientset {
+func getKubernetesClient(t testing.TB, region, clusterName, endpoint, caData string) kubernetes.Interface {
 	t.Helper()
 
 	caBytes, err := base64.StdEncoding.DecodeString(caData)
@@ -75,30 +84,28 @@ func getKubernetesClient(t *testing.T, region, clusterName, endpoint, caData str
 	return clientset
 }
 
-// validateClusterEndpoint checks that the cluster endpoint uses HTTPS and is an EKS endpoint.
-func validateClusterEndpoint(t *testing.T, endpoint string) {
-	t.Helper()
-	assert.True(t, strings.HasPrefix(endpoint, "https://"), "Endpoint should be HTTPS")
-	assert.Contains(t, endpoint, ".eks.amazonaws.com", "Endpoint should be an EKS endpoint")
-}
-
 // validateClusterStatus validates the cluster exists via the AWS SDK and is ACTIVE.
 // If expectedVersion is non-empty, it also asserts the cluster version starts with that prefix.
-func validateClusterStatus(t *testing.T, region, clusterName, expectedVersion string) {
+func validateClusterStatus(t testing.TB, clients *ClusterClients, clusterName, expectedVersion string) {
 	t.Helper()
 
-	sess, err := session.NewSessionWithOptions(session.Options{
-		Config: aws.Config{
-			Region: aws.String(region),
-		},
-		SharedConfigState: session.SharedConfigEnable,
-	})
-	require.NoError(t, err, "Failed to create AWS session")
+	cluster, err := waitForClusterActive(t, clients.EKS, clusterName)
+	require.NoError(t, err, "Cluster should be in ACTIVE state")
 
-	eksSvc := eks.New(sess)
+	if expectedVersion != "" {
+		actualVersion := aws.StringValue(cluster.Version)
+		assert.True(t, strings.HasPrefix(actualVersion, expectedVersion),
+			"Cluster version from AWS SDK should match expected %s, got %s", expectedVersion, actualVersion)
+	}
+}
+
+// waitForClusterActive polls DescribeCluster until the cluster is ACTIVE.
+// A FAILED cluster stops polling immediately since it will never recover.
+func waitForClusterActive(t testing.TB, eksSvc eksiface.EKSAPI, clusterName string) (*eks.Cluster, error) {
+	t.Helper()
 
-	var actualVersion string
-	_, err = retry.DoWithRetryE(t, "Describe EKS cluster", sharedMaxRetries, sharedRetryInterval, func() (string, error) {
+	var cluster *eks.Cluster
+	_, err := retry.DoWithRetryE(t, "Describe EKS cluster", sharedMaxRetries, sharedRetryInterval, func() (string, error) {
 		result, err := eksSvc.DescribeCluster(&eks.DescribeClusterInput{
 			Name: aws.String(clusterName),
 		})
@@ -107,28 +114,87 @@ func validateClusterStatus(t *testing.T, region, clusterName, expectedVersion st
 		}
 
 		status := aws.StringValue(result.Cluster.Status)
-		if status != "ACTIVE" {
-			return "", fmt.Errorf("cluster status is %s, waiting for ACTIVE", status)
+		switch status {
+		case eks.ClusterStatusActive:
+			cluster = result.Cluster
+			return status, nil
+		case eks.ClusterStatusFailed:
+			return "", retry.FatalError{Underlying: fmt.Errorf("cluster %s is %s", clusterName, status)}
 		}
+		return "", fmt.Errorf("cluster status is %s, waiting for ACTIVE", status)
+	})
+
+	return cluster, err
+}
 
-		actualVersion = aws.StringValue(result.Cluster.Version)
-		return status, nil
+// validateNodegroupsActive asserts the cluster has at least one managed node group
+// and waits for every node group to become ACTIVE.
+func validateNodegroupsActive(t testing.TB, clients *ClusterClients, clusterName string) {
+	t.Helper()
+	require.NoError(t, waitForNodegroupsActive(t, clients.EKS, clusterName), "Node groups should be in ACTIVE state")
+}
+
+// waitForNodegroupsActive polls DescribeNodegroup for each of the cluster's node
+// groups until all are ACTIVE. CREATE_FAILED stops polling immediately.
+func waitForNodegroupsActive(t testing.TB, eksSvc eksiface.EKSAPI, clusterName string) error {
+	t.Helper()
+
+	var names []string
+	err := eksSvc.ListNodegroupsPages(&eks.ListNodegroupsInput{
+		ClusterName: aws.String(clusterName),
+	}, func(page *eks.ListNodegroupsOutput, _ bool) bool {
+		names = append(names, aws.StringValueSlice(page.Nodegroups)...)
+		return true
 	})
+	if err != nil {
+		return fmt.Errorf("failed to list node groups: %w", err)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("cluster %s has no managed node groups", clusterName)
+	}
 
-	require.NoError(t, err, "Cluster should be in ACTIVE state")
+	for _, name := range names {
+		_, err := retry.DoWithRetryE(t, "Describe node group "+name, sharedMaxRetries, sharedRetryInterval, func() (string, error) {
+			result, err := eksSvc.DescribeNodegroup(&eks.DescribeNodegroupInput{
+				ClusterName:   aws.String(clusterName),
+				NodegroupName: aws.String(name),
+			})
+			if err != nil {
+				return "", err
+			}
 
-	if expectedVersion != "" {
-		assert.True(t, strings.HasPrefix(actualVersion, expectedVersion),
-			"Cluster version from AWS SDK should match expected %s, got %s", expectedVersion, actualVersion)
+			status := aws.StringValue(result.Nodegroup.Status)
+			switch status {
+			case eks.NodegroupStatusActive:
+				return status, nil
+			case eks.NodegroupStatusCreateFailed:
+				return "", retry.FatalError{Underlying: fmt.Errorf("node group %s is %s", name, status)}
+			}
+			return "", fmt.Errorf("node group %s status is %s, waiting for ACTIVE", name, status)
+		})
+		if err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
 
 // validateNodeReadiness checks that at least one worker node is Ready.
-func validateNodeReadiness(t *testing.T, clientset *kubernetes.Clientset) {
+func validateNodeReadiness(t testing.TB, clients *ClusterClients) {
 	t.Helper()
+	_, err := waitForReadyNodes(t, clients.Kubernetes)
+	require.NoError(t, err, "At least one node should be ready")
+}
 
+// waitForReadyNodes polls the node list until at least one node is Ready and
+// returns how many are.
+func waitForReadyNodes(t testing.TB, k8s kubernetes.Interface) (int, error) {
+	t.Helper()
+
+	readyCount := 0
 	_, err := retry.DoWithRetryE(t, "Wait for nodes to be ready", sharedMaxRetries, sharedRetryInterval, func() (string, error) {
-		nodes, err := clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+		nodes, err := k8s.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
 		if err != nil {
 			return "", fmt.Errorf("failed to list nodes: %w", err)
 		}
@@ -137,7 +203,7 @@ func validateNodeReadiness(t *testing.T, clientset *kubernetes.Clientset) {
 			return "", fmt.Errorf("no nodes found")
 		}
 
-		readyCount := 0
+		readyCount = 0
 		for _, node := range nodes.Items {
 			for _, condition := range node.Status.Conditions {
 				if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
@@ -154,11 +220,19 @@ func validateNodeReadiness(t *testing.T, clientset *kubernetes.Clientset) {
 		return fmt.Sprintf("%d nodes ready", readyCount), nil
 	})
 
-	require.NoError(t, err, "At least one node should be ready")
+	return readyCount, err
 }
 
 // validateWorkloadDeployment deploys a test nginx pod and waits for it to reach Running state.
-func validateWorkloadDeployment(t *testing.T, clientset *kubernetes.Clientset) {
+func validateWorkloadDeployment(t testing.TB, clients *ClusterClients) {
+	t.Helper()
+	require.NoError(t, runTestWorkload(t, clients.Kubernetes), "Test pod should be running")
+}
+
+// runTestWorkload creates a test nginx pod, waits for it to reach Running, and
+// deletes it. A pod that terminates (Failed or Succeeded) stops polling
+// immediately since with RestartPolicyNever it will never run again.
+func runTestWorkload(t testing.TB, k8s kubernetes.Interface) error {
 	t.Helper()
 
 	namespace := "default"
@@ -199,122 +273,237 @@ func validateWorkloadDeployment(t *testing.T, clientset *kubernetes.Clientset) {
 		},
 	}
 
-	_, err := clientset.CoreV1().Pods(namespace).Create(context.Background(), pod, metav1.CreateOptions{})
-	require.NoError(t, err, "Failed to create test pod")
+	if _, err := k8s.CoreV1().Pods(namespace).Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create test pod: %w", err)
+	}
 
 	defer func() {
-		_ = clientset.CoreV1().Pods(namespace).Delete(context.Background(), podName, metav1.DeleteOptions{})
+		_ = k8s.CoreV1().Pods(namespace).Delete(context.Background(), podName, metav1.DeleteOptions{})
 	}()
 
-	_, err = retry.DoWithRetryE(t, "Wait for pod to be running", sharedMaxRetries, sharedRetryInterval, func() (string, error) {
-		p, err := clientset.CoreV1().Pods(namespace).Get(context.Background(), podName, metav1.GetOptions{})
+	_, err := retry.DoWithRetryE(t, "Wait for pod to be running", sharedMaxRetries, sharedRetryInterval, func() (string, error) {
+		p, err := k8s.CoreV1().Pods(namespace).Get(context.Background(), podName, metav1.GetOptions{})
 		if err != nil {
 			return "", fmt.Errorf("failed to get pod: %w", err)
 		}
 
-		if p.Status.Phase != corev1.PodRunning {
-			return "", fmt.Errorf("pod is in %s state, waiting for Running", p.Status.Phase)
+		switch p.Status.Phase {
+		case corev1.PodRunning:
+			return "pod running", nil
+		case corev1.PodFailed, corev1.PodSucceeded:
+			return "", retry.FatalError{Underlying: fmt.Errorf("pod %s terminated in %s state", podName, p.Status.Phase)}
 		}
+		return "", fmt.Errorf("pod is in %s state, waiting for Running", p.Status.Phase)
+	})
+
+	return err
+}
+
+// podFailedError is a command pod that ran and failed. Message is its
+// termination message.
+type podFailedError struct {
+	Pod     string
+	Message string
+}
+
+func (e *podFailedError) Error() string {
+	return fmt.Sprintf("pod %s failed: %s", e.Pod, e.Message)
+}
 
-		return "pod running", nil
+// runCommandPod runs pod, whose containers are expected to exit, and returns
+// the termination message of its first container. A pod that fails returns
+// a *podFailedError with the message, so it should use FallbackToLogsOnError
+// to report why. The pod is deleted afterwards.
+func runCommandPod(t testing.TB, k8s kubernetes.Interface, pod *corev1.Pod) (string, error) {
+	t.Helper()
+
+	pods := k8s.CoreV1().Pods(pod.Namespace)
+	if _, err := pods.Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+		return "", fmt.Errorf("failed to create pod %s: %w", pod.Name, err)
+	}
+	defer func() {
+		_ = pods.Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+	}()
+
+	var p *corev1.Pod
+	_, err := retry.DoWithRetryE(t, "Wait for pod "+pod.Name+" to complete", sharedMaxRetries, sharedRetryInterval, func() (string, error) {
+		var err error
+		p, err = pods.Get(context.Background(), pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get pod: %w", err)
+		}
+		switch p.Status.Phase {
+		case corev1.PodSucceeded, corev1.PodFailed:
+			return string(p.Status.Phase), nil
+		}
+		return "", fmt.Errorf("pod is in %s state, waiting for it to complete", p.Status.Phase)
 	})
+	if err != nil {
+		return "", retry.FatalError{Underlying: err}
+	}
 
-	require.NoError(t, err, "Test pod should be running")
+	msg := strings.TrimSpace(terminationMessage(p))
+	if p.Status.Phase == corev1.PodFailed {
+		return "", &podFailedError{Pod: pod.Name, Message: msg}
+	}
+	return msg, nil
 }
 
-// discoverEKSVersions queries AWS for supported EKS versions >= minVersion.
-// Uses the vpc-cni addon compatibility list as the source of truth.
-func discoverEKSVersions(t *testing.T, region, minVersion string) []string {
+// terminationMessage returns the termination message of p's first container.
+func terminationMessage(p *corev1.Pod) string {
+	for _, cs := range p.Status.ContainerStatuses {
+		if cs.State.Terminated != nil {
+			return cs.State.Terminated.Message
+		}
+	}
+	return ""
+}
+
+// waitForDeploymentAvailable polls until every desired replica of the
+// Deployment is updated and available.
+func waitForDeploymentAvailable(t testing.TB, k8s kubernetes.Interface, namespace, name string) (*appsv1.Deployment, error) {
 	t.Helper()
 
-	sess, err := session.NewSessionWithOptions(session.Options{
-		Config:            aws.Config{Region: aws.String(region)},
-		SharedConfigState: session.SharedConfigEnable,
+	var d *appsv1.Deployment
+	_, err := retry.DoWithRetryE(t, "Wait for deployment "+name, sharedMaxRetries, sharedRetryInterval, func() (string, error) {
+		var err error
+		d, err = k8s.AppsV1().Deployments(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get deployment: %w", err)
+		}
+
+		want := int32(1)
+		if d.Spec.Replicas != nil {
+			want = *d.Spec.Replicas
+		}
+		s := d.Status
+		if s.ObservedGeneration < d.Generation || s.UpdatedReplicas < want || s.AvailableReplicas < want {
+			return "", fmt.Errorf("deployment %s has %d/%d available replicas", name, s.AvailableReplicas, want)
+		}
+		return fmt.Sprintf("%d replicas available", s.AvailableReplicas), nil
 	})
-	require.NoError(t, err, "Failed to create AWS session")
+	return d, err
+}
+
+// waitForDaemonSetRolledOut polls until every node that should run the
+// DaemonSet runs an updated, available pod.
+func waitForDaemonSetRolledOut(t testing.TB, k8s kubernetes.Interface, namespace, name string) (*appsv1.DaemonSet, error) {
+	t.Helper()
 
-	eksSvc := eks.New(sess)
+	var ds *appsv1.DaemonSet
+	_, err := retry.DoWithRetryE(t, "Wait for daemonset "+name, sharedMaxRetries, sharedRetryInterval, func() (string, error) {
+		var err error
+		ds, err = k8s.AppsV1().DaemonSets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get daemonset: %w", err)
+		}
+
+		s := ds.Status
+		if s.DesiredNumberScheduled == 0 {
+			return "", fmt.Errorf("daemonset %s is not scheduled on any node", name)
+		}
+		if s.ObservedGeneration < ds.Generation || s.UpdatedNumberScheduled < s.DesiredNumberScheduled || s.NumberAvailable < s.DesiredNumberScheduled {
+			return "", fmt.Errorf("daemonset %s has %d/%d updated and %d/%d available pods",
+				name, s.UpdatedNumberScheduled, s.DesiredNumberScheduled, s.NumberAvailable, s.DesiredNumberScheduled)
+		}
+		return fmt.Sprintf("%d pods available", s.NumberAvailable), nil
+	})
+	return ds, err
+}
+
+// discoverEKSVersions returns the EKS versions matching constraint, oldest first.
+// Set EKS_VERSION_SOURCE=catalog to skip AWS and use the offline catalog; otherwise
+// AWS is queried and the catalog is only used if AWS is unreachable.
+func discoverEKSVersions(t testing.TB, region, eksEndpoint string, constraint version.Constraint) []version.KubeVersion {
+	t.Helper()
 
+	var versions []version.KubeVersion
+	if getEnvWithDefault("EKS_VERSION_SOURCE", "aws") == "catalog" {
+		versions = catalogEKSVersions(t)
+	} else {
+		eksSvc, err := newEKSClient(region, eksEndpoint)
+		if err == nil {
+			versions, err = awsEKSVersions(eksSvc)
+		}
+		if err != nil {
+			t.Logf("Falling back to the offline version catalog: %v", err)
+			versions = catalogEKSVersions(t)
+		}
+	}
+
+	versions = constraint.Filter(versions)
+	version.Sort(versions)
+
+	require.NotEmpty(t, versions, "No EKS versions found matching %s", constraint)
+
+	return versions
+}
+
+// awsEKSVersions queries AWS for the cluster versions EKS currently supports.
+// Uses the vpc-cni addon compatibility list as the source of truth.
+func awsEKSVersions(eksSvc eksiface.EKSAPI) ([]version.KubeVersion, error) {
 	input := &eks.DescribeAddonVersionsInput{
 		AddonName: aws.String("vpc-cni"),
 	}
 
 	result, err := eksSvc.DescribeAddonVersions(input)
-	require.NoError(t, err, "Failed to describe addon versions")
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe addon versions: %w", err)
+	}
 
 	// Collect unique cluster versions
-	versionSet := make(map[string]bool)
+	versionSet := make(map[version.KubeVersion]bool)
 	for _, addon := range result.Addons {
 		for _, addonVersion := range addon.AddonVersions {
 			for _, compat := range addonVersion.Compatibilities {
 				if compat.ClusterVersion != nil {
-					versionSet[*compat.ClusterVersion] = true
+					v, err := version.Parse(*compat.ClusterVersion)
+					if err != nil {
+						return nil, fmt.Errorf("AWS returned an unparseable cluster version: %w", err)
+					}
+					versionSet[v] = true
 				}
 			}
 		}
 	}
 
-	// Filter >= minVersion and sort
-	var versions []string
+	versions := make([]version.KubeVersion, 0, len(versionSet))
 	for v := range versionSet {
-		if compareVersions(v, minVersion) >= 0 {
-			versions = append(versions, v)
-		}
+		versions = append(versions, v)
 	}
 
-	sort.Slice(versions, func(i, j int) bool {
-		return compareVersions(versions[i], versions[j]) < 0
-	})
-
-	require.NotEmpty(t, versions, "No EKS versions found >= %s", minVersion)
-
-	return versions
+	return versions, nil
 }
 
-// compareVersions compares two dotted version strings (e.g. "1.31" vs "1.32").
-// Returns -1, 0, or 1.
-func compareVersions(a, b string) int {
-	aParts := strings.Split(a, ".")
-	bParts := strings.Split(b, ".")
-
-	maxLen := len(aParts)
-	if len(bParts) > maxLen {
-		maxLen = len(bParts)
-	}
+// catalogEKSVersions returns the versions the offline catalog lists as
+// creatable today (standard or extended support).
+func catalogEKSVersions(t testing.TB) []version.KubeVersion {
+	t.Helper()
 
-	for i := 0; i < maxLen; i++ {
-		var aNum, bNum int
-		if i < len(aParts) {
-			aNum, _ = strconv.Atoi(aParts[i])
-		}
-		if i < len(bParts) {
-			bNum, _ = strconv.Atoi(bParts[i])
-		}
-		if aNum < bNum {
-			return -1
-		}
-		if aNum > bNum {
-			return 1
-		}
-	}
+	cat, err := catalog.Load()
+	require.NoError(t, err, "Failed to load EKS version catalog")
 
-	return 0
+	t.Logf("Using offline EKS version catalog (updated %s)", cat.Updated.Format("2006-01-02"))
+	return cat.Available(time.Now())
 }
 
 // copyFixtureToTemp copies a Terraform fixture directory to a temp dir,
 // rewriting relative module source paths to absolute. This allows parallel
 // Terraform runs without state lock conflicts.
-func copyFixtureToTemp(t *testing.T, fixtureRelPath string) string {
+func copyFixtureToTemp(t testing.TB, fixtureRelPath string) string {
 	t.Helper()
+	// Temp directory is auto-cleaned by t.TempDir()
+	return copyFixture(t, fixtureRelPath, t.TempDir())
+}
 
-	// Resolve fixture path relative to repo root (tests run from test/integration/)
-	repoRoot, err := filepath.Abs(filepath.Join("..", ".."))
-	require.NoError(t, err, "Failed to resolve repo root")
-
-	fixtureSrc := filepath.Join(repoRoot, fixtureRelPath)
+// copyFixture copies a Terraform fixture's .tf files into dstDir the same way
+// as copyFixtureToTemp, leaving any Terraform state already in dstDir alone so
+// an interrupted run can be re-applied or destroyed from the same directory.
+func copyFixture(t testing.TB, fixtureRelPath, dstDir string) string {
+	t.Helper()
 
-	// Create temp directory (auto-cleaned by t.TempDir())
-	tmpDir := t.TempDir()
+	fixtureSrc := repoPath(t, fixtureRelPath)
+	require.NoError(t, os.MkdirAll(dstDir, 0755), "Failed to create %s", dstDir)
 
 	entries, err := os.ReadDir(fixtureSrc)
 	require.NoError(t, err, "Failed to read fixture directory: %s", fixtureSrc)
@@ -331,12 +520,22 @@ func copyFixtureToTemp(t *testing.T, fixtureRelPath string) string {
 		// Rewrite relative module source paths to absolute
 		contentStr := rewriteModuleSources(string(content), fixtureSrc)
 
-		dstPath := filepath.Join(tmpDir, entry.Name())
+		dstPath := filepath.Join(dstDir, entry.Name())
 		err = os.WriteFile(dstPath, []byte(contentStr), 0644)
 		require.NoError(t, err, "Failed to write %s", dstPath)
 	}
 
-	return tmpDir
+	return dstDir
+}
+
+// repoPath resolves a path relative to the repo root (tests run from test/integration/).
+func repoPath(t testing.TB, relPath string) string {
+	t.Helper()
+
+	repoRoot, err := filepath.Abs(filepath.Join("..", ".."))
+	require.NoError(t, err, "Failed to resolve repo root")
+
+	return filepath.Join(repoRoot, relPath)
 }
 
 // rewriteModuleSources replaces relative source paths (source = "../..") with
@@ -351,8 +550,23 @@ func rewriteModuleSources(content, fixtureDir string) string {
 	})
 }
 
+// defaultPipelineTTL is how long a run's resources live before the reaper may
+// delete them. It covers the integration timeout plus time to resume.
+const defaultPipelineTTL = 6 * time.Hour
+
 // getPipelineTags generates pipeline tags for resource identification and cleanup.
-func getPipelineTags(projectName string) map[string]string {
+// CreatedAt and ExpiresAt (RFC 3339, UTC) let `task cleanup-reap` delete orphans
+// without touching in-flight runs; PIPELINE_TTL overrides the lifetime.
+func getPipelineTags(t testing.TB, projectName string) map[string]string {
+	t.Helper()
+
+	ttl := defaultPipelineTTL
+	if raw := os.Getenv("PIPELINE_TTL"); raw != "" {
+		var err error
+		ttl, err = time.ParseDuration(raw)
+		require.NoError(t, err, "Invalid PIPELINE_TTL")
+	}
+
 	runID := os.Getenv("PIPELINE_RUN_ID")
 
 	environment := "local"
@@ -367,27 +581,42 @@ func getPipelineTags(projectName string) map[string]string {
 		runID = fmt.Sprintf("local-%s", time.Now().Format("20060102-150405"))
 	}
 
+	now := time.Now().UTC()
 	return map[string]string{
 		"Pipeline":    projectName,
 		"RunID":       runID,
 		"Environment": environment,
+		"CreatedAt":   now.Format(time.RFC3339),
+		"ExpiresAt":   now.Add(ttl).Format(time.RFC3339),
 	}
 }
 
 // testConfig centralizes environment variable lookups and shared test setup.
 type testConfig struct {
-	AWSRegion    string
-	AWSProfile   string
-	ProjectName  string
-	MinVersion   string
-	PipelineTags map[string]string
-	UniqueID     string
+	AWSRegion         string
+	AWSProfile        string
+	EKSEndpoint       string
+	ProjectName       string
+	VersionConstraint version.Constraint
+	VersionSelector   matrix.VersionSelector
+	LastGreenPath     string
+	CodeFingerprint   string
+	Resume            bool
+	RunStatePath      string
+	RunsDir           string
+	ReportDir         string
+	BudgetUSD         float64
+	VPCPool           string
+	PrivateEndpoint   bool
+	Checks            []Check
+	PipelineTags      map[string]string
+	UniqueID          string
 }
 
 // newTestConfig creates a testConfig, skipping in short mode.
 // Defaults AWS_PROFILE to "sandbox" for local development.
 // CI uses OIDC credentials so no profile is needed there.
-func newTestConfig(t *testing.T) *testConfig {
+func newTestConfig(t testing.TB) *testConfig {
 	t.Helper()
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
@@ -403,14 +632,67 @@ func newTestConfig(t *testing.T) *testConfig {
 		t.Setenv("AWS_PROFILE", awsProfile)
 	}
 
+	minVersion, err := version.Parse(getEnvWithDefault("MIN_EKS_VERSION", "1.31"))
+	require.NoError(t, err, "Invalid MIN_EKS_VERSION")
+
+	// EKS_VERSION_CONSTRAINT (e.g. ">=1.31,<1.35") narrows the matrix further;
+	// by default every version at or above MIN_EKS_VERSION is tested.
+	constraint := version.AtLeast(minVersion)
+	if raw := os.Getenv("EKS_VERSION_CONSTRAINT"); raw != "" {
+		constraint, err = version.ParseConstraint(raw + ",>=" + minVersion.String())
+		require.NoError(t, err, "Invalid EKS_VERSION_CONSTRAINT")
+	}
+
+	// EKS_VERSION_STRATEGY picks which discovered versions run (see matrix.NewSelector).
+	lastGreenPath := repoPath(t, filepath.Join(".task", "last-green-versions.json"))
+	selectorCfg, err := matrix.SelectorConfigFromEnv(os.Getenv, lastGreenPath)
+	require.NoError(t, err, "Invalid version selection config")
+	// The green record only holds for the Terraform code it was earned with.
+	fingerprint, err := matrix.Fingerprint(repoPath(t, "."), "modules", "examples")
+	require.NoError(t, err, "Failed to fingerprint the Terraform code")
+	selectorCfg.Fingerprint = fingerprint
+	selectorCfg.Logf = t.Logf
+	selector, err := matrix.NewSelector(selectorCfg)
+	require.NoError(t, err, "Invalid version selection config")
+
+	// MATRIX_BUDGET_USD fails the run if its estimated cost exceeds the limit.
+	var budget float64
+	if raw := os.Getenv("MATRIX_BUDGET_USD"); raw != "" {
+		budget, err = strconv.ParseFloat(raw, 64)
+		require.NoError(t, err, "Invalid MATRIX_BUDGET_USD")
+	}
+
+	// EKS_ENDPOINT_ACCESS=private deploys the matrix clusters without public
+	// endpoint access. The runner can then only run the checks that call AWS.
+	endpointAccess := getEnvWithDefault("EKS_ENDPOINT_ACCESS", "public")
+	require.Contains(t, []string{"public", "private"}, endpointAccess, "Invalid EKS_ENDPOINT_ACCESS")
+	privateEndpoint := endpointAccess == "private"
+
+	// EKS_CHECKS (e.g. "status,nodes" or "all") picks the post-deploy checks
+	// each matrix cluster runs; by default every check registered byDefault.
+	checks, err := selectChecks(os.Getenv("EKS_CHECKS"), privateEndpoint)
+	require.NoError(t, err, "Invalid EKS_CHECKS")
+
 	projectName := getEnvWithDefault("PROJECT_NAME", "eks-cluster")
 	return &testConfig{
-		AWSRegion:    getEnvWithDefault("AWS_REGION", "us-west-1"),
-		AWSProfile:   awsProfile,
-		ProjectName:  projectName,
-		MinVersion:   getEnvWithDefault("MIN_EKS_VERSION", "1.31"),
-		PipelineTags: getPipelineTags(projectName),
-		UniqueID:     strings.ToLower(random.UniqueId()),
+		AWSRegion:         getEnvWithDefault("AWS_REGION", "us-west-1"),
+		AWSProfile:        awsProfile,
+		EKSEndpoint:       os.Getenv("AWS_ENDPOINT_URL_EKS"),
+		ProjectName:       projectName,
+		VersionConstraint: constraint,
+		VersionSelector:   selector,
+		LastGreenPath:     lastGreenPath,
+		CodeFingerprint:   fingerprint,
+		Resume:            *resumeRun || os.Getenv("MATRIX_RESUME") == "true",
+		RunStatePath:      repoPath(t, filepath.Join(".task", "run-state.json")),
+		RunsDir:           repoPath(t, filepath.Join(".task", "runs")),
+		BudgetUSD:         budget,
+		VPCPool:           os.Getenv("VPC_POOL"),
+		PrivateEndpoint:   privateEndpoint,
+		Checks:            checks,
+		ReportDir:         getEnvWithDefault("MATRIX_REPORT_DIR", repoPath(t, filepath.Join(".task", "reports"))),
+		PipelineTags:      getPipelineTags(t, projectName),
+		UniqueID:          strings.ToLower(random.UniqueId()),
 	}
 }
 
@@ -423,7 +705,7 @@ type eksOutputs struct {
 }
 
 // getEKSOutputs retrieves the four standard EKS outputs from Terraform.
-func getEKSOutputs(t *testing.T, opts *terraform.Options) *eksOutputs {
+func getEKSOutputs(t testing.TB, opts *terraform.Options) *eksOutputs {
 	t.Helper()
 	return &eksOutputs{
 		ClusterEndpoint: terraform.Output(t, opts, "cluster_endpoint"),
@@ -434,7 +716,7 @@ func getEKSOutputs(t *testing.T, opts *terraform.Options) *eksOutputs {
 }
 
 // validate asserts that the EKS outputs are non-empty and match expected values.
-func (o *eksOutputs) validate(t *testing.T, expectedName, expectedVersionPrefix string) {
+func (o *eksOutputs) validate(t testing.TB, expectedName, expectedVersionPrefix string) {
 	t.Helper()
 	assert.NotEmpty(t, o.ClusterEndpoint, "Cluster endpoint should not be empty")
 	assert.NotEmpty(t, o.ClusterCAData, "Cluster CA data should not be empty")
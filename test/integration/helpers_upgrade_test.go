@@ -0,0 +1,201 @@
+// Offline tests for the upgrade-path helpers, run against the fakeaws EKS
+// server and client-go's fake clientset.
+package test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apex/terratest-eks/fakeaws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// deployment returns the sample workload with the given UID and available
+// replicas, as the deployment controller would report it.
+func deployment(uid types.UID, available int32) *appsv1.Deployment {
+	d := upgradeWorkload()
+	d.UID = uid
+	d.Generation = 2
+	d.Status = appsv1.DeploymentStatus{
+		ObservedGeneration: 2,
+		Replicas:           upgradeWorkloadReplicas,
+		UpdatedReplicas:    available,
+		AvailableReplicas:  available,
+	}
+	return d
+}
+
+func TestNodegroupVersions(t *testing.T) {
+	fake := useFakeEKS(t)
+	fake.AddCluster(fakeaws.Cluster{Name: "demo", Version: "1.33"})
+	require.NoError(t, fake.AddNodegroup("demo", fakeaws.Nodegroup{Name: "system"}))
+	require.NoError(t, fake.AddNodegroup("demo", fakeaws.Nodegroup{Name: "workers", Version: "1.32"}))
+
+	clients := newFakeClusterClients(t, fake)
+	versions, err := nodegroupVersions(clients.EKS, "demo")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"system": "1.33", "workers": "1.32"}, versions)
+
+	fake.AddCluster(fakeaws.Cluster{Name: "empty", Version: "1.33"})
+	_, err = nodegroupVersions(clients.EKS, "empty")
+	assert.ErrorContains(t, err, "has no managed node groups")
+}
+
+func TestValidateNodegroupVersions(t *testing.T) {
+	fake := useFakeEKS(t)
+	fake.AddCluster(fakeaws.Cluster{Name: "demo", Version: "1.33"})
+	require.NoError(t, fake.AddNodegroup("demo", fakeaws.Nodegroup{Name: "workers", Version: "1.33", Statuses: []string{"UPDATING", "ACTIVE"}}))
+
+	validateNodegroupVersions(t, newFakeClusterClients(t, fake), "demo", "1.33")
+}
+
+func TestValidateAddonsCompatible(t *testing.T) {
+	tests := []struct {
+		name    string
+		addon   fakeaws.Addon
+		want    string
+		wantErr string
+	}{
+		{name: "compatible", addon: fakeaws.Addon{Name: "coredns", Version: "v1.12.1-eksbuild.2"}, want: "v1.12.1-eksbuild.2"},
+		{
+			name:    "left on the previous version",
+			addon:   fakeaws.Addon{Name: "coredns", Version: "v1.11.4-eksbuild.2"},
+			want:    "v1.11.4-eksbuild.2",
+			wantErr: "Addon coredns v1.11.4-eksbuild.2 should be compatible with Kubernetes 1.33",
+		},
+		{
+			name:    "degraded stops polling",
+			addon:   fakeaws.Addon{Name: "coredns", Version: "v1.12.1-eksbuild.2", Status: "DEGRADED"},
+			want:    "v1.12.1-eksbuild.2",
+			wantErr: "addon coredns v1.12.1-eksbuild.2 is DEGRADED",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeEKS(t)
+			fake.AddCluster(fakeaws.Cluster{Name: "demo", Version: "1.33"})
+			fake.AddAddonVersion("coredns", "v1.11.4-eksbuild.2", "1.31", "1.32")
+			fake.AddAddonVersion("coredns", "v1.12.1-eksbuild.2", "1.32", "1.33")
+			require.NoError(t, fake.AddAddon("demo", tt.addon))
+
+			rt := &recordingT{TB: t}
+			versions := validateAddonsCompatible(rt, newFakeClusterClients(t, fake), "demo", "1.33", []string{"coredns"})
+			assert.Equal(t, map[string]string{"coredns": tt.want}, versions)
+			if tt.wantErr == "" {
+				assert.Empty(t, rt.errors)
+				return
+			}
+			require.Len(t, rt.errors, 1)
+			assert.Contains(t, rt.errors[0], tt.wantErr)
+		})
+	}
+}
+
+func TestWaitForDeploymentAvailable(t *testing.T) {
+	fake := useFakeEKS(t)
+
+	clients := newFakeClusterClients(t, fake, deployment("uid-1", upgradeWorkloadReplicas))
+	d, err := waitForDeploymentAvailable(t, clients.Kubernetes, upgradeWorkloadNamespace, upgradeWorkloadName)
+	require.NoError(t, err)
+	assert.Equal(t, types.UID("uid-1"), d.UID)
+
+	clients = newFakeClusterClients(t, fake, deployment("uid-1", 1))
+	_, err = waitForDeploymentAvailable(t, clients.Kubernetes, upgradeWorkloadNamespace, upgradeWorkloadName)
+	assert.ErrorContains(t, err, "unsuccessful after 5 retries")
+}
+
+func TestValidateWorkloadSurvived(t *testing.T) {
+	fake := useFakeEKS(t)
+	before := deployment("uid-1", upgradeWorkloadReplicas)
+
+	clients := newFakeClusterClients(t, fake, deployment("uid-1", upgradeWorkloadReplicas))
+	validateWorkloadSurvived(t, clients.Kubernetes, before)
+
+	// Deleted and recreated during the upgrade: available, but not the same object.
+	clients = newFakeClusterClients(t, fake, deployment("uid-2", upgradeWorkloadReplicas))
+	rt := &recordingT{TB: t}
+	validateWorkloadSurvived(rt, clients.Kubernetes, before)
+	require.Len(t, rt.errors, 1)
+	assert.Contains(t, rt.errors[0], "Sample workload should not have been recreated")
+}
+
+func TestWatchAvailability(t *testing.T) {
+	tests := []struct {
+		name        string
+		reads       []int32 // available replicas per read; -1 fails the read
+		wantSamples int
+		wantOutages int
+		wantErr     string // substring of the recorded failure
+	}{
+		{
+			name:        "replicas drained one at a time",
+			reads:       []int32{2, 1, -1, 1, 2},
+			wantSamples: 4,
+		},
+		{
+			name:        "every replica down at once",
+			reads:       []int32{2, 1, 0, 0, 2},
+			wantSamples: 5,
+			wantOutages: 2,
+			wantErr:     "none in 2 of",
+		},
+		{
+			name:    "never read",
+			reads:   []int32{-1, -1},
+			wantErr: "could not be read during the upgrade: connection refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := k8sfake.NewClientset(deployment("uid-1", upgradeWorkloadReplicas))
+			var mu sync.Mutex
+			calls := 0
+			cs.PrependReactor("get", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				available := tt.reads[min(calls, len(tt.reads)-1)]
+				calls++
+				if available < 0 {
+					return true, nil, errors.New("connection refused")
+				}
+				return true, deployment("uid-1", available), nil
+			})
+
+			w := watchAvailability(cs, upgradeWorkloadNamespace, upgradeWorkloadName, time.Millisecond)
+			require.Eventually(t, func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return calls >= len(tt.reads)
+			}, time.Second, time.Millisecond)
+			w.Stop()
+			w.Stop()
+
+			// Reads after the scripted ones repeat the last, so compare the
+			// scripted prefix only.
+			extra := calls - len(tt.reads)
+			if tt.reads[len(tt.reads)-1] >= 0 {
+				assert.Equal(t, tt.wantSamples+extra, w.samples)
+			}
+			assert.Equal(t, tt.wantOutages, w.outages)
+
+			rt := &recordingT{TB: t}
+			validateNoOutage(rt, w)
+			if tt.wantErr == "" {
+				assert.Empty(t, rt.errors)
+				return
+			}
+			require.Len(t, rt.errors, 1)
+			assert.Contains(t, rt.errors[0], tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Offline tests for the IRSA check, run against a stub IAM client and
+// client-go's fake clientset standing in for the pod identity webhook and STS.
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const (
+	testOIDCProvider    = "oidc.eks.us-west-1.amazonaws.com/id/EXAMPLED539D4633E53DE1B71EXAMPLE"
+	testOIDCProviderARN = "arn:aws:iam::123456789012:oidc-provider/" + testOIDCProvider
+	nodeRoleSession     = "arn:aws:sts::123456789012:assumed-role/demo-node-role/i-0123456789abcdef0"
+)
+
+// stubIAM records the roles created and deleted through it.
+type stubIAM struct {
+	iamiface.IAMAPI
+
+	mu      sync.Mutex
+	created []*iam.CreateRoleInput
+	deleted []string
+}
+
+func (s *stubIAM) CreateRole(in *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.created = append(s.created, in)
+	return &iam.CreateRoleOutput{Role: &iam.Role{
+		RoleName: in.RoleName,
+		Arn:      aws.String("arn:aws:iam::123456789012:role/" + aws.StringValue(in.RoleName)),
+	}}, nil
+}
+
+func (s *stubIAM) DeleteRole(in *iam.DeleteRoleInput) (*iam.DeleteRoleOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleted = append(s.deleted, aws.StringValue(in.RoleName))
+	return &iam.DeleteRoleOutput{}, nil
+}
+
+// withIRSAIdentity makes pod GETs report the pod as completed with the
+// identity STS would give it: a session of the role its ServiceAccount is
+// annotated with, or of the node role without one. The first denials pods
+// fail with AccessDenied instead, as while a new role propagates.
+func withIRSAIdentity(t *testing.T, clients *ClusterClients, denials int) {
+	t.Helper()
+
+	cs, ok := clients.Kubernetes.(*k8sfake.Clientset)
+	require.True(t, ok, "expected a fake clientset")
+
+	var mu sync.Mutex
+	failed := make(map[string]bool)
+	cs.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		get := action.(k8stesting.GetAction)
+		obj, err := cs.Tracker().Get(get.GetResource(), get.GetNamespace(), get.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		pod := obj.(*corev1.Pod).DeepCopy()
+
+		mu.Lock()
+		if _, seen := failed[pod.Name]; !seen {
+			failed[pod.Name] = len(failed) < denials
+		}
+		deny := failed[pod.Name]
+		mu.Unlock()
+
+		// The clientset is locked while reactors run, so read the tracker directly.
+		identity := nodeRoleSession
+		if sa, err := cs.Tracker().Get(corev1.SchemeGroupVersion.WithResource("serviceaccounts"), pod.Namespace, pod.Spec.ServiceAccountName); err == nil {
+			if roleARN := sa.(*corev1.ServiceAccount).Annotations[irsaRoleAnnotation]; roleARN != "" {
+				identity = strings.Replace(roleARN, ":iam::", ":sts::", 1)
+				identity = strings.Replace(identity, ":role/", ":assumed-role/", 1) + "/botocore-session-1"
+			}
+		}
+
+		pod.Status.Phase = corev1.PodSucceeded
+		msg := identity + "\n"
+		if deny {
+			pod.Status.Phase = corev1.PodFailed
+			msg = "An error occurred (AccessDenied) when calling the AssumeRoleWithWebIdentity operation: Not authorized"
+		}
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:  "aws-cli",
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: msg}},
+		}}
+		return true, pod, nil
+	})
+}
+
+func newIRSATarget() irsaTarget {
+	return irsaTarget{
+		UniqueID:        "abc123",
+		Region:          "us-west-1",
+		OIDCProvider:    testOIDCProvider,
+		OIDCProviderARN: testOIDCProviderARN,
+		Tags:            map[string]string{"RunID": "run-1"},
+	}
+}
+
+func TestValidateIRSA(t *testing.T) {
+	for _, denials := range []int{0, 2} {
+		fake := useFakeEKS(t)
+		clients := newFakeClusterClients(t, fake)
+		stub := &stubIAM{}
+		clients.IAM = stub
+		withIRSAIdentity(t, clients, denials)
+
+		validateIRSA(t, clients, newIRSATarget())
+
+		require.Len(t, stub.created, 1)
+		role := stub.created[0]
+		roleName := aws.StringValue(role.RoleName)
+		assert.True(t, strings.HasPrefix(roleName, "irsa-abc123-"), roleName)
+		assert.Equal(t, []*iam.Tag{{Key: aws.String("RunID"), Value: aws.String("run-1")}}, role.Tags)
+		assert.Contains(t, aws.StringValue(role.AssumeRolePolicyDocument), testOIDCProviderARN)
+		assert.Equal(t, []string{roleName}, stub.deleted, "the role is deleted afterwards")
+
+		sas, err := clients.Kubernetes.CoreV1().ServiceAccounts(irsaNamespace).List(context.Background(), metav1.ListOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, sas.Items, "the ServiceAccount is deleted afterwards")
+		pods, err := clients.Kubernetes.CoreV1().Pods(irsaNamespace).List(context.Background(), metav1.ListOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, pods.Items, "every pod is deleted afterwards")
+	}
+}
+
+func TestRunCallerIdentityPod(t *testing.T) {
+	fake := useFakeEKS(t)
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "plain", Namespace: irsaNamespace}}
+	clients := newFakeClusterClients(t, fake, sa)
+	withIRSAIdentity(t, clients, 1)
+
+	_, err := runCallerIdentityPod(t, clients.Kubernetes, "plain", "us-west-1")
+	assert.ErrorContains(t, err, "(AccessDenied)")
+
+	// Without the annotation the pod falls back to the node's role.
+	caller, err := runCallerIdentityPod(t, clients.Kubernetes, "plain", "us-west-1")
+	require.NoError(t, err)
+	assert.Equal(t, nodeRoleSession, caller)
+	assert.Error(t, assumedRoleMatches(caller, "arn:aws:iam::123456789012:role/demo-irsa-abc"))
+}
+
+func TestIRSATrustPolicy(t *testing.T) {
+	policy, err := irsaTrustPolicy(testOIDCProviderARN, testOIDCProvider, "default", "app")
+	require.NoError(t, err)
+
+	var doc struct {
+		Statement []struct {
+			Principal map[string]string
+			Action    string
+			Condition map[string]map[string]string
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(policy), &doc))
+	require.Len(t, doc.Statement, 1)
+	s := doc.Statement[0]
+	assert.Equal(t, testOIDCProviderARN, s.Principal["Federated"])
+	assert.Equal(t, "sts:AssumeRoleWithWebIdentity", s.Action)
+	assert.Equal(t, map[string]string{
+		testOIDCProvider + ":sub": "system:serviceaccount:default:app",
+		testOIDCProvider + ":aud": "sts.amazonaws.com",
+	}, s.Condition["StringEquals"])
+
+	_, err = irsaTrustPolicy("", "", "default", "app")
+	assert.ErrorContains(t, err, "no OIDC provider")
+}
+
+func TestAssumedRoleMatches(t *testing.T) {
+	const role = "arn:aws:iam::123456789012:role/demo-irsa-abc"
+	tests := []struct {
+		caller  string
+		role    string
+		wantErr string
+	}{
+		{caller: "arn:aws:sts::123456789012:assumed-role/demo-irsa-abc/botocore-session-1", role: role},
+		{caller: "arn:aws:sts::123456789012:assumed-role/demo-irsa-abc/s", role: "arn:aws:iam::123456789012:role/team/demo-irsa-abc"},
+		{caller: nodeRoleSession, role: role, wantErr: "expected a session of"},
+		{caller: "arn:aws:sts::210987654321:assumed-role/demo-irsa-abc/s", role: role, wantErr: "expected a session of"},
+		{caller: "arn:aws:sts::123456789012:assumed-role/demo-irsa-abcd/s", role: role, wantErr: "expected a session of"},
+		{caller: "arn:aws:iam::123456789012:user/ci", role: role, wantErr: "expected a session of"},
+		{caller: "Unable to locate credentials", role: role, wantErr: "not an ARN"},
+	}
+	for _, tt := range tests {
+		err := assumedRoleMatches(tt.caller, tt.role)
+		if tt.wantErr == "" {
+			assert.NoError(t, err, tt.caller)
+		} else {
+			assert.ErrorContains(t, err, tt.wantErr, tt.caller)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// IRSA check. It proves IAM roles for service accounts work end to end: an
+// IAM role trusted by the cluster's OIDC provider, a ServiceAccount annotated
+// with it, and a pod under that ServiceAccount whose sts:GetCallerIdentity
+// call must come back as the role.
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func init() {
+	registerCheck(checkFunc{"irsa", func(t testing.TB, env *CheckEnv) {
+		tags := map[string]string{clusterVersionTag: env.Version}
+		for k, v := range env.Config.PipelineTags {
+			tags[k] = v
+		}
+		validateIRSA(t, env.Clients, irsaTarget{
+			UniqueID:        env.Config.UniqueID,
+			Region:          env.Config.AWSRegion,
+			OIDCProvider:    terraform.Output(t, env.Options, "oidc_provider"),
+			OIDCProviderARN: terraform.Output(t, env.Options, "oidc_provider_arn"),
+			Tags:            tags,
+		})
+	}}, false)
+}
+
+// IRSA test fixtures.
+const (
+	irsaNamespace         = "default"
+	irsaRoleAnnotation    = "eks.amazonaws.com/role-arn"
+	irsaImage             = "public.ecr.aws/aws-cli/aws-cli:2.27.0"
+	irsaPodAttempts       = 3
+	callerIdentityCommand = "aws sts get-caller-identity --query Arn --output text > /dev/termination-log"
+	webIdentityAudience   = "sts.amazonaws.com"
+	assumeRoleWebIdentity = "sts:AssumeRoleWithWebIdentity"
+)
+
+// irsaTarget is the cluster an IRSA check runs against.
+type irsaTarget struct {
+	UniqueID        string // the run's ID, kept in the role name so the leak check finds a leaked role
+	Region          string
+	OIDCProvider    string // issuer without https://, e.g. oidc.eks.us-west-1.amazonaws.com/id/ABC
+	OIDCProviderARN string
+	Tags            map[string]string // tags for the IAM role, so the leak check covers it
+}
+
+// validateIRSA creates a role and ServiceAccount, runs a pod under it and
+// asserts the pod's AWS identity is the role. The role and ServiceAccount are
+// deleted afterwards.
+func validateIRSA(t testing.TB, clients *ClusterClients, target irsaTarget) {
+	t.Helper()
+
+	suffix := strings.ToLower(random.UniqueId())
+	saName := "terratest-irsa-" + suffix
+	roleName := "irsa-" + target.UniqueID + "-" + suffix
+
+	policy, err := irsaTrustPolicy(target.OIDCProviderARN, target.OIDCProvider, irsaNamespace, saName)
+	require.NoError(t, err)
+	roleARN, err := createIRSARole(clients.IAM, roleName, policy, target.Tags)
+	require.NoError(t, err, "Failed to create IRSA role")
+	defer func() {
+		if _, err := clients.IAM.DeleteRole(&iam.DeleteRoleInput{RoleName: aws.String(roleName)}); err != nil {
+			t.Logf("Failed to delete IRSA role %s: %v", roleName, err)
+		}
+	}()
+
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        saName,
+			Namespace:   irsaNamespace,
+			Labels:      map[string]string{"app": "terratest-irsa", "test": "true"},
+			Annotations: map[string]string{irsaRoleAnnotation: roleARN},
+		},
+	}
+	_, err = clients.Kubernetes.CoreV1().ServiceAccounts(irsaNamespace).Create(context.Background(), sa, metav1.CreateOptions{})
+	require.NoError(t, err, "Failed to create IRSA ServiceAccount")
+	defer func() {
+		_ = clients.Kubernetes.CoreV1().ServiceAccounts(irsaNamespace).Delete(context.Background(), saName, metav1.DeleteOptions{})
+	}()
+
+	// A new role can take a few seconds to become assumable, so a pod whose
+	// call was denied is retried with a fresh pod.
+	callerARN, err := retry.DoWithRetryE(t, "Call sts:GetCallerIdentity from an IRSA pod", irsaPodAttempts, sharedRetryInterval, func() (string, error) {
+		return runCallerIdentityPod(t, clients.Kubernetes, saName, target.Region)
+	})
+	require.NoError(t, err, "Pod should get an AWS identity through IRSA")
+	require.NoError(t, assumedRoleMatches(callerARN, roleARN))
+}
+
+// irsaTrustPolicy returns a trust policy that lets only the given
+// ServiceAccount assume the role through the cluster's OIDC provider.
+func irsaTrustPolicy(providerARN, provider, namespace, serviceAccount string) (string, error) {
+	if providerARN == "" || provider == "" {
+		return "", fmt.Errorf("cluster has no OIDC provider (is enable_irsa false?)")
+	}
+	policy := map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Effect":    "Allow",
+			"Principal": map[string]string{"Federated": providerARN},
+			"Action":    assumeRoleWebIdentity,
+			"Condition": map[string]interface{}{
+				"StringEquals": map[string]string{
+					provider + ":sub": fmt.Sprintf("system:serviceaccount:%s:%s", namespace, serviceAccount),
+					provider + ":aud": webIdentityAudience,
+				},
+			},
+		}},
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode trust policy: %w", err)
+	}
+	return string(data), nil
+}
+
+// createIRSARole creates the role with the trust policy and tags, and returns
+// its ARN.
+func createIRSARole(iamSvc iamiface.IAMAPI, name, trustPolicy string, tags map[string]string) (string, error) {
+	input := &iam.CreateRoleInput{
+		RoleName:                 aws.String(name),
+		AssumeRolePolicyDocument: aws.String(trustPolicy),
+		Description:              aws.String("Terratest IRSA check; safe to delete"),
+	}
+	for k, v := range tags {
+		input.Tags = append(input.Tags, &iam.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	out, err := iamSvc.CreateRole(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to create role %s: %w", name, err)
+	}
+	return aws.StringValue(out.Role.Arn), nil
+}
+
+// runCallerIdentityPod runs a pod under serviceAccount that calls
+// sts:GetCallerIdentity, and returns the caller ARN it reports through its
+// termination message. The pod is deleted afterwards.
+func runCallerIdentityPod(t testing.TB, k8s kubernetes.Interface, serviceAccount, region string) (string, error) {
+	t.Helper()
+
+	podName := fmt.Sprintf("terratest-irsa-%s", strings.ToLower(random.UniqueId()))
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: irsaNamespace,
+			Labels:    map[string]string{"app": "terratest-irsa", "test": "true"},
+		},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: serviceAccount,
+			RestartPolicy:      corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    "aws-cli",
+					Image:   irsaImage,
+					Command: []string{"/bin/sh", "-c", callerIdentityCommand},
+					Env: []corev1.EnvVar{
+						{Name: "AWS_REGION", Value: region},
+						{Name: "AWS_STS_REGIONAL_ENDPOINTS", Value: "regional"},
+					},
+					TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("50m"),
+							corev1.ResourceMemory: resource.MustParse("64Mi"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return runCommandPod(t, k8s, pod)
+}
+
+// assumedRoleMatches checks that callerARN, as returned by
+// sts:GetCallerIdentity, is a session of the IAM role roleARN.
+func assumedRoleMatches(callerARN, roleARN string) error {
+	role, err := arn.Parse(roleARN)
+	if err != nil {
+		return fmt.Errorf("invalid role ARN %q: %w", roleARN, err)
+	}
+	caller, err := arn.Parse(callerARN)
+	if err != nil {
+		return fmt.Errorf("pod reported %q, not an ARN: %w", callerARN, err)
+	}
+
+	// Role paths don't appear in assumed-role ARNs, only the name.
+	roleName := role.Resource[strings.LastIndex(role.Resource, "/")+1:]
+	if caller.Service != "sts" || caller.AccountID != role.AccountID ||
+		!strings.HasPrefix(caller.Resource, "assumed-role/"+roleName+"/") {
+		return fmt.Errorf("pod identity is %s, expected a session of %s", callerARN, roleARN)
+	}
+	return nil
+}
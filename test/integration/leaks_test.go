@@ -0,0 +1,201 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apex/terratest-eks/cleanup"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// clusterVersionTag is set on every resource examples/eks creates, which tells
+// one version's resources apart from the shared VPC and the other versions.
+const clusterVersionTag = "ClusterVersion"
+
+// leakCheckRetries gives deletions that AWS finishes after Terraform returns
+// (IAM, log groups recreated by a dying control plane) time to disappear.
+const leakCheckRetries = 6
+
+// clusterTags are the tags EKS and the VPC CNI set to the cluster name on the
+// security groups and network interfaces they create without the run's tags.
+var clusterTags = []string{"aws:eks:cluster-name", "cluster.k8s.aws/name", "cluster.k8s.amazonaws.com/name"}
+
+// resourceInventory lists the AWS resources still carrying a run's tags.
+// *leakInventory is the real one; offline tests substitute a fake.
+type resourceInventory interface {
+	Find(ctx context.Context) ([]cleanup.Resource, error)
+}
+
+// networkFinder lists network interfaces and security groups by EC2 filters.
+// *cleanup.Cleaner is the real one.
+type networkFinder interface {
+	FindInterfacesAndGroups(ctx context.Context, filters []*ec2.Filter) ([]cleanup.Resource, error)
+}
+
+// leakInventory adds to the run-tagged resources the network interfaces and
+// security groups in the run's VPCs or tagged for its clusters, which EKS and
+// the VPC CNI create without the run's tags. Register VPCs and clusters as
+// they are created; subtests do so in parallel.
+type leakInventory struct {
+	tagged  resourceInventory
+	network networkFinder
+
+	mu       sync.Mutex
+	vpcs     []string
+	clusters []string
+}
+
+// newResourceInventory returns an inventory of everything tagged with the
+// run's Pipeline and RunID. Call it after the RunID is final (resume changes it).
+func newResourceInventory(t testing.TB, cfg *testConfig) *leakInventory {
+	t.Helper()
+
+	sess, err := newAWSSession(cfg.AWSRegion)
+	require.NoError(t, err, "Failed to create AWS session")
+
+	clients := cleanup.NewClients(sess, "")
+	clients.EKS = eksClientFromSession(sess, cfg.EKSEndpoint)
+	cleaner := &cleanup.Cleaner{
+		Clients: clients,
+		Filter: cleanup.Filter{
+			Pipeline: cfg.PipelineTags[cleanup.TagPipeline],
+			RunID:    cfg.PipelineTags[cleanup.TagRunID],
+		},
+		// Every role the fixtures create embeds the cluster name, and so
+		// the UniqueID; this spares a ListRoleTags per role in the account.
+		RoleNameContains: cfg.UniqueID,
+	}
+	return &leakInventory{tagged: cleaner, network: cleaner}
+}
+
+// addVPC includes the interfaces and security groups in VPC id.
+func (inv *leakInventory) addVPC(id string) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	inv.vpcs = append(inv.vpcs, id)
+}
+
+// addCluster includes the interfaces and security groups tagged for cluster name.
+func (inv *leakInventory) addCluster(name string) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	inv.clusters = append(inv.clusters, name)
+}
+
+// Find returns the run-tagged resources followed by the untagged network
+// interfaces and security groups, each once.
+func (inv *leakInventory) Find(ctx context.Context) ([]cleanup.Resource, error) {
+	inv.mu.Lock()
+	var queries [][]*ec2.Filter
+	if len(inv.vpcs) > 0 {
+		queries = append(queries, []*ec2.Filter{{Name: aws.String("vpc-id"), Values: aws.StringSlice(inv.vpcs)}})
+	}
+	if len(inv.clusters) > 0 {
+		for _, key := range clusterTags {
+			queries = append(queries, []*ec2.Filter{{Name: aws.String("tag:" + key), Values: aws.StringSlice(inv.clusters)}})
+		}
+	}
+	inv.mu.Unlock()
+
+	found, err := inv.tagged.Find(ctx)
+	errs := []error{err}
+	seen := make(map[string]bool, len(found))
+	for _, r := range found {
+		seen[r.String()] = true
+	}
+	for _, filters := range queries {
+		more, err := inv.network.FindInterfacesAndGroups(ctx, filters)
+		errs = append(errs, err)
+		for _, r := range more {
+			if !seen[r.String()] {
+				seen[r.String()] = true
+				found = append(found, r)
+			}
+		}
+	}
+	return found, errors.Join(errs...)
+}
+
+// versionResources selects the resources examples/eks created for v, as
+// cluster clusterName.
+func versionResources(v, clusterName string) func(cleanup.Resource) bool {
+	return func(r cleanup.Resource) bool {
+		return r.Tags[clusterVersionTag] == v || clusterResource(r, clusterName)
+	}
+}
+
+// clusterResource reports whether EKS or the VPC CNI tagged r for clusterName.
+func clusterResource(r cleanup.Resource, clusterName string) bool {
+	if clusterName == "" {
+		return false
+	}
+	for _, key := range clusterTags {
+		if r.Tags[key] == clusterName {
+			return true
+		}
+	}
+	return false
+}
+
+// allResources selects every resource in the inventory.
+func allResources(cleanup.Resource) bool { return true }
+
+// assertNoLeaks runs after a destroy and fails t with the ARN of every
+// resource inv still finds that selects. what names the destroyed fixture in
+// the failure message.
+func assertNoLeaks(t testing.TB, inv resourceInventory, what string, selects func(cleanup.Resource) bool) {
+	t.Helper()
+
+	var leaked []cleanup.Resource
+	var listErr error
+	_, err := retry.DoWithRetryE(t, "Check for resources leaked by "+what, leakCheckRetries, sharedRetryInterval, func() (string, error) {
+		found, err := inv.Find(context.Background())
+		listErr = err
+		leaked = leaked[:0]
+		for _, r := range found {
+			if selects(r) {
+				leaked = append(leaked, r)
+			}
+		}
+
+		if len(leaked) > 0 {
+			return "", fmt.Errorf("%d resources left after destroying %s", len(leaked), what)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to list resources: %w", err)
+		}
+		return "no leaks", nil
+	})
+	if err == nil {
+		return
+	}
+
+	if len(leaked) == 0 {
+		t.Errorf("Could not check %s for leaked resources: %v", what, listErr)
+		return
+	}
+	refs := make([]string, len(leaked))
+	for i, r := range leaked {
+		refs[i] = "  " + leakRef(r)
+	}
+	msg := fmt.Sprintf("Destroying %s leaked %d resources:\n%s", what, len(leaked), strings.Join(refs, "\n"))
+	if listErr != nil {
+		msg += fmt.Sprintf("\nSome resources could not be listed: %v", listErr)
+	}
+	t.Error(msg)
+}
+
+// leakRef identifies r by ARN, or by kind and ID where AWS returns no ARN.
+func leakRef(r cleanup.Resource) string {
+	if r.ARN != "" {
+		return r.ARN
+	}
+	return r.String()
+}
@@ -0,0 +1,143 @@
+// Offline tests for the logging check, run against the fakeaws EKS and
+// CloudWatch Logs servers.
+package test
+
+import (
+	"testing"
+
+	"github.com/apex/terratest-eks/fakeaws"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useFakeLogs points clients.Logs at a new fakeaws CloudWatch Logs server.
+func useFakeLogs(t *testing.T, clients *ClusterClients) *fakeaws.Logs {
+	t.Helper()
+
+	fake := fakeaws.NewLogs()
+	t.Cleanup(fake.Close)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(fake.URL()),
+		Credentials: credentials.NewStaticCredentials("AKIDTEST", "secret", ""),
+	})
+	require.NoError(t, err, "Failed to create AWS session")
+	clients.Logs = cloudwatchlogs.New(sess)
+	return fake
+}
+
+func TestValidateControlPlaneLogging(t *testing.T) {
+	const group = "/aws/eks/demo/cluster"
+	want := loggingExpectation{LogTypes: []string{"api", "audit", "authenticator"}, RetentionInDays: 7}
+	auditStream := fakeaws.LogStream{Name: "kube-apiserver-audit-0123456789abcdef", Messages: []string{`{"kind":"Event"}`}}
+
+	tests := []struct {
+		name     string
+		logTypes []string
+		group    *fakeaws.LogGroup
+		streams  []fakeaws.LogStream
+		wantErr  []string // substrings of the recorded failures, in order
+	}{
+		{
+			name:     "configured",
+			logTypes: []string{"authenticator", "audit", "api"},
+			group:    &fakeaws.LogGroup{Name: group, RetentionInDays: 7},
+			streams:  []fakeaws.LogStream{auditStream},
+		},
+		{
+			name:     "audit events arrive late",
+			logTypes: want.LogTypes,
+			group:    &fakeaws.LogGroup{Name: group, RetentionInDays: 7},
+			streams:  []fakeaws.LogStream{{Name: auditStream.Name, Messages: auditStream.Messages, HiddenFor: 3}},
+		},
+		{
+			name:     "extra log type",
+			logTypes: []string{"api", "audit", "authenticator", "scheduler"},
+			group:    &fakeaws.LogGroup{Name: group, RetentionInDays: 7},
+			streams:  []fakeaws.LogStream{auditStream},
+			wantErr:  []string{"Cluster should enable exactly the cluster_enabled_log_types"},
+		},
+		{
+			name:    "logging disabled",
+			group:   &fakeaws.LogGroup{Name: group, RetentionInDays: 7},
+			streams: []fakeaws.LogStream{auditStream},
+			wantErr: []string{"Cluster should enable exactly the cluster_enabled_log_types"},
+		},
+		{
+			name:     "wrong retention",
+			logTypes: want.LogTypes,
+			group:    &fakeaws.LogGroup{Name: group},
+			streams:  []fakeaws.LogStream{auditStream},
+			wantErr:  []string{"Log group " + group + " should have the configured retention"},
+		},
+		{
+			name:     "no log group",
+			logTypes: want.LogTypes,
+			wantErr:  []string{"log group " + group + " does not exist"},
+		},
+		{
+			name:     "no audit events",
+			logTypes: want.LogTypes,
+			group:    &fakeaws.LogGroup{Name: group, RetentionInDays: 7},
+			streams:  []fakeaws.LogStream{{Name: "kube-apiserver-0123456789abcdef", Messages: []string{"I0101 started"}}},
+			wantErr:  []string{"Audit events should arrive in " + group},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeEKS(t)
+			fake.AddCluster(fakeaws.Cluster{Name: "demo", Version: "1.33", LogTypes: tt.logTypes})
+			clients := newFakeClusterClients(t, fake)
+			logs := useFakeLogs(t, clients)
+			// A log group for another cluster shares the prefix.
+			logs.AddLogGroup(fakeaws.LogGroup{Name: "/aws/eks/demo/cluster-2", RetentionInDays: 7})
+			if tt.group != nil {
+				logs.AddLogGroup(*tt.group)
+				for _, s := range tt.streams {
+					require.NoError(t, logs.AddLogStream(group, s))
+				}
+			}
+
+			rt := &recordingT{TB: t}
+			validateControlPlaneLogging(rt, clients, "demo", want)
+
+			require.Len(t, rt.errors, len(tt.wantErr), "%v", rt.errors)
+			for i, want := range tt.wantErr {
+				assert.Contains(t, rt.errors[i], want)
+			}
+		})
+	}
+}
+
+func TestValidateControlPlaneLoggingWithoutAudit(t *testing.T) {
+	fake := useFakeEKS(t)
+	fake.AddCluster(fakeaws.Cluster{Name: "demo", Version: "1.33", LogTypes: []string{"api"}})
+	clients := newFakeClusterClients(t, fake)
+	logs := useFakeLogs(t, clients)
+	logs.AddLogGroup(fakeaws.LogGroup{Name: "/aws/eks/demo/cluster", RetentionInDays: 30})
+
+	validateControlPlaneLogging(t, clients, "demo", loggingExpectation{LogTypes: []string{"api"}, RetentionInDays: 30})
+	assert.Equal(t, 0, logs.Calls(fakeaws.OpFilterLogEvents), "audit events are only awaited with audit enabled")
+}
+
+func TestLoggingFromOptions(t *testing.T) {
+	assert.Equal(t, loggingExpectation{LogTypes: []string{"api", "audit", "authenticator"}, RetentionInDays: 7},
+		loggingFromOptions(&terraform.Options{}), "the variables' defaults")
+	assert.Equal(t, loggingExpectation{LogTypes: []string{"audit"}, RetentionInDays: 30},
+		loggingFromOptions(&terraform.Options{Vars: map[string]interface{}{
+			"cluster_enabled_log_types":              []string{"audit"},
+			"cloudwatch_log_group_retention_in_days": 30,
+		}}))
+	assert.Equal(t, loggingExpectation{LogTypes: []string{}, RetentionInDays: 1},
+		loggingFromOptions(&terraform.Options{Vars: map[string]interface{}{
+			"cluster_enabled_log_types":              []interface{}{},
+			"cloudwatch_log_group_retention_in_days": 1.0,
+		}}))
+}
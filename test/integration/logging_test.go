@@ -0,0 +1,171 @@
+// Logging check. The cluster must send exactly the control plane log types
+// in cluster_enabled_log_types to /aws/eks/<name>/cluster, which must keep
+// them for cloudwatch_log_group_retention_in_days. With audit enabled, audit
+// events must start arriving within the polling timeout.
+package test
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	registerCheck(awsOnlyCheck{checkFunc{"logging", func(t testing.TB, env *CheckEnv) {
+		validateControlPlaneLogging(t, env.Clients, env.ClusterName, loggingFromOptions(env.Options))
+	}}}, false)
+}
+
+// Defaults of the examples/eks logging inputs.
+var (
+	defaultLogTypes         = []string{"api", "audit", "authenticator"}
+	defaultLogRetentionDays = int64(7)
+)
+
+// auditStreamPrefix prefixes the log streams EKS writes audit events to.
+const auditStreamPrefix = "kube-apiserver-audit-"
+
+// loggingExpectation is the control plane logging examples/eks was applied
+// with.
+type loggingExpectation struct {
+	LogTypes        []string
+	RetentionInDays int64
+}
+
+// loggingFromOptions reads the logging inputs from opts, falling back to the
+// variables' defaults.
+func loggingFromOptions(opts *terraform.Options) loggingExpectation {
+	want := loggingExpectation{LogTypes: defaultLogTypes, RetentionInDays: defaultLogRetentionDays}
+	switch types := opts.Vars["cluster_enabled_log_types"].(type) {
+	case []string:
+		want.LogTypes = types
+	case []interface{}:
+		want.LogTypes = make([]string, len(types))
+		for i, v := range types {
+			want.LogTypes[i] = fmt.Sprint(v)
+		}
+	}
+	switch days := opts.Vars["cloudwatch_log_group_retention_in_days"].(type) {
+	case int:
+		want.RetentionInDays = int64(days)
+	case int64:
+		want.RetentionInDays = days
+	case float64:
+		want.RetentionInDays = int64(days)
+	}
+	return want
+}
+
+// controlPlaneLogGroup is the log group EKS sends a cluster's logs to.
+func controlPlaneLogGroup(clusterName string) string {
+	return "/aws/eks/" + clusterName + "/cluster"
+}
+
+// validateControlPlaneLogging checks the cluster's enabled log types, its
+// log group's retention and, with audit enabled, that audit events arrive.
+func validateControlPlaneLogging(t testing.TB, clients *ClusterClients, clusterName string, want loggingExpectation) {
+	t.Helper()
+
+	out, err := clients.EKS.DescribeCluster(&eks.DescribeClusterInput{Name: aws.String(clusterName)})
+	require.NoError(t, err, "Failed to describe cluster")
+	assert.Equal(t, sortedCopy(want.LogTypes), enabledLogTypes(out.Cluster.Logging),
+		"Cluster should enable exactly the cluster_enabled_log_types")
+
+	groupName := controlPlaneLogGroup(clusterName)
+	group, err := describeLogGroup(clients.Logs, groupName)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, want.RetentionInDays, aws.Int64Value(group.RetentionInDays),
+		"Log group %s should have the configured retention", groupName)
+
+	for _, lt := range want.LogTypes {
+		if lt == eks.LogTypeAudit {
+			assert.NoError(t, waitForLogEvents(t, clients.Logs, groupName, auditStreamPrefix),
+				"Audit events should arrive in %s", groupName)
+		}
+	}
+}
+
+// enabledLogTypes returns the log types a cluster has enabled, sorted.
+func enabledLogTypes(logging *eks.Logging) []string {
+	enabled := []string{}
+	if logging == nil {
+		return enabled
+	}
+	for _, setup := range logging.ClusterLogging {
+		if aws.BoolValue(setup.Enabled) {
+			enabled = append(enabled, aws.StringValueSlice(setup.Types)...)
+		}
+	}
+	sort.Strings(enabled)
+	return enabled
+}
+
+func sortedCopy(s []string) []string {
+	out := append([]string{}, s...)
+	sort.Strings(out)
+	return out
+}
+
+// describeLogGroup returns the log group named name.
+func describeLogGroup(logsSvc cloudwatchlogsiface.CloudWatchLogsAPI, name string) (*cloudwatchlogs.LogGroup, error) {
+	var found *cloudwatchlogs.LogGroup
+	err := logsSvc.DescribeLogGroupsPages(&cloudwatchlogs.DescribeLogGroupsInput{
+		LogGroupNamePrefix: aws.String(name),
+	}, func(page *cloudwatchlogs.DescribeLogGroupsOutput, _ bool) bool {
+		for _, g := range page.LogGroups {
+			if aws.StringValue(g.LogGroupName) == name {
+				found = g
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe log group %s: %w", name, err)
+	}
+	if found == nil {
+		return nil, fmt.Errorf("log group %s does not exist", name)
+	}
+	return found, nil
+}
+
+// waitForLogEvents polls until a stream in group whose name starts with
+// streamPrefix has an event, for up to sharedMaxRetries polls.
+func waitForLogEvents(t testing.TB, logsSvc cloudwatchlogsiface.CloudWatchLogsAPI, group, streamPrefix string) error {
+	t.Helper()
+
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("Wait for %s* events in %s", streamPrefix, group), sharedMaxRetries, sharedRetryInterval, func() (string, error) {
+		// A page can come back empty while the search continues, so stop
+		// at the first event rather than the first page.
+		var stream string
+		err := logsSvc.FilterLogEventsPages(&cloudwatchlogs.FilterLogEventsInput{
+			LogGroupName:        aws.String(group),
+			LogStreamNamePrefix: aws.String(streamPrefix),
+			Limit:               aws.Int64(1),
+		}, func(page *cloudwatchlogs.FilterLogEventsOutput, _ bool) bool {
+			if len(page.Events) > 0 {
+				stream = aws.StringValue(page.Events[0].LogStreamName)
+			}
+			return stream == ""
+		})
+		if err != nil {
+			return "", retry.FatalError{Underlying: fmt.Errorf("failed to filter log events in %s: %w", group, err)}
+		}
+		if stream == "" {
+			return "", fmt.Errorf("no %s* events in %s yet", streamPrefix, group)
+		}
+		return stream, nil
+	})
+	return err
+}
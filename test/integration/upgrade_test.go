@@ -0,0 +1,434 @@
+// Upgrade-path test. For each N→N+1 pair from discoverEKSVersions it deploys
+// examples/eks at N, starts a sample workload, then re-applies with
+// cluster_version N+1 and checks that the control plane, node groups and
+// addons moved to N+1 while the workload kept at least one replica available.
+//
+// It deploys its own VPC and takes over an hour, so it only runs with
+// EKS_UPGRADE_TEST=true (task test-upgrade). EKS_VERSION_STRATEGY and
+// EKS_VERSION_CONSTRAINT pick the upgrade targets as they pick matrix versions.
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apex/terratest-eks/cleanup"
+	"github.com/apex/terratest-eks/matrix"
+	"github.com/apex/terratest-eks/report"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+// upgradePathTag is added to a path's pipeline tags. Paths share versions
+// (1.31-to-1.32 ends where 1.32-to-1.33 starts), so the ClusterVersion tag can't
+// tell their resources apart for the leak check.
+const upgradePathTag = "UpgradePath"
+
+// upgradeAddons are the cluster_addons examples/eks installs.
+var upgradeAddons = []string{"coredns", "kube-proxy", "vpc-cni"}
+
+// Sample workload kept running across the upgrade. Each replica needs a node
+// of its own, so the node group has one node per replica and the roll drains
+// one replica at a time.
+const (
+	upgradeWorkloadNamespace = "default"
+	upgradeWorkloadName      = "terratest-upgrade"
+	upgradeWorkloadReplicas  = 2
+	upgradeNodeCount         = upgradeWorkloadReplicas
+)
+
+// upgradeAvailabilityInterval is how often the workload's availability is
+// sampled while the upgrade runs.
+var upgradeAvailabilityInterval = 15 * time.Second
+
+func TestEksClusterUpgradePath(t *testing.T) {
+	cfg := newTestConfig(t)
+	if os.Getenv("EKS_UPGRADE_TEST") != "true" {
+		t.Skip("Set EKS_UPGRADE_TEST=true to run upgrade-path tests (task test-upgrade)")
+	}
+
+	discovered := discoverEKSVersions(t, cfg.AWSRegion, cfg.EKSEndpoint, cfg.VersionConstraint)
+	targets, err := cfg.VersionSelector.Select(discovered)
+	require.NoError(t, err, "Failed to select EKS versions")
+	paths := matrix.UpgradePaths(discovered, targets)
+	t.Logf("Discovered EKS versions: %v | Targets: %v | Upgrade paths: %v", discovered, targets, paths)
+	if len(paths) == 0 {
+		t.Skipf("No selected version has its previous minor version available")
+	}
+
+	inventory := newResourceInventory(t, cfg)
+	vpcName := fmt.Sprintf("terratest-upgrade-vpc-%s", cfg.UniqueID)
+	runDir := filepath.Join(cfg.RunsDir, "upgrade-"+cfg.UniqueID)
+
+	rep := report.New(t.Name())
+	rep.SetProperty("run_id", cfg.PipelineTags["RunID"])
+	rep.SetProperty("region", cfg.AWSRegion)
+	defer writeReports(t, rep, cfg.ReportDir, "upgrade")
+
+	vpcOpts := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: copyFixture(t, "examples/vpc", filepath.Join(runDir, "vpc")),
+		Vars: map[string]interface{}{
+			"vpc_name":      vpcName,
+			"aws_region":    cfg.AWSRegion,
+			"environment":   "terratest",
+			"pipeline_tags": cfg.PipelineTags,
+		},
+		NoColor:     true,
+		Parallelism: 20,
+	})
+	defer func() {
+		terraform.Destroy(t, vpcOpts)
+		assertNoLeaks(t, inventory, "VPC "+vpcName, allResources)
+		if !t.Failed() {
+			_ = os.RemoveAll(runDir)
+		}
+	}()
+	terraform.InitAndApply(t, vpcOpts)
+	vpcID := terraform.Output(t, vpcOpts, "vpc_id")
+	inventory.addVPC(vpcID)
+	privateSubnets := terraform.OutputList(t, vpcOpts, "private_subnets")
+
+	// Barrier subtest, as in TestEksClusterVersionMatrix: the VPC destroy
+	// waits for every path.
+	t.Run("paths", func(t *testing.T) {
+		for _, path := range paths {
+			name := fmt.Sprintf("EKS_%s_to_%s", strings.ReplaceAll(path.From.String(), ".", "_"), strings.ReplaceAll(path.To.String(), ".", "_"))
+			t.Run(name, func(st *testing.T) {
+				st.Parallel()
+
+				rc := rep.Case(name)
+				defer rc.Finish(st)
+				t := rc.Track(st)
+
+				slug := strings.ReplaceAll(path.From.String(), ".", "-")
+				clusterName := fmt.Sprintf("test-upg-%s-%s", slug, cfg.UniqueID)
+				rc.Version, rc.ClusterName, rc.Region = path.String(), clusterName, cfg.AWSRegion
+				inventory.addCluster(clusterName)
+
+				tags := map[string]string{upgradePathTag: path.String()}
+				for k, v := range cfg.PipelineTags {
+					tags[k] = v
+				}
+				eksOpts := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+					TerraformDir: copyFixture(t, "examples/eks", filepath.Join(runDir, "eks-"+slug)),
+					Vars: map[string]interface{}{
+						"cluster_name":        clusterName,
+						"cluster_version":     path.From.String(),
+						"aws_region":          cfg.AWSRegion,
+						"vpc_id":              vpcID,
+						"private_subnet_ids":  privateSubnets,
+						"environment":         "terratest",
+						"node_instance_types": []string{"t3.small"},
+						"node_desired_size":   upgradeNodeCount,
+						"node_min_size":       upgradeNodeCount,
+						"node_max_size":       upgradeNodeCount,
+						"pipeline_tags":       tags,
+					},
+					NoColor:     true,
+					Parallelism: 20,
+				})
+
+				defer rc.Phase(t, report.PhaseDestroy, func() {
+					terraform.Destroy(t, eksOpts)
+					assertNoLeaks(t, inventory, "cluster "+clusterName, func(r cleanup.Resource) bool {
+						return r.Tags[upgradePathTag] == path.String() || clusterResource(r, clusterName)
+					})
+				})
+
+				// ── Deploy N and start the workload ──────────────────────
+				rc.Phase(t, report.PhaseInit, func() { terraform.Init(t, eksOpts) })
+				rc.Phase(t, report.PhaseApply, func() {
+					defer recordEKSResources(t, rc, eksOpts)
+					terraform.Apply(t, eksOpts)
+				})
+
+				var clients *ClusterClients
+				var workload *appsv1.Deployment
+				rc.Phase(t, report.PhaseValidate, func() {
+					out := getEKSOutputs(t, eksOpts)
+					out.validate(t, clusterName, path.From.String())
+					clients = newClusterClients(t, cfg, out)
+
+					validateClusterStatus(t, clients, clusterName, path.From.String())
+					validateNodegroupVersions(t, clients, clusterName, path.From.String())
+					before := validateAddonsCompatible(t, clients, clusterName, path.From.String(), upgradeAddons)
+					t.Logf("Addons at %s: %v", path.From, before)
+
+					var err error
+					workload, err = startUpgradeWorkload(t, clients.Kubernetes)
+					require.NoError(t, err, "Sample workload should become available before the upgrade")
+				})
+
+				// ── Bump cluster_version to N+1 in place ─────────────────
+				// The workload is sampled from the apply until every node
+				// group has rolled.
+				watch := watchAvailability(clients.Kubernetes, upgradeWorkloadNamespace, upgradeWorkloadName, upgradeAvailabilityInterval)
+				defer watch.Stop()
+				eksOpts.Vars["cluster_version"] = path.To.String()
+				rc.Phase(t, report.PhaseUpgrade, func() {
+					terraform.Apply(t, eksOpts)
+				})
+
+				rc.Phase(t, report.PhaseValidate, func() {
+					validateClusterStatus(t, clients, clusterName, path.To.String())
+					validateNodegroupVersions(t, clients, clusterName, path.To.String())
+					watch.Stop()
+					validateNoOutage(t, watch)
+					after := validateAddonsCompatible(t, clients, clusterName, path.To.String(), upgradeAddons)
+					t.Logf("Addons at %s: %v", path.To, after)
+					validateWorkloadSurvived(t, clients.Kubernetes, workload)
+				})
+			})
+		}
+	})
+}
+
+// validateNodegroupVersions waits for every managed node group to be ACTIVE
+// and asserts each runs want. Node groups roll after the control plane, so a
+// node group still updating is waited for rather than failed.
+func validateNodegroupVersions(t testing.TB, clients *ClusterClients, clusterName, want string) {
+	t.Helper()
+
+	_, err := retry.DoWithRetryE(t, "Wait for node groups to run "+want, sharedMaxRetries, sharedRetryInterval, func() (string, error) {
+		versions, err := nodegroupVersions(clients.EKS, clusterName)
+		if err != nil {
+			return "", retry.FatalError{Underlying: err}
+		}
+		var behind []string
+		for name, v := range versions {
+			if v != want {
+				behind = append(behind, fmt.Sprintf("%s=%s", name, v))
+			}
+		}
+		if len(behind) > 0 {
+			return "", fmt.Errorf("node groups not on %s yet: %s", want, strings.Join(behind, ", "))
+		}
+		return "all node groups upgraded", nil
+	})
+	require.NoError(t, err, "Node groups should run %s", want)
+	require.NoError(t, waitForNodegroupsActive(t, clients.EKS, clusterName), "Node groups should be in ACTIVE state")
+}
+
+// nodegroupVersions returns the Kubernetes version of each of the cluster's
+// managed node groups.
+func nodegroupVersions(eksSvc eksiface.EKSAPI, clusterName string) (map[string]string, error) {
+	var names []string
+	err := eksSvc.ListNodegroupsPages(&eks.ListNodegroupsInput{
+		ClusterName: aws.String(clusterName),
+	}, func(page *eks.ListNodegroupsOutput, _ bool) bool {
+		names = append(names, aws.StringValueSlice(page.Nodegroups)...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list node groups: %w", err)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("cluster %s has no managed node groups", clusterName)
+	}
+
+	versions := make(map[string]string, len(names))
+	for _, name := range names {
+		out, err := eksSvc.DescribeNodegroup(&eks.DescribeNodegroupInput{
+			ClusterName:   aws.String(clusterName),
+			NodegroupName: aws.String(name),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe node group %s: %w", name, err)
+		}
+		versions[name] = aws.StringValue(out.Nodegroup.Version)
+	}
+	return versions, nil
+}
+
+// validateAddonsCompatible waits for each addon to be ACTIVE and asserts it
+// runs a version EKS lists as compatible with clusterVersion. It returns the
+// addon versions.
+func validateAddonsCompatible(t testing.TB, clients *ClusterClients, clusterName, clusterVersion string, addons []string) map[string]string {
+	t.Helper()
+
+	versions := make(map[string]string, len(addons))
+	for _, addon := range addons {
+		available, err := describeAddonVersions(clients.EKS, addon, clusterVersion)
+		require.NoError(t, err)
+
+		a, err := waitForAddonActive(t, clients.EKS, clusterName, addon)
+		if a != nil {
+			versions[addon] = aws.StringValue(a.AddonVersion)
+		}
+		if !assert.NoError(t, err, "Addon %s should be ACTIVE", addon) {
+			continue
+		}
+		assert.True(t, available.Compatible[versions[addon]],
+			"Addon %s %s should be compatible with Kubernetes %s", addon, versions[addon], clusterVersion)
+	}
+	return versions
+}
+
+// startUpgradeWorkload creates the sample Deployment and its
+// PodDisruptionBudget and waits for it to be available. A Deployment, unlike a
+// bare pod, is rescheduled when the upgrade replaces its node; the budget
+// makes the node group roll wait for one replica to be available elsewhere
+// before draining the other.
+func startUpgradeWorkload(t testing.TB, k8s kubernetes.Interface) (*appsv1.Deployment, error) {
+	t.Helper()
+
+	if _, err := k8s.PolicyV1().PodDisruptionBudgets(upgradeWorkloadNamespace).Create(context.Background(), upgradeDisruptionBudget(), metav1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to create pod disruption budget %s: %w", upgradeWorkloadName, err)
+	}
+	if _, err := k8s.AppsV1().Deployments(upgradeWorkloadNamespace).Create(context.Background(), upgradeWorkload(), metav1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to create deployment %s: %w", upgradeWorkloadName, err)
+	}
+	return waitForDeploymentAvailable(t, k8s, upgradeWorkloadNamespace, upgradeWorkloadName)
+}
+
+// validateWorkloadSurvived asserts that the Deployment started before the
+// upgrade still exists, is the same object, and is fully available again.
+func validateWorkloadSurvived(t testing.TB, k8s kubernetes.Interface, before *appsv1.Deployment) {
+	t.Helper()
+
+	after, err := waitForDeploymentAvailable(t, k8s, before.Namespace, before.Name)
+	require.NoError(t, err, "Sample workload should be available after the upgrade")
+	assert.Equal(t, before.UID, after.UID, "Sample workload should not have been recreated")
+}
+
+// availabilityWatch samples a Deployment's available replicas in the
+// background until stopped.
+type availabilityWatch struct {
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	// Read after Stop.
+	samples int   // successful reads
+	outages int   // reads with no replica available
+	errors  int   // failed reads, e.g. while the API server is replaced
+	lastErr error // the last failed read's error
+}
+
+// watchAvailability starts sampling the Deployment every interval.
+func watchAvailability(k8s kubernetes.Interface, namespace, name string, interval time.Duration) *availabilityWatch {
+	w := &availabilityWatch{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			d, err := k8s.AppsV1().Deployments(namespace).Get(context.Background(), name, metav1.GetOptions{})
+			switch {
+			case err != nil:
+				w.errors++
+				w.lastErr = err
+			case d.Status.AvailableReplicas == 0:
+				w.samples++
+				w.outages++
+			default:
+				w.samples++
+			}
+
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return w
+}
+
+// Stop ends sampling and waits for the last sample. It may be called again.
+func (w *availabilityWatch) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+}
+
+// validateNoOutage asserts that the stopped watch read the workload and never
+// found it without an available replica.
+func validateNoOutage(t testing.TB, w *availabilityWatch) {
+	t.Helper()
+
+	if w.samples == 0 {
+		t.Errorf("Sample workload's availability could not be read during the upgrade: %v", w.lastErr)
+		return
+	}
+	assert.Zero(t, w.outages, "Sample workload should keep an available replica during the upgrade (none in %d of %d samples)", w.outages, w.samples)
+	if w.errors > 0 {
+		t.Logf("%d of %d availability samples failed during the upgrade, last: %v", w.errors, w.samples+w.errors, w.lastErr)
+	}
+}
+
+// upgradeDisruptionBudget keeps one sample replica available through drains.
+func upgradeDisruptionBudget() *policyv1.PodDisruptionBudget {
+	minAvailable := intstr.FromInt32(1)
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: upgradeWorkloadName, Namespace: upgradeWorkloadNamespace},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector:     &metav1.LabelSelector{MatchLabels: upgradeWorkloadLabels()},
+		},
+	}
+}
+
+func upgradeWorkloadLabels() map[string]string {
+	return map[string]string{"app": upgradeWorkloadName, "test": "true"}
+}
+
+// upgradeWorkload is the sample Deployment: nginx replicas on separate nodes,
+// so one node being replaced never takes down every replica.
+func upgradeWorkload() *appsv1.Deployment {
+	replicas := int32(upgradeWorkloadReplicas)
+	labels := upgradeWorkloadLabels()
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      upgradeWorkloadName,
+			Namespace: upgradeWorkloadNamespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Affinity: &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+							LabelSelector: &metav1.LabelSelector{MatchLabels: labels},
+							TopologyKey:   hostnameLabel,
+						}},
+					}},
+					Containers: []corev1.Container{
+						{
+							Name:  "nginx",
+							Image: "nginx:alpine",
+							Ports: []corev1.ContainerPort{{ContainerPort: 80}},
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("50m"),
+									corev1.ResourceMemory: resource.MustParse("32Mi"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,63 @@
+package matrix
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Fingerprint hashes the Terraform code under dirs: every file's path
+// relative to root and its contents. Provider caches and local state are
+// skipped, since running Terraform changes them without changing the code.
+// The result names the code a green record was earned with.
+func Fingerprint(root string, dirs ...string) (string, error) {
+	h := sha256.New()
+	for _, dir := range dirs {
+		err := filepath.WalkDir(filepath.Join(root, dir), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".terraform" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if generatedFile(d.Name()) {
+				return nil
+			}
+
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+			if _, err := io.Copy(h, f); err != nil {
+				return err
+			}
+			h.Write([]byte{0})
+			return nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to fingerprint %s: %w", dir, err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// generatedFile reports whether name is written by terraform runs rather
+// than part of the code.
+func generatedFile(name string) bool {
+	return strings.Contains(name, ".tfstate") || strings.HasSuffix(name, ".tfplan")
+}
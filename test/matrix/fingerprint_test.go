@@ -0,0 +1,46 @@
+package matrix
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprint(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(root, rel)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	write("modules/eks-cluster/main.tf", `resource "aws_eks_cluster" "this" {}`)
+	write("examples/eks/main.tf", `module "eks" {}`)
+	write("README.md", "not code under test")
+
+	fingerprint := func() string {
+		fp, err := Fingerprint(root, "modules", "examples")
+		require.NoError(t, err)
+		return fp
+	}
+	base := fingerprint()
+	assert.Len(t, base, 64)
+
+	write("README.md", "edited")
+	write("examples/eks/.terraform/providers/aws", "cache")
+	write("examples/eks/terraform.tfstate", "{}")
+	write("examples/eks/terraform.tfstate.d/ws/terraform.tfstate", "{}")
+	assert.Equal(t, base, fingerprint(), "files outside the dirs and terraform's own files don't count")
+
+	write("modules/eks-cluster/main.tf", `resource "aws_eks_cluster" "this" { version = "1.33" }`)
+	edited := fingerprint()
+	assert.NotEqual(t, base, edited, "an edit changes the fingerprint")
+
+	require.NoError(t, os.Rename(filepath.Join(root, "examples/eks/main.tf"), filepath.Join(root, "examples/eks/cluster.tf")))
+	assert.NotEqual(t, edited, fingerprint(), "a rename changes the fingerprint")
+
+	_, err := Fingerprint(root, "missing")
+	assert.ErrorContains(t, err, "failed to fingerprint missing")
+}
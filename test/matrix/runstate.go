@@ -0,0 +1,177 @@
+package matrix
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/apex/terratest-eks/version"
+)
+
+// Phase is the last lifecycle step a stack (the shared VPC or one version's
+// cluster) reached. Applying means an apply started, so resources may exist.
+type Phase string
+
+const (
+	PhasePending   Phase = "pending"
+	PhaseApplying  Phase = "applying"
+	PhaseApplied   Phase = "applied"
+	PhaseValidated Phase = "validated"
+	PhaseDestroyed Phase = "destroyed"
+)
+
+// VPCState records the shared VPC of a run. Pool is set when the VPC was
+// leased from a vpcpool rather than deployed from Dir; destroyed then means
+// the lease was released.
+type VPCState struct {
+	Phase          Phase    `json:"phase"`
+	Dir            string   `json:"dir,omitempty"`
+	Pool           string   `json:"pool,omitempty"`
+	VPCID          string   `json:"vpc_id,omitempty"`
+	PrivateSubnets []string `json:"private_subnets,omitempty"`
+}
+
+// VersionState records one version's cluster. Passed is set once validation
+// succeeds and stays set through destroy.
+type VersionState struct {
+	Phase       Phase     `json:"phase"`
+	Passed      bool      `json:"passed"`
+	Failed      bool      `json:"failed"`
+	ClusterName string    `json:"cluster_name,omitempty"`
+	Dir         string    `json:"dir,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// RunState is the persisted progress of a matrix run, saved after every
+// update so a run that dies part-way can be resumed or cleaned up.
+type RunState struct {
+	RunID     string                   `json:"run_id"`
+	UniqueID  string                   `json:"unique_id"`
+	StartedAt time.Time                `json:"started_at"`
+	UpdatedAt time.Time                `json:"updated_at"`
+	VPC       VPCState                 `json:"vpc"`
+	Versions  map[string]*VersionState `json:"versions"`
+
+	path string
+	mu   sync.Mutex
+	now  func() time.Time
+}
+
+// NewRunState starts a run that will test versions and saves it to path.
+func NewRunState(path, runID, uniqueID string, versions []version.KubeVersion) (*RunState, error) {
+	s := &RunState{
+		RunID:    runID,
+		UniqueID: uniqueID,
+		VPC:      VPCState{Phase: PhasePending},
+		Versions: make(map[string]*VersionState, len(versions)),
+		path:     path,
+		now:      time.Now,
+	}
+	s.StartedAt = s.now().UTC()
+	for _, v := range versions {
+		s.Versions[v.String()] = &VersionState{Phase: PhasePending}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s, s.saveLocked()
+}
+
+// LoadRunState reads the run state at path. A missing file returns nil, nil.
+func LoadRunState(path string) (*RunState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run state: %w", err)
+	}
+
+	s := &RunState{path: path, now: time.Now}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to decode run state %s: %w", path, err)
+	}
+	for raw := range s.Versions {
+		if _, err := version.Parse(raw); err != nil {
+			return nil, fmt.Errorf("run state %s: %w", path, err)
+		}
+	}
+	return s, nil
+}
+
+// UpdateVPC applies fn to the VPC state and saves.
+func (s *RunState) UpdateVPC(fn func(*VPCState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn(&s.VPC)
+	return s.saveLocked()
+}
+
+// UpdateVersion applies fn to the state of v and saves. v must be part of the run.
+func (s *RunState) UpdateVersion(v version.KubeVersion, fn func(*VersionState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vs, ok := s.Versions[v.String()]
+	if !ok {
+		return fmt.Errorf("version %s is not part of run %s", v, s.RunID)
+	}
+	fn(vs)
+	vs.UpdatedAt = s.now().UTC()
+	return s.saveLocked()
+}
+
+// Pending returns the versions that have not passed yet, oldest first.
+func (s *RunState) Pending() []version.KubeVersion {
+	return s.versionsWhere(func(vs *VersionState) bool { return !vs.Passed })
+}
+
+// Passed returns the versions that passed validation, oldest first.
+func (s *RunState) Passed() []version.KubeVersion {
+	return s.versionsWhere(func(vs *VersionState) bool { return vs.Passed })
+}
+
+// Leftovers returns versions that passed but whose cluster was never
+// destroyed, oldest first.
+func (s *RunState) Leftovers() []version.KubeVersion {
+	return s.versionsWhere(func(vs *VersionState) bool { return vs.Passed && vs.Phase != PhaseDestroyed })
+}
+
+// Complete reports whether every version passed and nothing is left deployed.
+func (s *RunState) Complete() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.VPC.Phase != PhaseDestroyed && s.VPC.Phase != PhasePending {
+		return false
+	}
+	for _, vs := range s.Versions {
+		if !vs.Passed || vs.Phase != PhaseDestroyed {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *RunState) versionsWhere(keep func(*VersionState) bool) []version.KubeVersion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []version.KubeVersion
+	for raw, vs := range s.Versions {
+		if keep(vs) {
+			out = append(out, version.MustParse(raw))
+		}
+	}
+	version.Sort(out)
+	return out
+}
+
+func (s *RunState) saveLocked() error {
+	s.UpdatedAt = s.now().UTC()
+	return writeJSON(s.path, s)
+}
@@ -0,0 +1,85 @@
+package matrix
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apex/terratest-eks/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run-state.json")
+
+	state, err := NewRunState(path, "run-1", "abc123", versions(t, "1.33", "1.31", "1.32"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.31", "1.32", "1.33"}, version.Strings(state.Pending()))
+	assert.False(t, state.Complete())
+
+	require.NoError(t, state.UpdateVPC(func(vpc *VPCState) {
+		vpc.Phase = PhaseApplied
+		vpc.Dir = "/runs/abc123/vpc"
+		vpc.VPCID = "vpc-123"
+		vpc.PrivateSubnets = []string{"subnet-a", "subnet-b"}
+	}))
+	require.NoError(t, state.UpdateVersion(version.MustParse("1.31"), func(vs *VersionState) {
+		vs.Phase = PhaseValidated
+		vs.Passed = true
+		vs.ClusterName = "test-eks-1-31-abc123"
+	}))
+	require.NoError(t, state.UpdateVersion(version.MustParse("1.32"), func(vs *VersionState) {
+		vs.Phase = PhaseDestroyed
+		vs.Failed = true
+	}))
+	assert.Error(t, state.UpdateVersion(version.MustParse("1.34"), func(*VersionState) {}))
+
+	loaded, err := LoadRunState(path)
+	require.NoError(t, err)
+	assert.Equal(t, "run-1", loaded.RunID)
+	assert.Equal(t, "abc123", loaded.UniqueID)
+	assert.Equal(t, PhaseApplied, loaded.VPC.Phase)
+	assert.Equal(t, []string{"subnet-a", "subnet-b"}, loaded.VPC.PrivateSubnets)
+	assert.Equal(t, "test-eks-1-31-abc123", loaded.Versions["1.31"].ClusterName)
+	assert.False(t, loaded.Versions["1.31"].UpdatedAt.IsZero())
+
+	assert.Equal(t, []string{"1.32", "1.33"}, version.Strings(loaded.Pending()))
+	assert.Equal(t, []string{"1.31"}, version.Strings(loaded.Passed()))
+	assert.Equal(t, []string{"1.31"}, version.Strings(loaded.Leftovers()))
+	assert.False(t, loaded.Complete())
+}
+
+func TestRunStateComplete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run-state.json")
+	state, err := NewRunState(path, "run-1", "abc123", versions(t, "1.31"))
+	require.NoError(t, err)
+
+	require.NoError(t, state.UpdateVersion(version.MustParse("1.31"), func(vs *VersionState) {
+		vs.Phase = PhaseDestroyed
+		vs.Passed = true
+	}))
+	require.NoError(t, state.UpdateVPC(func(vpc *VPCState) { vpc.Phase = PhaseApplied }))
+	assert.False(t, state.Complete(), "VPC still deployed")
+	assert.Empty(t, state.Leftovers())
+
+	require.NoError(t, state.UpdateVPC(func(vpc *VPCState) { vpc.Phase = PhaseDestroyed }))
+	assert.True(t, state.Complete())
+}
+
+func TestLoadRunState(t *testing.T) {
+	dir := t.TempDir()
+
+	state, err := LoadRunState(filepath.Join(dir, "missing.json"))
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	bad := filepath.Join(dir, "bad.json")
+	require.NoError(t, os.WriteFile(bad, []byte(`{"versions": {"v1.31": {"phase": "pending"}}}`), 0644))
+	_, err = LoadRunState(bad)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(bad, []byte(`{`), 0644))
+	_, err = LoadRunState(bad)
+	assert.Error(t, err)
+}
@@ -0,0 +1,308 @@
+// Package matrix holds the pieces of the EKS version matrix that don't need
+// AWS: version selection strategies and run bookkeeping.
+package matrix
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apex/terratest-eks/version"
+)
+
+// Strategy names accepted by NewSelector (and EKS_VERSION_STRATEGY).
+const (
+	StrategyAll          = "all"
+	StrategyLatest       = "latest"
+	StrategyOldestNewest = "oldest-newest"
+	StrategyLatestN      = "latest-n"
+	StrategyList         = "list"
+	StrategyChanged      = "changed"
+)
+
+// VersionSelector picks which of the available EKS versions a matrix run tests.
+type VersionSelector interface {
+	// Name identifies the strategy in logs.
+	Name() string
+	// Select returns the versions to test, oldest first. available is sorted
+	// oldest first and already filtered by the run's version constraint.
+	Select(available []version.KubeVersion) ([]version.KubeVersion, error)
+}
+
+// SelectorConfig configures NewSelector.
+type SelectorConfig struct {
+	Strategy string
+	// Count is the number of versions for StrategyLatestN.
+	Count int
+	// Versions is the explicit list for StrategyList.
+	Versions []version.KubeVersion
+	// LastGreenPath is the record read by StrategyChanged.
+	LastGreenPath string
+	// Fingerprint identifies the code under test for StrategyChanged (see
+	// Fingerprint). A record earned with other code doesn't count.
+	Fingerprint string
+	// Logf, if set, explains selections StrategyChanged falls back to.
+	Logf func(format string, args ...interface{})
+}
+
+// NewSelector builds the selector named by cfg.Strategy. An empty strategy means all.
+func NewSelector(cfg SelectorConfig) (VersionSelector, error) {
+	switch cfg.Strategy {
+	case "", StrategyAll:
+		return AllSelector{}, nil
+	case StrategyLatest:
+		return LatestNSelector{N: 1}, nil
+	case StrategyOldestNewest:
+		return OldestNewestSelector{}, nil
+	case StrategyLatestN:
+		if cfg.Count < 1 {
+			return nil, fmt.Errorf("strategy %s needs a count of at least 1, got %d", StrategyLatestN, cfg.Count)
+		}
+		return LatestNSelector{N: cfg.Count}, nil
+	case StrategyList:
+		if len(cfg.Versions) == 0 {
+			return nil, fmt.Errorf("strategy %s needs at least one version", StrategyList)
+		}
+		return ExplicitSelector{Versions: cfg.Versions}, nil
+	case StrategyChanged:
+		if cfg.LastGreenPath == "" {
+			return nil, fmt.Errorf("strategy %s needs a last green record path", StrategyChanged)
+		}
+		if cfg.Fingerprint == "" {
+			return nil, fmt.Errorf("strategy %s needs a fingerprint of the code under test", StrategyChanged)
+		}
+		record, err := LoadGreenRecord(cfg.LastGreenPath)
+		if err != nil {
+			return nil, err
+		}
+		return ChangedSelector{LastGreen: record, Fingerprint: cfg.Fingerprint, Logf: cfg.Logf}, nil
+	}
+	return nil, fmt.Errorf("unknown version strategy %q (want %s)", cfg.Strategy,
+		strings.Join([]string{StrategyAll, StrategyLatest, StrategyOldestNewest, StrategyLatestN, StrategyList, StrategyChanged}, ", "))
+}
+
+// SelectorConfigFromEnv reads EKS_VERSION_STRATEGY, EKS_VERSION_COUNT, and
+// EKS_VERSIONS (comma-separated) using getenv.
+func SelectorConfigFromEnv(getenv func(string) string, lastGreenPath string) (SelectorConfig, error) {
+	cfg := SelectorConfig{
+		Strategy:      getenv("EKS_VERSION_STRATEGY"),
+		LastGreenPath: lastGreenPath,
+	}
+
+	if raw := getenv("EKS_VERSION_COUNT"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid EKS_VERSION_COUNT %q: %w", raw, err)
+		}
+		cfg.Count = n
+	}
+
+	if raw := getenv("EKS_VERSIONS"); raw != "" {
+		var parts []string
+		for _, p := range strings.Split(raw, ",") {
+			parts = append(parts, strings.TrimSpace(p))
+		}
+		versions, err := version.ParseAll(parts)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid EKS_VERSIONS: %w", err)
+		}
+		cfg.Versions = versions
+	}
+
+	return cfg, nil
+}
+
+// AllSelector tests every available version.
+type AllSelector struct{}
+
+func (AllSelector) Name() string { return StrategyAll }
+
+func (AllSelector) Select(available []version.KubeVersion) ([]version.KubeVersion, error) {
+	return available, nil
+}
+
+// LatestNSelector tests the newest N available versions.
+type LatestNSelector struct {
+	N int
+}
+
+func (s LatestNSelector) Name() string {
+	if s.N == 1 {
+		return StrategyLatest
+	}
+	return fmt.Sprintf("%s(%d)", StrategyLatestN, s.N)
+}
+
+func (s LatestNSelector) Select(available []version.KubeVersion) ([]version.KubeVersion, error) {
+	return version.LatestMinors(available, s.N), nil
+}
+
+// OldestNewestSelector tests the oldest and newest available versions.
+type OldestNewestSelector struct{}
+
+func (OldestNewestSelector) Name() string { return StrategyOldestNewest }
+
+func (OldestNewestSelector) Select(available []version.KubeVersion) ([]version.KubeVersion, error) {
+	if len(available) <= 2 {
+		return available, nil
+	}
+	return []version.KubeVersion{available[0], available[len(available)-1]}, nil
+}
+
+// ExplicitSelector tests exactly the listed versions. Requesting a version
+// that isn't available is an error rather than a silent skip.
+type ExplicitSelector struct {
+	Versions []version.KubeVersion
+}
+
+func (ExplicitSelector) Name() string { return StrategyList }
+
+func (s ExplicitSelector) Select(available []version.KubeVersion) ([]version.KubeVersion, error) {
+	avail := make(map[version.KubeVersion]bool, len(available))
+	for _, v := range available {
+		avail[v] = true
+	}
+
+	var selected, missing []version.KubeVersion
+	for _, v := range s.Versions {
+		if avail[v] {
+			selected = append(selected, v)
+		} else {
+			missing = append(missing, v)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("requested versions %v are not available (available: %v)",
+			version.Strings(missing), version.Strings(available))
+	}
+
+	version.Sort(selected)
+	return selected, nil
+}
+
+// ChangedSelector tests versions that have not passed with the code under
+// test. With no record, or a record earned with other code, every version is
+// tested. When every version already passed (say, a docs-only change), the
+// newest is tested again rather than nothing: a run that tests nothing must not
+// report green.
+type ChangedSelector struct {
+	LastGreen   *GreenRecord
+	Fingerprint string
+	// Logf, if set, is told why the newest version was picked as a fallback.
+	Logf func(format string, args ...interface{})
+}
+
+func (ChangedSelector) Name() string { return StrategyChanged }
+
+func (s ChangedSelector) Select(available []version.KubeVersion) ([]version.KubeVersion, error) {
+	if s.LastGreen == nil || s.LastGreen.Fingerprint != s.Fingerprint {
+		return available, nil
+	}
+
+	green := make(map[string]bool, len(s.LastGreen.Versions))
+	for _, v := range s.LastGreen.Versions {
+		green[v] = true
+	}
+
+	var selected []version.KubeVersion
+	for _, v := range available {
+		if !green[v.String()] {
+			selected = append(selected, v)
+		}
+	}
+	if len(selected) == 0 && len(available) > 0 {
+		newest := available[len(available)-1]
+		if s.Logf != nil {
+			s.Logf("Every available version %v already passed with this code in run %s; testing only the newest, %s",
+				version.Strings(available), s.LastGreen.RunID, newest)
+		}
+		return []version.KubeVersion{newest}, nil
+	}
+	return selected, nil
+}
+
+// GreenRecord lists the versions that passed in previous runs with the code
+// named by Fingerprint.
+type GreenRecord struct {
+	Fingerprint string    `json:"fingerprint"`
+	Versions    []string  `json:"versions"`
+	RunID       string    `json:"run_id"`
+	RecordedAt  time.Time `json:"recorded_at"`
+}
+
+// LoadGreenRecord reads a green record. A missing file returns nil, nil.
+func LoadGreenRecord(path string) (*GreenRecord, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read green record: %w", err)
+	}
+
+	var record GreenRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode green record %s: %w", path, err)
+	}
+	return &record, nil
+}
+
+// RecordGreen adds passed to the green record at path, creating it if needed.
+// A record for other code is replaced, since its versions haven't passed with
+// this code.
+func RecordGreen(path, runID, fingerprint string, passed []version.KubeVersion, at time.Time) error {
+	record, err := LoadGreenRecord(path)
+	if err != nil {
+		return err
+	}
+	if record == nil || record.Fingerprint != fingerprint {
+		record = &GreenRecord{Fingerprint: fingerprint}
+	}
+
+	known := make(map[version.KubeVersion]bool)
+	for _, raw := range record.Versions {
+		if v, err := version.Parse(raw); err == nil {
+			known[v] = true
+		}
+	}
+	for _, v := range passed {
+		known[v] = true
+	}
+
+	merged := make([]version.KubeVersion, 0, len(known))
+	for v := range known {
+		merged = append(merged, v)
+	}
+	version.Sort(merged)
+
+	record.Versions = version.Strings(merged)
+	record.RunID = runID
+	record.RecordedAt = at.UTC()
+
+	return writeJSON(path, record)
+}
+
+// writeJSON writes v as indented JSON via a temp file and rename, so a crash
+// mid-write never leaves a truncated file behind.
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
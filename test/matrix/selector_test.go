@@ -0,0 +1,150 @@
+package matrix
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apex/terratest-eks/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func versions(t *testing.T, raw ...string) []version.KubeVersion {
+	t.Helper()
+	vs, err := version.ParseAll(raw)
+	require.NoError(t, err)
+	return vs
+}
+
+func TestSelectors(t *testing.T) {
+	available := versions(t, "1.31", "1.32", "1.33", "1.34")
+
+	tests := []struct {
+		name     string
+		cfg      SelectorConfig
+		wantName string
+		want     []string
+	}{
+		{"default is all", SelectorConfig{}, "all", []string{"1.31", "1.32", "1.33", "1.34"}},
+		{"all", SelectorConfig{Strategy: "all"}, "all", []string{"1.31", "1.32", "1.33", "1.34"}},
+		{"latest", SelectorConfig{Strategy: "latest"}, "latest", []string{"1.34"}},
+		{"oldest-newest", SelectorConfig{Strategy: "oldest-newest"}, "oldest-newest", []string{"1.31", "1.34"}},
+		{"latest-n", SelectorConfig{Strategy: "latest-n", Count: 2}, "latest-n(2)", []string{"1.33", "1.34"}},
+		{"list", SelectorConfig{Strategy: "list", Versions: versions(t, "1.33", "1.31")}, "list", []string{"1.31", "1.33"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel, err := NewSelector(tt.cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantName, sel.Name())
+
+			got, err := sel.Select(available)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, version.Strings(got))
+		})
+	}
+}
+
+func TestOldestNewestWithFewVersions(t *testing.T) {
+	got, err := OldestNewestSelector{}.Select(versions(t, "1.33"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.33"}, version.Strings(got))
+}
+
+func TestExplicitSelectorRejectsUnavailable(t *testing.T) {
+	_, err := ExplicitSelector{Versions: versions(t, "1.31", "1.40")}.Select(versions(t, "1.31", "1.32"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "[1.40]")
+}
+
+func TestNewSelectorErrors(t *testing.T) {
+	for name, cfg := range map[string]SelectorConfig{
+		"unknown":              {Strategy: "random"},
+		"latest-n no count":    {Strategy: "latest-n"},
+		"list without values":  {Strategy: "list"},
+		"changed without path": {Strategy: "changed"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewSelector(cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestChangedSelector(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "last-green-versions.json")
+	available := versions(t, "1.31", "1.32", "1.33")
+	var logged []string
+	selectWith := func(fingerprint string) ([]version.KubeVersion, error) {
+		logf := func(format string, args ...interface{}) { logged = append(logged, fmt.Sprintf(format, args...)) }
+		sel, err := NewSelector(SelectorConfig{Strategy: "changed", LastGreenPath: path, Fingerprint: fingerprint, Logf: logf})
+		require.NoError(t, err)
+		return sel.Select(available)
+	}
+
+	got, err := selectWith("code-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.31", "1.32", "1.33"}, version.Strings(got), "no record runs everything")
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, RecordGreen(path, "run-1", "code-1", versions(t, "1.32", "1.31"), at))
+	require.NoError(t, RecordGreen(path, "run-2", "code-1", versions(t, "1.31"), at))
+
+	record, err := LoadGreenRecord(path)
+	require.NoError(t, err)
+	assert.Equal(t, "code-1", record.Fingerprint)
+	assert.Equal(t, []string{"1.31", "1.32"}, record.Versions)
+	assert.Equal(t, "run-2", record.RunID)
+	assert.Equal(t, at, record.RecordedAt)
+
+	got, err = selectWith("code-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.33"}, version.Strings(got))
+
+	got, err = selectWith("code-2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.31", "1.32", "1.33"}, version.Strings(got), "changed code runs everything again")
+
+	require.NoError(t, RecordGreen(path, "run-3", "code-1", versions(t, "1.33"), at))
+	assert.Empty(t, logged)
+	got, err = selectWith("code-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.33"}, version.Strings(got), "everything green falls back to the newest")
+	require.Len(t, logged, 1)
+	assert.Contains(t, logged[0], "already passed with this code in run run-3")
+
+	require.NoError(t, RecordGreen(path, "run-4", "code-2", versions(t, "1.33"), at))
+	record, err = LoadGreenRecord(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.33"}, record.Versions, "a record for other code is replaced")
+
+	_, err = NewSelector(SelectorConfig{Strategy: "changed", LastGreenPath: path})
+	assert.ErrorContains(t, err, "needs a fingerprint")
+}
+
+func TestSelectorConfigFromEnv(t *testing.T) {
+	env := map[string]string{
+		"EKS_VERSION_STRATEGY": "list",
+		"EKS_VERSION_COUNT":    "3",
+		"EKS_VERSIONS":         "1.32, 1.34",
+	}
+
+	cfg, err := SelectorConfigFromEnv(func(k string) string { return env[k] }, "green.json")
+	require.NoError(t, err)
+	assert.Equal(t, "list", cfg.Strategy)
+	assert.Equal(t, 3, cfg.Count)
+	assert.Equal(t, []string{"1.32", "1.34"}, version.Strings(cfg.Versions))
+	assert.Equal(t, "green.json", cfg.LastGreenPath)
+
+	env["EKS_VERSIONS"] = "1.32,latest"
+	_, err = SelectorConfigFromEnv(func(k string) string { return env[k] }, "")
+	assert.Error(t, err)
+
+	env["EKS_VERSIONS"] = ""
+	env["EKS_VERSION_COUNT"] = "two"
+	_, err = SelectorConfigFromEnv(func(k string) string { return env[k] }, "")
+	assert.Error(t, err)
+}
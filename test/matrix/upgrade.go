@@ -0,0 +1,42 @@
+package matrix
+
+import (
+	"github.com/apex/terratest-eks/version"
+)
+
+// UpgradePath is an in-place cluster_version bump. EKS upgrades one minor
+// version at a time, so To is always From.NextMinor().
+type UpgradePath struct {
+	From version.KubeVersion
+	To   version.KubeVersion
+}
+
+// String returns e.g. "1.32-to-1.33", which is also a valid AWS tag value.
+func (p UpgradePath) String() string {
+	return p.From.String() + "-to-" + p.To.String()
+}
+
+// UpgradePaths returns the upgrades into each target whose previous minor
+// version is also available, oldest first. Pass the selector's output as
+// targets to test upgrades into the versions the matrix would test.
+func UpgradePaths(available, targets []version.KubeVersion) []UpgradePath {
+	have := make(map[version.KubeVersion]bool, len(available))
+	for _, v := range available {
+		have[v] = true
+	}
+
+	sorted := append([]version.KubeVersion(nil), targets...)
+	version.Sort(sorted)
+
+	var paths []UpgradePath
+	for _, to := range sorted {
+		if to.Minor == 0 {
+			continue
+		}
+		from := version.KubeVersion{Major: to.Major, Minor: to.Minor - 1}
+		if have[from] && have[to] {
+			paths = append(paths, UpgradePath{From: from, To: to})
+		}
+	}
+	return paths
+}
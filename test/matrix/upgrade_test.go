@@ -0,0 +1,34 @@
+package matrix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpgradePaths(t *testing.T) {
+	available := versions(t, "1.31", "1.32", "1.33", "1.35")
+
+	tests := []struct {
+		name    string
+		targets []string
+		want    []string
+	}{
+		{"every version", []string{"1.31", "1.32", "1.33", "1.35"}, []string{"1.31-to-1.32", "1.32-to-1.33"}},
+		{"latest has no predecessor", []string{"1.35"}, nil},
+		{"unsorted targets", []string{"1.33", "1.32"}, []string{"1.31-to-1.32", "1.32-to-1.33"}},
+		{"target not available", []string{"1.34"}, nil},
+		{"no targets", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range UpgradePaths(available, versions(t, tt.targets...)) {
+				assert.Equal(t, p.From.NextMinor(), p.To)
+				got = append(got, p.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package planjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Assertion helpers in the style of testify's assert package: each reports
+// a failure through t and returns whether it passed. Pass a *testing.T, or a
+// require-style wrapper that calls FailNow.
+
+type tHelper interface {
+	Helper()
+}
+
+func helper(t assert.TestingT) {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+}
+
+// AssertResourceExists asserts that d has a resource at addr.
+func AssertResourceExists(t assert.TestingT, d *Document, addr string, msgAndArgs ...interface{}) bool {
+	helper(t)
+	if d.Resource(addr) != nil {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("No resource %s. Resources of that type:\n%s",
+		addr, addresses(d.ResourcesOfType(typeOf(addr)))), msgAndArgs...)
+}
+
+// AssertResourceCount asserts that d has n resources of type typ.
+func AssertResourceCount(t assert.TestingT, d *Document, typ string, n int, msgAndArgs ...interface{}) bool {
+	helper(t)
+	found := d.ResourcesOfType(typ)
+	if len(found) == n {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("Expected %d %s, found %d:\n%s", n, typ, len(found), addresses(found)), msgAndArgs...)
+}
+
+// AssertAttr asserts that the attribute at path of the resource at addr
+// equals want, compared as JSON (see Value.Equal).
+func AssertAttr(t assert.TestingT, d *Document, addr, path string, want interface{}, msgAndArgs ...interface{}) bool {
+	helper(t)
+	v, ok := attr(t, d, addr, path, msgAndArgs...)
+	if !ok {
+		return false
+	}
+	norm, err := normalise(want)
+	if err != nil {
+		return assert.Fail(t, err.Error(), msgAndArgs...)
+	}
+	if !v.Equal(want) {
+		return assert.Fail(t, fmt.Sprintf("%s %s:\nexpected: %s\nactual  : %s", addr, path, toJSON(norm), toJSON(v.Raw())), msgAndArgs...)
+	}
+	return true
+}
+
+// AssertAttrContains asserts that the list, set or string attribute at path
+// contains elem.
+func AssertAttrContains(t assert.TestingT, d *Document, addr, path string, elem interface{}, msgAndArgs ...interface{}) bool {
+	helper(t)
+	v, ok := attr(t, d, addr, path, msgAndArgs...)
+	if !ok {
+		return false
+	}
+	if !contains(v, elem) {
+		return assert.Fail(t, fmt.Sprintf("%s %s = %s does not contain %s", addr, path, toJSON(v.Raw()), toJSON(elem)), msgAndArgs...)
+	}
+	return true
+}
+
+// AssertAttrNotContains asserts that the list, set or string attribute at
+// path does not contain elem. An absent attribute fails; a null one passes.
+func AssertAttrNotContains(t assert.TestingT, d *Document, addr, path string, elem interface{}, msgAndArgs ...interface{}) bool {
+	helper(t)
+	v, ok := attr(t, d, addr, path, msgAndArgs...)
+	if !ok {
+		return false
+	}
+	if contains(v, elem) {
+		return assert.Fail(t, fmt.Sprintf("%s %s = %s should not contain %s", addr, path, toJSON(v.Raw()), toJSON(elem)), msgAndArgs...)
+	}
+	return true
+}
+
+// AssertAction asserts that the planned change to addr is want.
+func AssertAction(t assert.TestingT, d *Document, addr string, want Action, msgAndArgs ...interface{}) bool {
+	helper(t)
+	c := d.Change(addr)
+	if c == nil {
+		return assert.Fail(t, fmt.Sprintf("No planned change to %s", addr), msgAndArgs...)
+	}
+	if got := c.Action(); got != want {
+		return assert.Fail(t, fmt.Sprintf("%s: expected %s, planned %s:\n%s", addr, want, got, diffLines(c)), msgAndArgs...)
+	}
+	return true
+}
+
+// AssertNoDestroy asserts that the plan deletes or replaces nothing, the
+// usual bar for an in-place upgrade.
+func AssertNoDestroy(t assert.TestingT, d *Document, msgAndArgs ...interface{}) bool {
+	helper(t)
+	var destroyed []string
+	for _, c := range d.Changes() {
+		if c.Destroys() {
+			destroyed = append(destroyed, fmt.Sprintf("  %s (%s)", c.Address, c.Action()))
+		}
+	}
+	if len(destroyed) == 0 {
+		return true
+	}
+	return assert.Fail(t, "Plan destroys resources:\n"+strings.Join(destroyed, "\n"), msgAndArgs...)
+}
+
+// attr fails t if addr doesn't exist or has nothing at path.
+func attr(t assert.TestingT, d *Document, addr, path string, msgAndArgs ...interface{}) (Value, bool) {
+	helper(t)
+	r := d.Resource(addr)
+	if r == nil {
+		return Value{}, AssertResourceExists(t, d, addr, msgAndArgs...)
+	}
+	v := r.Attr(path)
+	switch {
+	case v.Unknown():
+		return v, assert.Fail(t, fmt.Sprintf("%s %s is only known after apply", addr, path), msgAndArgs...)
+	case !v.Exists():
+		return v, assert.Fail(t, fmt.Sprintf("%s has no attribute %s", addr, path), msgAndArgs...)
+	}
+	return v, true
+}
+
+func contains(v Value, elem interface{}) bool {
+	if s, ok := v.Raw().(string); ok {
+		sub, ok := elem.(string)
+		return ok && strings.Contains(s, sub)
+	}
+	l, _ := v.Raw().([]interface{})
+	for i := range l {
+		if v.Index(i).Equal(elem) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the resource type in addr, after any module steps.
+func typeOf(addr string) string {
+	for strings.HasPrefix(addr, "module.") {
+		rest := strings.TrimPrefix(addr, "module.")
+		i := strings.IndexAny(rest, ".[")
+		if i < 0 {
+			return ""
+		}
+		if rest[i] == '[' {
+			end := strings.Index(rest[i:], "].")
+			if end < 0 {
+				return ""
+			}
+			i += end + 1
+		}
+		addr = rest[i+1:]
+	}
+	typ, _, _ := strings.Cut(strings.TrimPrefix(addr, "data."), ".")
+	return typ
+}
+
+func addresses(rs []*Resource) string {
+	if len(rs) == 0 {
+		return "  (none)"
+	}
+	lines := make([]string, len(rs))
+	for i, r := range rs {
+		lines[i] = "  " + r.Address
+	}
+	return strings.Join(lines, "\n")
+}
+
+func diffLines(c *Change) string {
+	diffs := c.Diff()
+	if len(diffs) == 0 {
+		return "  (no attribute changes)"
+	}
+	lines := make([]string, len(diffs))
+	for i, d := range diffs {
+		lines[i] = "  " + d.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func toJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%#v", v)
+	}
+	return string(data)
+}
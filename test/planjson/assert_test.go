@@ -0,0 +1,155 @@
+package planjson
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingT collects failures instead of failing the test.
+type recordingT struct {
+	failures []string
+}
+
+// Errorf records testify's failure report with its continuation-line
+// indentation removed, so tests can match multi-line messages.
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	t.failures = append(t.failures, strings.ReplaceAll(msg, "\n\t            \t", "\n"))
+}
+
+func TestAssertions(t *testing.T) {
+	create := load(t, "eks_create.plan.json")
+	upgrade := load(t, "eks_upgrade.plan.json")
+
+	tests := []struct {
+		name    string
+		check   func(t assert.TestingT) bool
+		failure string // substring of the failure; "" means it passes
+	}{
+		{
+			name:  "resource exists",
+			check: func(t assert.TestingT) bool { return AssertResourceExists(t, create, kmsKeyAddr) },
+		},
+		{
+			name: "resource missing lists others of its type",
+			check: func(t assert.TestingT) bool {
+				return AssertResourceExists(t, create, `module.eks.aws_eks_node_group.this["default"]`)
+			},
+			failure: "No resource module.eks.aws_eks_node_group.this[\"default\"]. Resources of that type:\n  " + nodegroupAddr,
+		},
+		{
+			name:  "count",
+			check: func(t assert.TestingT) bool { return AssertResourceCount(t, create, "aws_eks_addon", 3) },
+		},
+		{
+			name:    "wrong count",
+			check:   func(t assert.TestingT) bool { return AssertResourceCount(t, create, "aws_kms_key", 0) },
+			failure: "Expected 0 aws_kms_key, found 1:\n  " + kmsKeyAddr,
+		},
+		{
+			name: "attr",
+			check: func(t assert.TestingT) bool {
+				return AssertAttr(t, create, nodegroupAddr, "scaling_config.0", map[string]int{"desired_size": 1, "max_size": 2, "min_size": 1})
+			},
+		},
+		{
+			name: "attr differs",
+			check: func(t assert.TestingT) bool {
+				return AssertAttr(t, create, clusterAddr, "enabled_cluster_log_types", []string{"audit"})
+			},
+			failure: "expected: [\"audit\"]\nactual  : [\"api\",\"audit\",\"authenticator\"]",
+		},
+		{
+			name:    "attr unknown",
+			check:   func(t assert.TestingT) bool { return AssertAttr(t, create, clusterAddr, "endpoint", "") },
+			failure: "endpoint is only known after apply",
+		},
+		{
+			name: "attr missing",
+			check: func(t assert.TestingT) bool {
+				return AssertAttr(t, create, clusterAddr, "kubernetes_network_config", nil)
+			},
+			failure: "has no attribute kubernetes_network_config",
+		},
+		{
+			name: "contains",
+			check: func(t assert.TestingT) bool {
+				return AssertAttrContains(t, create, clusterAddr, "enabled_cluster_log_types", "audit")
+			},
+		},
+		{
+			name: "string contains",
+			check: func(t assert.TestingT) bool {
+				return AssertAttrContains(t, create, clusterAddr, "name", "terratest-eks")
+			},
+		},
+		{
+			name: "not contains",
+			check: func(t assert.TestingT) bool {
+				return AssertAttrNotContains(t, create, clusterAddr, "vpc_config.0.public_access_cidrs", "0.0.0.0/0")
+			},
+			failure: `vpc_config.0.public_access_cidrs = ["0.0.0.0/0"] should not contain "0.0.0.0/0"`,
+		},
+		{
+			name:  "action",
+			check: func(t assert.TestingT) bool { return AssertAction(t, upgrade, clusterAddr, ActionUpdate) },
+		},
+		{
+			name:    "wrong action shows the diff",
+			check:   func(t assert.TestingT) bool { return AssertAction(t, upgrade, corednsAddr, ActionNoOp) },
+			failure: "expected no-op, planned update:\n  addon_version: \"v1.11.4-eksbuild.2\" => \"v1.12.1-eksbuild.2\"",
+		},
+		{
+			name:  "no destroy",
+			check: func(t assert.TestingT) bool { return AssertNoDestroy(t, upgrade) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &recordingT{}
+			ok := tt.check(rt)
+			if tt.failure == "" {
+				assert.True(t, ok)
+				assert.Empty(t, rt.failures)
+				return
+			}
+			assert.False(t, ok)
+			if assert.Len(t, rt.failures, 1) {
+				assert.Contains(t, rt.failures[0], tt.failure)
+			}
+		})
+	}
+}
+
+func TestAssertNoDestroyFails(t *testing.T) {
+	d, err := Parse([]byte(`{
+		"format_version": "1.2",
+		"resource_changes": [
+			{"address": "aws_eks_cluster.this", "change": {"actions": ["delete", "create"]}},
+			{"address": "aws_iam_role.this", "change": {"actions": ["update"]}}
+		]
+	}`))
+	assert.NoError(t, err)
+
+	rt := &recordingT{}
+	assert.False(t, AssertNoDestroy(rt, d))
+	if assert.Len(t, rt.failures, 1) {
+		assert.Contains(t, rt.failures[0], "Plan destroys resources:\n  aws_eks_cluster.this (replace)")
+	}
+}
+
+func TestTypeOf(t *testing.T) {
+	for addr, want := range map[string]string{
+		"aws_vpc.this":                    "aws_vpc",
+		"data.aws_caller_identity.this":   "aws_caller_identity",
+		clusterAddr:                       "aws_eks_cluster",
+		nodegroupAddr:                     "aws_eks_node_group",
+		`module.a["x.y"].aws_s3_bucket.b`: "aws_s3_bucket",
+	} {
+		assert.Equal(t, want, typeOf(addr), addr)
+	}
+}
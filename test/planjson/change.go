@@ -0,0 +1,169 @@
+package planjson
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Action summarises a change's actions.
+type Action string
+
+const (
+	ActionNoOp    Action = "no-op"
+	ActionCreate  Action = "create"
+	ActionRead    Action = "read"
+	ActionUpdate  Action = "update"
+	ActionDelete  Action = "delete"
+	ActionReplace Action = "replace"
+)
+
+// Change is one resource's planned change.
+type Change struct {
+	Address string
+	Module  string
+	Mode    string
+	Type    string
+	Name    string
+	// Actions is Terraform's raw list; a replace is ["delete", "create"] or
+	// ["create", "delete"].
+	Actions []string
+
+	Before       map[string]interface{}
+	After        map[string]interface{}
+	AfterUnknown map[string]interface{}
+}
+
+// Action returns the change's single action.
+func (c *Change) Action() Action {
+	if len(c.Actions) == 2 {
+		return ActionReplace
+	}
+	if len(c.Actions) == 1 {
+		return Action(c.Actions[0])
+	}
+	return ActionNoOp
+}
+
+// Destroys reports whether the change deletes the existing object, on its
+// own or as part of a replace.
+func (c *Change) Destroys() bool {
+	a := c.Action()
+	return a == ActionDelete || a == ActionReplace
+}
+
+// BeforeAttr returns the attribute at path before the change.
+func (c *Change) BeforeAttr(path string) Value {
+	return Value{v: c.Before, found: true}.Attr(path)
+}
+
+// AfterAttr returns the attribute at path after the change.
+func (c *Change) AfterAttr(path string) Value {
+	return Value{v: c.After, unknown: c.AfterUnknown, found: true}.Attr(path)
+}
+
+// AttrDiff is one attribute the change alters.
+type AttrDiff struct {
+	Path   string
+	Before interface{}
+	After  interface{}
+	// Unknown means After will only be known after apply.
+	Unknown bool
+}
+
+func (d AttrDiff) String() string {
+	after := fmt.Sprintf("%#v", d.After)
+	if d.Unknown {
+		after = "(known after apply)"
+	}
+	return fmt.Sprintf("%s: %#v => %s", d.Path, d.Before, after)
+}
+
+// Diff returns the leaf attributes that differ between Before and After,
+// ordered by path. Nested objects and lists are walked, so a version bump
+// shows up as "version", not as the whole object.
+func (c *Change) Diff() []AttrDiff {
+	var diffs []AttrDiff
+	diff("", object(c.Before), object(c.After), object(c.AfterUnknown), &diffs)
+	return diffs
+}
+
+func diff(path string, before, after, unknown interface{}, out *[]AttrDiff) {
+	if u, ok := unknown.(bool); ok && u {
+		*out = append(*out, AttrDiff{Path: path, Before: before, Unknown: true})
+		return
+	}
+
+	// Walk into an object or list that appears or disappears, so its leaves
+	// are diffed one by one.
+	before, after = emptyLike(before, after, unknown), emptyLike(after, before, unknown)
+
+	switch b := before.(type) {
+	case map[string]interface{}:
+		if a, ok := after.(map[string]interface{}); ok {
+			um, _ := unknown.(map[string]interface{})
+			keys := make(map[string]bool)
+			for k := range b {
+				keys[k] = true
+			}
+			for k := range a {
+				keys[k] = true
+			}
+			for k := range um {
+				keys[k] = true
+			}
+			for _, k := range sortedKeys(keys) {
+				diff(join(path, k), b[k], a[k], um[k], out)
+			}
+			return
+		}
+	case []interface{}:
+		if a, ok := after.([]interface{}); ok {
+			ul, _ := unknown.([]interface{})
+			for i := 0; i < max(len(a), len(b), len(ul)); i++ {
+				diff(join(path, strconv.Itoa(i)), at(b, i), at(a, i), at(ul, i), out)
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*out = append(*out, AttrDiff{Path: path, Before: before, After: after})
+	}
+}
+
+// object boxes m so that a nil map is a nil interface{}.
+func object(m map[string]interface{}) interface{} {
+	if m == nil {
+		return nil
+	}
+	return m
+}
+
+// emptyLike returns an empty object or list in place of a nil v when one of
+// others is a non-empty one.
+func emptyLike(v interface{}, others ...interface{}) interface{} {
+	if v != nil {
+		return v
+	}
+	for _, other := range others {
+		switch o := other.(type) {
+		case map[string]interface{}:
+			if len(o) > 0 {
+				return map[string]interface{}{}
+			}
+		case []interface{}:
+			if len(o) > 0 {
+				return []interface{}{}
+			}
+		}
+	}
+	return v
+}
+
+func at(l []interface{}, i int) interface{} {
+	if i < len(l) {
+		return l[i]
+	}
+	return nil
+}
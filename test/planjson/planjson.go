@@ -0,0 +1,223 @@
+// Package planjson loads the JSON that `terraform show -json` prints for a
+// plan file or a state, and answers resource-level questions about it:
+// which resources of a type exist, what an attribute is planned to be, and
+// what a change does to each attribute.
+//
+// Addresses are Terraform's full resource addresses, for example
+// module.eks.aws_eks_node_group.this["default"]. Attribute paths are
+// dot-separated, with list indices as numbers: vpc_config.0.subnet_ids.
+package planjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Document is a parsed plan or state.
+type Document struct {
+	FormatVersion    string
+	TerraformVersion string
+
+	resources map[string]*Resource
+	changes   map[string]*Change
+}
+
+// Load reads and parses a plan or state JSON file.
+func Load(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses the output of `terraform show -json`. A plan's resources are
+// its planned values; a state's are its current values.
+func Parse(data []byte) (*Document, error) {
+	var raw rawDocument
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse plan JSON: %w", err)
+	}
+	if raw.FormatVersion == "" {
+		return nil, fmt.Errorf("not terraform show -json output: no format_version")
+	}
+
+	d := &Document{
+		FormatVersion:    raw.FormatVersion,
+		TerraformVersion: raw.TerraformVersion,
+		resources:        make(map[string]*Resource),
+		changes:          make(map[string]*Change),
+	}
+
+	for _, rc := range raw.ResourceChanges {
+		d.changes[rc.Address] = &Change{
+			Address:      rc.Address,
+			Module:       rc.ModuleAddress,
+			Mode:         rc.Mode,
+			Type:         rc.Type,
+			Name:         rc.Name,
+			Actions:      rc.Change.Actions,
+			Before:       asMap(rc.Change.Before),
+			After:        asMap(rc.Change.After),
+			AfterUnknown: asMap(rc.Change.AfterUnknown),
+		}
+	}
+
+	root := raw.PlannedValues.RootModule
+	if root == nil {
+		root = raw.Values.RootModule
+	}
+	d.addModule(root)
+
+	return d, nil
+}
+
+func (d *Document) addModule(m *rawModule) {
+	if m == nil {
+		return
+	}
+	for _, r := range m.Resources {
+		res := &Resource{
+			Address: r.Address,
+			Module:  m.Address,
+			Mode:    r.Mode,
+			Type:    r.Type,
+			Name:    r.Name,
+			Index:   r.Index,
+			values:  r.Values,
+		}
+		if c := d.changes[r.Address]; c != nil {
+			res.unknown = c.AfterUnknown
+		}
+		d.resources[r.Address] = res
+	}
+	for _, child := range m.ChildModules {
+		d.addModule(child)
+	}
+}
+
+// Resources returns every resource, ordered by address.
+func (d *Document) Resources() []*Resource {
+	return d.filter(func(*Resource) bool { return true })
+}
+
+// ResourcesOfType returns the resources of type typ, such as
+// "aws_eks_cluster", ordered by address.
+func (d *Document) ResourcesOfType(typ string) []*Resource {
+	return d.filter(func(r *Resource) bool { return r.Type == typ })
+}
+
+// Resource returns the resource at addr, or nil if there is none.
+func (d *Document) Resource(addr string) *Resource {
+	return d.resources[addr]
+}
+
+// Changes returns the plan's resource changes, including no-ops, ordered by
+// address. A state has none.
+func (d *Document) Changes() []*Change {
+	changes := make([]*Change, 0, len(d.changes))
+	for _, addr := range sortedKeys(d.changes) {
+		changes = append(changes, d.changes[addr])
+	}
+	return changes
+}
+
+// Change returns the planned change to addr, or nil if there is none.
+func (d *Document) Change(addr string) *Change {
+	return d.changes[addr]
+}
+
+func (d *Document) filter(keep func(*Resource) bool) []*Resource {
+	var out []*Resource
+	for _, addr := range sortedKeys(d.resources) {
+		if r := d.resources[addr]; keep(r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Resource is one resource instance's planned or current values.
+type Resource struct {
+	Address string
+	// Module is the containing module's address, "" for the root module.
+	Module string
+	Mode   string
+	Type   string
+	Name   string
+	// Index is nil, a count index (float64), or a for_each key (string).
+	Index interface{}
+
+	values  map[string]interface{}
+	unknown map[string]interface{}
+}
+
+// Attr returns the attribute at path. Attributes only known after apply are
+// missing from a plan's values; their Value reports Unknown.
+func (r *Resource) Attr(path string) Value {
+	return Value{v: r.values, unknown: r.unknown, found: true}.Attr(path)
+}
+
+// Values returns the resource's attributes as decoded from JSON.
+func (r *Resource) Values() map[string]interface{} {
+	return r.values
+}
+
+func (r *Resource) String() string {
+	return r.Address
+}
+
+type rawDocument struct {
+	FormatVersion    string `json:"format_version"`
+	TerraformVersion string `json:"terraform_version"`
+	PlannedValues    struct {
+		RootModule *rawModule `json:"root_module"`
+	} `json:"planned_values"`
+	Values struct {
+		RootModule *rawModule `json:"root_module"`
+	} `json:"values"`
+	ResourceChanges []struct {
+		Address       string `json:"address"`
+		ModuleAddress string `json:"module_address"`
+		Mode          string `json:"mode"`
+		Type          string `json:"type"`
+		Name          string `json:"name"`
+		Change        struct {
+			Actions      []string    `json:"actions"`
+			Before       interface{} `json:"before"`
+			After        interface{} `json:"after"`
+			AfterUnknown interface{} `json:"after_unknown"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+type rawModule struct {
+	Address   string `json:"address"`
+	Resources []struct {
+		Address string                 `json:"address"`
+		Mode    string                 `json:"mode"`
+		Type    string                 `json:"type"`
+		Name    string                 `json:"name"`
+		Index   interface{}            `json:"index"`
+		Values  map[string]interface{} `json:"values"`
+	} `json:"resources"`
+	ChildModules []*rawModule `json:"child_modules"`
+}
+
+// asMap returns v if it is a JSON object; before is null for a create and
+// after_unknown is false when nothing is unknown.
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
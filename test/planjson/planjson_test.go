@@ -0,0 +1,159 @@
+package planjson
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Addresses in the fixtures, which follow examples/eks.
+const (
+	clusterAddr   = "module.eks.module.eks.aws_eks_cluster.this[0]"
+	nodegroupAddr = `module.eks.module.eks.module.eks_managed_node_group["default"].aws_eks_node_group.this[0]`
+	kmsKeyAddr    = "module.eks.module.eks.module.kms.aws_kms_key.this[0]"
+	corednsAddr   = `module.eks.module.eks.aws_eks_addon.this["coredns"]`
+)
+
+func load(t *testing.T, name string) *Document {
+	t.Helper()
+	d, err := Load(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return d
+}
+
+func TestResourcesOfType(t *testing.T) {
+	d := load(t, "eks_create.plan.json")
+
+	assert.Equal(t, "1.6.6", d.TerraformVersion)
+	assert.Len(t, d.Resources(), 7)
+	require.Len(t, d.ResourcesOfType("aws_eks_cluster"), 1)
+	assert.Equal(t, clusterAddr, d.ResourcesOfType("aws_eks_cluster")[0].Address)
+	assert.Empty(t, d.ResourcesOfType("aws_eks_fargate_profile"))
+
+	addons := d.ResourcesOfType("aws_eks_addon")
+	require.Len(t, addons, 3)
+	assert.Equal(t, "coredns", addons[0].Index)
+	assert.Equal(t, "module.eks.module.eks", addons[0].Module)
+}
+
+func TestResourceAttr(t *testing.T) {
+	d := load(t, "eks_create.plan.json")
+
+	ng := d.Resource(nodegroupAddr)
+	require.NotNil(t, ng)
+	assert.Equal(t, `module.eks.module.eks.module.eks_managed_node_group["default"]`, ng.Module)
+	assert.Equal(t, 1, ng.Attr("scaling_config").Len())
+	assert.Equal(t, 2, ng.Attr("scaling_config").Index(0).Attr("max_size").Int())
+	assert.Equal(t, 1, ng.Attr("scaling_config.0.desired_size").Int())
+	assert.True(t, ng.Attr("scaling_config").Equal([]map[string]int{{"desired_size": 1, "max_size": 2, "min_size": 1}}))
+	assert.Equal(t, "1.32", ng.Attr("version").String())
+	assert.Equal(t, []string{"t3.small"}, ng.Attr("instance_types").Strings())
+	assert.Equal(t, "default", ng.Attr("labels").StringMap()["NodeGroup"])
+
+	cluster := d.Resource(clusterAddr)
+	assert.Equal(t, []string{"0.0.0.0/0"}, cluster.Attr("vpc_config.0.public_access_cidrs").Strings())
+	assert.True(t, cluster.Attr("vpc_config.0.endpoint_private_access").Bool())
+	assert.Equal(t, "1.32", cluster.Attr("tags").Key("ClusterVersion").String())
+	assert.Equal(t, "vpc_config.0.subnet_ids", cluster.Attr("vpc_config.0.subnet_ids").Path())
+	assert.Equal(t, []string{"subnet-0123456789abcdef0", "subnet-0fedcba9876543210"}, cluster.Attr("vpc_config.0.subnet_ids").Strings(),
+		"sets come back sorted")
+
+	for _, path := range []string{"arn", "endpoint", "encryption_config.0.provider.0.key_arn"} {
+		v := cluster.Attr(path)
+		assert.True(t, v.Unknown(), path)
+		assert.False(t, v.Exists(), path)
+	}
+	assert.False(t, cluster.Attr("version").Unknown())
+
+	assert.False(t, cluster.Attr("no_such_attr").Exists())
+	assert.False(t, cluster.Attr("vpc_config.3.subnet_ids").Exists())
+	assert.True(t, d.Resource(kmsKeyAddr).Attr("deletion_window_in_days").Exists(), "null is a value")
+	assert.Nil(t, d.Resource(`module.eks.aws_eks_node_group.this["default"]`))
+}
+
+func TestLoadState(t *testing.T) {
+	d := load(t, "eks.state.json")
+
+	assert.Len(t, d.Resources(), 7)
+	assert.Empty(t, d.Changes())
+	cluster := d.Resource(clusterAddr)
+	require.NotNil(t, cluster)
+	assert.Equal(t, "arn:aws:eks:us-west-1:123456789012:cluster/terratest-eks-a1b2c3", cluster.Attr("arn").String())
+	assert.False(t, cluster.Attr("arn").Unknown())
+}
+
+func TestChangeDiff(t *testing.T) {
+	d := load(t, "eks_upgrade.plan.json")
+
+	cluster := d.Change(clusterAddr)
+	require.NotNil(t, cluster)
+	assert.Equal(t, ActionUpdate, cluster.Action())
+	assert.False(t, cluster.Destroys())
+	assert.Equal(t, []AttrDiff{
+		{Path: "tags.ClusterVersion", Before: "1.32", After: "1.33"},
+		{Path: "version", Before: "1.32", After: "1.33"},
+	}, cluster.Diff())
+	assert.Equal(t, "1.32", cluster.BeforeAttr("version").String())
+	assert.Equal(t, "1.33", cluster.AfterAttr("version").String())
+
+	coredns := d.Change(corednsAddr)
+	assert.Equal(t, []AttrDiff{{Path: "addon_version", Before: "v1.11.4-eksbuild.2", After: "v1.12.1-eksbuild.2"}}, coredns.Diff())
+	assert.Equal(t, `addon_version: "v1.11.4-eksbuild.2" => "v1.12.1-eksbuild.2"`, coredns.Diff()[0].String())
+
+	kms := d.Change(kmsKeyAddr)
+	assert.Equal(t, ActionNoOp, kms.Action())
+	assert.Empty(t, kms.Diff())
+
+	assert.Len(t, d.Changes(), 7)
+	assert.Nil(t, d.Change("aws_vpc.this"))
+}
+
+func TestChangeDiffOfCreate(t *testing.T) {
+	d := load(t, "eks_create.plan.json")
+
+	diffs := d.Change(nodegroupAddr).Diff()
+	assert.Contains(t, diffs, AttrDiff{Path: "scaling_config.0.desired_size", After: float64(1)})
+	assert.Contains(t, diffs, AttrDiff{Path: "arn", Unknown: true})
+	assert.Equal(t, "arn: <nil> => (known after apply)", AttrDiff{Path: "arn", Unknown: true}.String())
+
+	diffs = d.Change(clusterAddr).Diff()
+	assert.Contains(t, diffs, AttrDiff{Path: "encryption_config.0.provider.0.key_arn", Unknown: true})
+	assert.Contains(t, diffs, AttrDiff{Path: "encryption_config.0.resources.0", After: "secrets"})
+}
+
+func TestChangeActions(t *testing.T) {
+	tests := []struct {
+		actions  []string
+		want     Action
+		destroys bool
+	}{
+		{[]string{"no-op"}, ActionNoOp, false},
+		{[]string{"create"}, ActionCreate, false},
+		{[]string{"read"}, ActionRead, false},
+		{[]string{"update"}, ActionUpdate, false},
+		{[]string{"delete"}, ActionDelete, true},
+		{[]string{"delete", "create"}, ActionReplace, true},
+		{[]string{"create", "delete"}, ActionReplace, true},
+	}
+	for _, tt := range tests {
+		c := &Change{Actions: tt.actions}
+		assert.Equal(t, tt.want, c.Action(), "%v", tt.actions)
+		assert.Equal(t, tt.destroys, c.Destroys(), "%v", tt.actions)
+	}
+
+	deleted := &Change{Actions: []string{"delete"}, Before: map[string]interface{}{"name": "demo"}}
+	assert.Equal(t, []AttrDiff{{Path: "name", Before: "demo"}}, deleted.Diff())
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse([]byte("Error: No configuration files"))
+	assert.ErrorContains(t, err, "failed to parse plan JSON")
+
+	_, err = Parse([]byte(`{"resource_changes": []}`))
+	assert.ErrorContains(t, err, "no format_version")
+
+	_, err = Load(filepath.Join("testdata", "missing.json"))
+	assert.Error(t, err)
+}
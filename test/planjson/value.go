@@ -0,0 +1,165 @@
+package planjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Value is an attribute looked up by path. Looking up a missing path is not
+// an error; the Value reports !Exists and its accessors return zero values.
+type Value struct {
+	path    string
+	v       interface{}
+	unknown interface{}
+	found   bool
+}
+
+// Attr looks up a dot-separated path below v.
+func (v Value) Attr(path string) Value {
+	for _, step := range strings.Split(path, ".") {
+		if i, err := strconv.Atoi(step); err == nil {
+			v = v.Index(i)
+		} else {
+			v = v.Key(step)
+		}
+	}
+	return v
+}
+
+// Key looks up one object key, taken literally, so it works for keys with
+// dots such as tag names.
+func (v Value) Key(key string) Value {
+	next := Value{path: join(v.path, key)}
+	if m, ok := v.v.(map[string]interface{}); ok {
+		next.v, next.found = m[key]
+	}
+	if m, ok := v.unknown.(map[string]interface{}); ok {
+		next.unknown = m[key]
+	}
+	return next
+}
+
+// Index looks up one list element.
+func (v Value) Index(i int) Value {
+	next := Value{path: join(v.path, strconv.Itoa(i))}
+	if l, ok := v.v.([]interface{}); ok && i >= 0 && i < len(l) {
+		next.v, next.found = l[i], true
+	}
+	if l, ok := v.unknown.([]interface{}); ok && i >= 0 && i < len(l) {
+		next.unknown = l[i]
+	}
+	return next
+}
+
+// Path is the path the value was looked up by.
+func (v Value) Path() string {
+	return v.path
+}
+
+// Exists reports whether the path has a value, which may be null.
+func (v Value) Exists() bool {
+	return v.found
+}
+
+// Unknown reports whether the value will only be known after apply.
+func (v Value) Unknown() bool {
+	b, _ := v.unknown.(bool)
+	return b
+}
+
+// Raw returns the value as decoded from JSON: nil, bool, float64, string,
+// []interface{} or map[string]interface{}.
+func (v Value) Raw() interface{} {
+	return v.v
+}
+
+// String returns a string value, or "" for any other type.
+func (v Value) String() string {
+	s, _ := v.v.(string)
+	return s
+}
+
+// Bool returns a bool value, or false for any other type.
+func (v Value) Bool() bool {
+	b, _ := v.v.(bool)
+	return b
+}
+
+// Int returns a number value truncated to an int, or 0 for any other type.
+func (v Value) Int() int {
+	f, _ := v.v.(float64)
+	return int(f)
+}
+
+// Len returns the length of a list or object value, or 0 for any other type.
+func (v Value) Len() int {
+	switch t := v.v.(type) {
+	case []interface{}:
+		return len(t)
+	case map[string]interface{}:
+		return len(t)
+	}
+	return 0
+}
+
+// Strings returns the string elements of a list or set value, sorted, since
+// Terraform sets have no order.
+func (v Value) Strings() []string {
+	l, _ := v.v.([]interface{})
+	var out []string
+	for _, item := range l {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// StringMap returns the string entries of an object value, such as tags.
+func (v Value) StringMap() map[string]string {
+	m, _ := v.v.(map[string]interface{})
+	out := make(map[string]string, len(m))
+	for k, item := range m {
+		if s, ok := item.(string); ok {
+			out[k] = s
+		}
+	}
+	return out
+}
+
+// Equal reports whether the value equals want once want is normalised
+// through JSON, so Go ints, []string and structs compare as Terraform
+// numbers, lists and objects.
+func (v Value) Equal(want interface{}) bool {
+	norm, err := normalise(want)
+	if err != nil {
+		return false
+	}
+	return v.found && jsonEqual(v.v, norm)
+}
+
+func normalise(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalise %#v: %w", v, err)
+	}
+	var out interface{}
+	return out, json.Unmarshal(data, &out)
+}
+
+func jsonEqual(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+func join(path, step string) string {
+	if path == "" {
+		return step
+	}
+	return path + "." + step
+}
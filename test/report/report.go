@@ -0,0 +1,471 @@
+// Package report records structured results of an integration suite — per
+// case phase timings, failures, and the AWS resources created — and writes
+// them as a JSON summary and a JUnit XML file for CI to upload and trend.
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Status is the outcome of a case.
+type Status string
+
+const (
+	StatusPassed  Status = "passed"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped"
+)
+
+// Phase names used by the matrix and upgrade tests. Any name is accepted.
+const (
+	PhaseInit     = "init"
+	PhaseApply    = "apply"
+	PhaseUpgrade  = "upgrade"
+	PhaseValidate = "validate"
+	PhaseDestroy  = "destroy"
+)
+
+// PhaseTiming is one timed step of a case.
+type PhaseTiming struct {
+	Name     string        `json:"name"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration_ns"`
+	Failed   bool          `json:"failed"`
+}
+
+// CheckResult is the outcome of one named post-deploy check of a case.
+// Properties are findings the check reports without failing, such as a
+// version other than the expected one.
+type CheckResult struct {
+	Name       string            `json:"name"`
+	Status     Status            `json:"status"`
+	Duration   time.Duration     `json:"duration_ns"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// Case is the result of one subtest.
+type Case struct {
+	Name        string            `json:"name"`
+	Version     string            `json:"version,omitempty"`
+	ClusterName string            `json:"cluster_name,omitempty"`
+	Region      string            `json:"region,omitempty"`
+	Status      Status            `json:"status"`
+	Duration    time.Duration     `json:"duration_ns"`
+	Phases      []PhaseTiming     `json:"phases"`
+	Checks      []CheckResult     `json:"checks,omitempty"`
+	Failures    []string          `json:"failures,omitempty"`
+	Resources   map[string]string `json:"resources,omitempty"`
+	CostUSD     float64           `json:"estimated_cost_usd,omitempty"`
+
+	start      time.Time
+	mu         *sync.Mutex
+	now        func() time.Time
+	checkProps map[string]map[string]string // by check, until the check ends
+}
+
+// Reporter collects cases for one suite. It is safe for concurrent use by
+// parallel subtests.
+type Reporter struct {
+	Suite      string            `json:"suite"`
+	StartedAt  time.Time         `json:"started_at"`
+	Duration   time.Duration     `json:"duration_ns"`
+	Properties map[string]string `json:"properties,omitempty"`
+	Cases      []*Case           `json:"cases"`
+
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// New starts a report for suite.
+func New(suite string) *Reporter {
+	return newWithClock(suite, time.Now)
+}
+
+func newWithClock(suite string, now func() time.Time) *Reporter {
+	return &Reporter{
+		Suite:      suite,
+		StartedAt:  now().UTC(),
+		Properties: make(map[string]string),
+		now:        now,
+	}
+}
+
+// SetProperty records a suite-level value such as the run ID or VPC ID.
+func (r *Reporter) SetProperty(key, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Properties[key] = value
+}
+
+// Case starts a case. Its status is passed until Finish says otherwise.
+func (r *Reporter) Case(name string) *Case {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := &Case{
+		Name:      name,
+		Status:    StatusPassed,
+		Resources: make(map[string]string),
+		start:     r.now(),
+		mu:        &r.mu,
+		now:       r.now,
+	}
+	r.Cases = append(r.Cases, c)
+	return c
+}
+
+// SetResource records the ID of an AWS resource the case created, keyed by kind
+// (e.g. "cluster_arn").
+func (c *Case) SetResource(kind, id string) {
+	if id == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Resources[kind] = id
+}
+
+// SetCost records the estimated cost of the case's resources.
+func (c *Case) SetCost(usd float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.CostUSD = usd
+}
+
+// Fail records a failure message.
+func (c *Case) Fail(msg string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Failures = append(c.Failures, strings.TrimSpace(msg))
+}
+
+// Phase times fn as the named phase. The phase is marked failed if t becomes
+// failed while fn runs, including when fn stops the test with FailNow.
+func (c *Case) Phase(t testing.TB, name string, fn func()) {
+	t.Helper()
+
+	failedBefore := t.Failed()
+	start := c.now()
+	defer func() {
+		timing := PhaseTiming{
+			Name:     name,
+			Start:    start.UTC(),
+			Duration: c.now().Sub(start),
+			Failed:   t.Failed() && !failedBefore,
+		}
+		c.mu.Lock()
+		c.Phases = append(c.Phases, timing)
+		c.mu.Unlock()
+	}()
+
+	fn()
+}
+
+// Check times fn as the named check and records its outcome from t, which
+// should be a subtest of its own so the outcome is the check's alone. Like
+// Phase, it records the result even when fn stops the test with FailNow or
+// Skip.
+func (c *Case) Check(t testing.TB, name string, fn func()) {
+	t.Helper()
+
+	start := c.now()
+	defer func() {
+		result := CheckResult{Name: name, Status: StatusPassed, Duration: c.now().Sub(start)}
+		switch {
+		case t.Failed():
+			result.Status = StatusFailed
+		case t.Skipped():
+			result.Status = StatusSkipped
+		}
+		c.mu.Lock()
+		result.Properties = c.checkProps[name]
+		delete(c.checkProps, name)
+		c.Checks = append(c.Checks, result)
+		c.mu.Unlock()
+	}()
+
+	fn()
+}
+
+// SetCheckProperty records a finding of the named check while it runs; Check
+// adds it to the check's result.
+func (c *Case) SetCheckProperty(check, key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checkProps == nil {
+		c.checkProps = make(map[string]map[string]string)
+	}
+	if c.checkProps[check] == nil {
+		c.checkProps[check] = make(map[string]string)
+	}
+	c.checkProps[check][key] = value
+}
+
+// Finish records the case outcome from t. Call it deferred, before any other
+// defers of the subtest, so it runs last.
+func (c *Case) Finish(t testing.TB) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Duration = c.now().Sub(c.start)
+	switch {
+	case t.Failed():
+		c.Status = StatusFailed
+	case t.Skipped():
+		c.Status = StatusSkipped
+	default:
+		c.Status = StatusPassed
+	}
+}
+
+// Track wraps t so that assertion and fatal messages reported through it are
+// also recorded on c. Pass the result to helpers that take testing.TB.
+func (c *Case) Track(t testing.TB) *T {
+	return &T{TB: t, c: c}
+}
+
+// T is a testing.TB that records failure messages on its Case.
+type T struct {
+	testing.TB
+	c *Case
+}
+
+func (t *T) Error(args ...interface{}) {
+	t.TB.Helper()
+	t.c.Fail(fmt.Sprint(args...))
+	t.TB.Error(args...)
+}
+
+func (t *T) Errorf(format string, args ...interface{}) {
+	t.TB.Helper()
+	t.c.Fail(fmt.Sprintf(format, args...))
+	t.TB.Errorf(format, args...)
+}
+
+func (t *T) Fatal(args ...interface{}) {
+	t.TB.Helper()
+	t.c.Fail(fmt.Sprint(args...))
+	t.TB.Fatal(args...)
+}
+
+func (t *T) Fatalf(format string, args ...interface{}) {
+	t.TB.Helper()
+	t.c.Fail(fmt.Sprintf(format, args...))
+	t.TB.Fatalf(format, args...)
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Reporter) WriteJSON(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Duration = r.now().Sub(r.StartedAt)
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	Classname  string          `xml:"classname,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Failure    *junitFailure   `xml:"failure,omitempty"`
+	Skipped    *struct{}       `xml:"skipped,omitempty"`
+	SystemOut  string          `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML. Case details that JUnit has no
+// field for (cluster, region, resources, phase timings) go in properties.
+func (r *Reporter) WriteJUnit(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	suite := junitTestSuite{
+		Name:       r.Suite,
+		Tests:      len(r.Cases),
+		Time:       seconds(r.now().Sub(r.StartedAt)),
+		Timestamp:  r.StartedAt.Format(time.RFC3339),
+		Properties: properties(r.Properties),
+	}
+
+	for _, c := range r.Cases {
+		props := map[string]string{
+			"version":      c.Version,
+			"cluster_name": c.ClusterName,
+			"region":       c.Region,
+		}
+		for kind, id := range c.Resources {
+			props["resource."+kind] = id
+		}
+		if c.CostUSD > 0 {
+			props["estimated_cost_usd"] = fmt.Sprintf("%.4f", c.CostUSD)
+		}
+
+		var out strings.Builder
+		for _, p := range c.Phases {
+			props["phase."+p.Name+".seconds"] = seconds(p.Duration)
+			status := "ok"
+			if p.Failed {
+				status = "FAILED"
+			}
+			fmt.Fprintf(&out, "%-9s %8ss  %s\n", p.Name, seconds(p.Duration), status)
+		}
+		for _, ch := range c.Checks {
+			props["check."+ch.Name] = string(ch.Status)
+			for k, v := range ch.Properties {
+				props["check."+ch.Name+"."+k] = v
+			}
+		}
+
+		tc := junitTestCase{
+			Name:       c.Name,
+			Classname:  r.Suite,
+			Time:       seconds(c.Duration),
+			Properties: properties(props),
+			SystemOut:  out.String(),
+		}
+
+		switch c.Status {
+		case StatusFailed:
+			suite.Failures++
+			tc.Failure = &junitFailure{
+				Message: failureMessage(c),
+				Type:    "failure",
+				Text:    strings.Join(c.Failures, "\n\n"),
+			}
+		case StatusSkipped:
+			suite.Skipped++
+			tc.Skipped = &struct{}{}
+		}
+
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	data, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, append([]byte(xml.Header), data...))
+}
+
+// failureMessage summarizes a failed case in one line: the failing phase and
+// the first line of the first recorded failure.
+func failureMessage(c *Case) string {
+	var msg string
+	for _, p := range c.Phases {
+		if p.Failed {
+			msg = "failed during " + p.Name
+			break
+		}
+	}
+	for _, ch := range c.Checks {
+		if ch.Status == StatusFailed {
+			if msg == "" {
+				msg = "failed"
+			}
+			msg += " (check " + ch.Name + ")"
+			break
+		}
+	}
+	if len(c.Failures) > 0 {
+		first := summarize(c.Failures[0])
+		if msg == "" {
+			return first
+		}
+		return msg + ": " + first
+	}
+	if msg == "" {
+		return "failed"
+	}
+	return msg
+}
+
+// summarize reduces a failure message to one line. testify messages span
+// several labelled lines; their "Messages" and "Error" lines are kept.
+func summarize(msg string) string {
+	var errLine, messages string
+	lines := strings.Split(msg, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if v, ok := strings.CutPrefix(line, "Error:"); ok && errLine == "" {
+			errLine = strings.TrimSpace(v)
+			// "Received unexpected error:" continues on the next line.
+			if strings.HasSuffix(errLine, ":") && i+1 < len(lines) {
+				errLine += " " + strings.TrimSpace(lines[i+1])
+			}
+		}
+		if v, ok := strings.CutPrefix(line, "Messages:"); ok {
+			messages = strings.TrimSpace(v)
+		}
+	}
+
+	switch {
+	case errLine == "":
+		return strings.TrimSpace(strings.SplitN(strings.TrimSpace(msg), "\n", 2)[0])
+	case messages == "":
+		return errLine
+	default:
+		return messages + ": " + errLine
+	}
+}
+
+func properties(m map[string]string) []junitProperty {
+	var props []junitProperty
+	for name, value := range m {
+		if value != "" {
+			props = append(props, junitProperty{Name: name, Value: value})
+		}
+	}
+	sort.Slice(props, func(i, j int) bool { return props[i].Name < props[j].Name })
+	return props
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,193 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock advances by step on every reading.
+func fakeClock(step time.Duration) func() time.Time {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+// stubT is a testing.TB whose failure state the test controls.
+type stubT struct {
+	testing.TB
+	failed  bool
+	skipped bool
+}
+
+func (s *stubT) Helper()                       {}
+func (s *stubT) Failed() bool                  { return s.failed }
+func (s *stubT) Skipped() bool                 { return s.skipped }
+func (s *stubT) Errorf(string, ...interface{}) { s.failed = true }
+func (s *stubT) Fatalf(string, ...interface{}) { s.failed = true }
+
+func sampleReport() *Reporter {
+	r := newWithClock("TestEksClusterVersionMatrix", fakeClock(time.Second))
+	r.SetProperty("run_id", "run-1")
+	r.SetProperty("vpc_id", "vpc-123")
+
+	passed := r.Case("EKS_1_33")
+	passed.Version, passed.ClusterName, passed.Region = "1.33", "test-eks-1-33-abc", "us-west-1"
+	ok := &stubT{}
+	passed.Phase(ok, PhaseInit, func() {})
+	passed.Phase(ok, PhaseApply, func() {})
+	passed.SetResource("cluster_arn", "arn:aws:eks:us-west-1:123456789012:cluster/test-eks-1-33-abc")
+	passed.SetResource("node_security_group_id", "")
+	passed.SetCost(0.0421)
+	passed.Finish(ok)
+
+	failed := r.Case("EKS_1_34")
+	failed.Version = "1.34"
+	bad := &stubT{}
+	failed.Phase(bad, PhaseApply, func() {})
+	failed.Phase(bad, PhaseValidate, func() {
+		failed.Check(&stubT{}, "endpoint", func() {})
+		failed.Check(&stubT{}, "addons", func() {
+			failed.SetCheckProperty("addons", "non_default_versions", "vpc-cni v1.19.2-eksbuild.1 (default v1.19.0-eksbuild.1)")
+		})
+		failed.Check(&stubT{skipped: true}, "irsa", func() {})
+		failed.Check(bad, "status", func() {
+			failed.Fail("\n\tError Trace:\thelpers_test.go:120\n\tError:      \tReceived unexpected error:\n\t            \tcluster demo is FAILED\n\tMessages:   \tCluster should be in ACTIVE state\n")
+			bad.failed = true
+		})
+	})
+	failed.Phase(bad, PhaseDestroy, func() {})
+	failed.Finish(bad)
+
+	skipped := r.Case("EKS_1_35")
+	skipped.Finish(&stubT{skipped: true})
+
+	return r
+}
+
+func TestPhaseTimings(t *testing.T) {
+	r := sampleReport()
+	c := r.Cases[1]
+
+	require.Len(t, c.Phases, 3)
+	assert.Equal(t, []string{PhaseApply, PhaseValidate, PhaseDestroy}, []string{c.Phases[0].Name, c.Phases[1].Name, c.Phases[2].Name})
+	assert.Equal(t, time.Second, c.Phases[0].Duration)
+	assert.Equal(t, []bool{false, true, false}, []bool{c.Phases[0].Failed, c.Phases[1].Failed, c.Phases[2].Failed},
+		"only the phase during which the test started failing is marked failed")
+	assert.Equal(t, StatusFailed, c.Status)
+	assert.Equal(t, StatusPassed, r.Cases[0].Status)
+	assert.Equal(t, StatusSkipped, r.Cases[2].Status)
+	assert.NotContains(t, r.Cases[0].Resources, "node_security_group_id", "empty IDs are not recorded")
+}
+
+func TestCheckResults(t *testing.T) {
+	c := sampleReport().Cases[1]
+
+	assert.Equal(t, []CheckResult{
+		{Name: "endpoint", Status: StatusPassed, Duration: time.Second},
+		{Name: "addons", Status: StatusPassed, Duration: time.Second,
+			Properties: map[string]string{"non_default_versions": "vpc-cni v1.19.2-eksbuild.1 (default v1.19.0-eksbuild.1)"}},
+		{Name: "irsa", Status: StatusSkipped, Duration: time.Second},
+		{Name: "status", Status: StatusFailed, Duration: time.Second},
+	}, c.Checks)
+	assert.Empty(t, sampleReport().Cases[0].Checks)
+}
+
+func TestTrackRecordsFailures(t *testing.T) {
+	c := New("suite").Case("case")
+	stub := &stubT{}
+	tracked := c.Track(stub)
+
+	tracked.Errorf("expected %d nodes, got %d", 2, 1)
+	tracked.Fatalf("  apply failed  ")
+
+	assert.True(t, stub.failed, "failures still reach the wrapped test")
+	assert.Equal(t, []string{"expected 2 nodes, got 1", "apply failed"}, c.Failures)
+}
+
+func TestWriteJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports", "matrix.json")
+	require.NoError(t, sampleReport().WriteJSON(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var got struct {
+		Suite      string            `json:"suite"`
+		Properties map[string]string `json:"properties"`
+		Cases      []struct {
+			Name        string            `json:"name"`
+			ClusterName string            `json:"cluster_name"`
+			Status      string            `json:"status"`
+			Failures    []string          `json:"failures"`
+			Resources   map[string]string `json:"resources"`
+			Phases      []struct {
+				Name     string `json:"name"`
+				Duration int64  `json:"duration_ns"`
+			} `json:"phases"`
+		} `json:"cases"`
+	}
+	require.NoError(t, json.Unmarshal(data, &got))
+
+	assert.Equal(t, "TestEksClusterVersionMatrix", got.Suite)
+	assert.Equal(t, "vpc-123", got.Properties["vpc_id"])
+	require.Len(t, got.Cases, 3)
+	assert.Equal(t, "test-eks-1-33-abc", got.Cases[0].ClusterName)
+	assert.Equal(t, "passed", got.Cases[0].Status)
+	assert.Equal(t, int64(time.Second), got.Cases[0].Phases[0].Duration)
+	assert.Contains(t, got.Cases[0].Resources["cluster_arn"], "cluster/test-eks-1-33-abc")
+	assert.Equal(t, "failed", got.Cases[1].Status)
+	require.Len(t, got.Cases[1].Failures, 1)
+	assert.Contains(t, got.Cases[1].Failures[0], "cluster demo is FAILED")
+}
+
+func TestWriteJUnit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junit.xml")
+	require.NoError(t, sampleReport().WriteJUnit(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var got junitTestSuites
+	require.NoError(t, xml.Unmarshal(data, &got))
+	require.Len(t, got.Suites, 1)
+
+	suite := got.Suites[0]
+	assert.Equal(t, "TestEksClusterVersionMatrix", suite.Name)
+	assert.Equal(t, 3, suite.Tests)
+	assert.Equal(t, 1, suite.Failures)
+	assert.Equal(t, 1, suite.Skipped)
+	assert.Contains(t, suite.Properties, junitProperty{Name: "run_id", Value: "run-1"})
+
+	require.Len(t, suite.Cases, 3)
+	passed := suite.Cases[0]
+	assert.Equal(t, "EKS_1_33", passed.Name)
+	assert.Nil(t, passed.Failure)
+	assert.Contains(t, passed.Properties, junitProperty{Name: "cluster_name", Value: "test-eks-1-33-abc"})
+	assert.Contains(t, passed.Properties, junitProperty{Name: "phase.apply.seconds", Value: "1.000"})
+	assert.Contains(t, passed.Properties, junitProperty{Name: "estimated_cost_usd", Value: "0.0421"})
+	assert.Contains(t, passed.Properties, junitProperty{Name: "resource.cluster_arn", Value: "arn:aws:eks:us-west-1:123456789012:cluster/test-eks-1-33-abc"})
+
+	failed := suite.Cases[1]
+	require.NotNil(t, failed.Failure)
+	assert.Equal(t, "failed during validate (check status): Cluster should be in ACTIVE state: Received unexpected error: cluster demo is FAILED", failed.Failure.Message)
+	assert.Contains(t, failed.Properties, junitProperty{Name: "check.irsa", Value: "skipped"})
+	assert.Contains(t, failed.Properties, junitProperty{Name: "check.addons.non_default_versions", Value: "vpc-cni v1.19.2-eksbuild.1 (default v1.19.0-eksbuild.1)"})
+	assert.Contains(t, failed.Failure.Text, "cluster demo is FAILED")
+	assert.Contains(t, failed.SystemOut, "validate")
+
+	assert.NotNil(t, suite.Cases[2].Skipped)
+}
+
+func TestSummarize(t *testing.T) {
+	assert.Equal(t, "plain message", summarize("plain message\nmore detail"))
+	assert.Equal(t, "Should be true", summarize("\n\tError Trace:\tx.go:1\n\tError:      \tShould be true\n"))
+}
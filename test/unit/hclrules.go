@@ -0,0 +1,262 @@
+package unit
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// RuleKind identifies the shape of a condition extracted from a validation block.
+type RuleKind string
+
+const (
+	RuleRegex RuleKind = "regex"
+	RuleRange RuleKind = "range"
+)
+
+// Bound is one side of a numeric range condition, e.g. {">=", 1}.
+type Bound struct {
+	Op    string
+	Value *big.Float
+}
+
+// VariableRule is a condition extracted from a variable's validation block
+// in a Terraform module.
+type VariableRule struct {
+	Variable     string
+	Kind         RuleKind
+	Pattern      *regexp.Regexp
+	Bounds       []Bound
+	ErrorMessage string
+	Range        hcl.Range
+}
+
+// Allows reports whether Terraform would accept value for this rule.
+// Range rules reject values that don't parse as a number, as Terraform's
+// type conversion would.
+func (r VariableRule) Allows(value string) bool {
+	switch r.Kind {
+	case RuleRegex:
+		return r.Pattern.MatchString(value)
+	case RuleRange:
+		n, ok := new(big.Float).SetString(strings.TrimSpace(value))
+		if !ok {
+			return false
+		}
+		for _, b := range r.Bounds {
+			cmp := n.Cmp(b.Value)
+			switch b.Op {
+			case ">":
+				if cmp <= 0 {
+					return false
+				}
+			case ">=":
+				if cmp < 0 {
+					return false
+				}
+			case "<":
+				if cmp >= 0 {
+					return false
+				}
+			case "<=":
+				if cmp > 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// LoadVariableRules parses the validation blocks in a module's variables file
+// and returns their conditions grouped by variable name. Conditions other than
+// can(regex("...", var.x)) and numeric comparisons joined by && are reported
+// as errors so that new validation shapes can't be silently ignored.
+func LoadVariableRules(filename string) (map[string][]VariableRule, error) {
+	file, diags := hclparse.NewParser().ParseHCLFile(filename)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, diags)
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("%s is not native HCL syntax", filename)
+	}
+
+	rules := make(map[string][]VariableRule)
+	var problems []string
+
+	for _, block := range body.Blocks {
+		if block.Type != "variable" || len(block.Labels) != 1 {
+			continue
+		}
+		name := block.Labels[0]
+
+		for _, validation := range block.Body.Blocks {
+			if validation.Type != "validation" {
+				continue
+			}
+
+			cond, ok := validation.Body.Attributes["condition"]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: variable %q validation has no condition", validation.DefRange(), name))
+				continue
+			}
+
+			rule, err := extractRule(name, cond.Expr)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: variable %q: %v", cond.Expr.Range(), name, err))
+				continue
+			}
+
+			if msg, ok := validation.Body.Attributes["error_message"]; ok {
+				if v, diags := msg.Expr.Value(nil); !diags.HasErrors() && v.Type() == cty.String {
+					rule.ErrorMessage = v.AsString()
+				}
+			}
+
+			rules[name] = append(rules[name], rule)
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return rules, fmt.Errorf("unsupported validation conditions:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return rules, nil
+}
+
+// extractRule recognises the condition shapes the Go validators can mirror.
+func extractRule(variable string, expr hclsyntax.Expression) (VariableRule, error) {
+	rule := VariableRule{Variable: variable, Range: expr.Range()}
+	expr = unwrapParens(expr)
+
+	if call, ok := expr.(*hclsyntax.FunctionCallExpr); ok {
+		pattern, err := regexFromCan(variable, call)
+		if err != nil {
+			return rule, err
+		}
+		rule.Kind = RuleRegex
+		rule.Pattern = pattern
+		return rule, nil
+	}
+
+	if _, ok := expr.(*hclsyntax.BinaryOpExpr); ok {
+		bounds, err := boundsFrom(variable, expr)
+		if err != nil {
+			return rule, err
+		}
+		rule.Kind = RuleRange
+		rule.Bounds = bounds
+		return rule, nil
+	}
+
+	return rule, fmt.Errorf("condition must be can(regex(...)) or a numeric comparison")
+}
+
+// regexFromCan extracts the pattern from can(regex("<pattern>", var.<variable>)).
+func regexFromCan(variable string, call *hclsyntax.FunctionCallExpr) (*regexp.Regexp, error) {
+	if call.Name != "can" || len(call.Args) != 1 {
+		return nil, fmt.Errorf("unsupported function %s()", call.Name)
+	}
+
+	inner, ok := unwrapParens(call.Args[0]).(*hclsyntax.FunctionCallExpr)
+	if !ok || inner.Name != "regex" || len(inner.Args) != 2 {
+		return nil, fmt.Errorf("can() must wrap regex(pattern, var.%s)", variable)
+	}
+
+	if !isVarRef(inner.Args[1], variable) {
+		return nil, fmt.Errorf("regex() must test var.%s directly", variable)
+	}
+
+	val, diags := inner.Args[0].Value(nil)
+	if diags.HasErrors() || val.Type() != cty.String || !val.IsKnown() || val.IsNull() {
+		return nil, fmt.Errorf("regex() pattern must be a string literal")
+	}
+
+	pattern, err := regexp.Compile(val.AsString())
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex pattern: %w", err)
+	}
+	return pattern, nil
+}
+
+// boundsFrom flattens `var.x >= 1 && var.x <= 10` into a list of bounds.
+func boundsFrom(variable string, expr hclsyntax.Expression) ([]Bound, error) {
+	bin, ok := unwrapParens(expr).(*hclsyntax.BinaryOpExpr)
+	if !ok {
+		return nil, fmt.Errorf("expected a comparison")
+	}
+
+	if bin.Op == hclsyntax.OpLogicalAnd {
+		lhs, err := boundsFrom(variable, bin.LHS)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := boundsFrom(variable, bin.RHS)
+		if err != nil {
+			return nil, err
+		}
+		return append(lhs, rhs...), nil
+	}
+
+	op, ok := comparisonOps[bin.Op]
+	if !ok {
+		return nil, fmt.Errorf("unsupported operator in range condition")
+	}
+
+	// Normalise "10 >= var.x" to "var.x <= 10".
+	valueExpr := bin.RHS
+	if !isVarRef(bin.LHS, variable) {
+		if !isVarRef(bin.RHS, variable) {
+			return nil, fmt.Errorf("comparison must reference var.%s", variable)
+		}
+		valueExpr = bin.LHS
+		op = flippedOps[op]
+	}
+
+	val, diags := valueExpr.Value(nil)
+	if diags.HasErrors() || val.Type() != cty.Number || !val.IsKnown() || val.IsNull() {
+		return nil, fmt.Errorf("comparison must be against a number literal")
+	}
+
+	return []Bound{{Op: op, Value: val.AsBigFloat()}}, nil
+}
+
+var comparisonOps = map[*hclsyntax.Operation]string{
+	hclsyntax.OpGreaterThan:        ">",
+	hclsyntax.OpGreaterThanOrEqual: ">=",
+	hclsyntax.OpLessThan:           "<",
+	hclsyntax.OpLessThanOrEqual:    "<=",
+}
+
+var flippedOps = map[string]string{">": "<", ">=": "<=", "<": ">", "<=": ">="}
+
+// isVarRef reports whether expr is exactly var.<name>.
+func isVarRef(expr hclsyntax.Expression, name string) bool {
+	trav, ok := unwrapParens(expr).(*hclsyntax.ScopeTraversalExpr)
+	if !ok || len(trav.Traversal) != 2 || trav.Traversal.RootName() != "var" {
+		return false
+	}
+	attr, ok := trav.Traversal[1].(hcl.TraverseAttr)
+	return ok && attr.Name == name
+}
+
+func unwrapParens(expr hclsyntax.Expression) hclsyntax.Expression {
+	for {
+		paren, ok := expr.(*hclsyntax.ParenthesesExpr)
+		if !ok {
+			return expr
+		}
+		expr = paren.Expression.(hclsyntax.Expression)
+	}
+}
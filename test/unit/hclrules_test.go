@@ -0,0 +1,86 @@
+package unit
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// moduleVariablesFile is the module whose validation blocks the Go validators mirror.
+var moduleVariablesFile = filepath.Join("..", "..", "modules", "eks-cluster", "variables.tf")
+
+// moduleValidators maps each module variable that has a validation block to the
+// Go validator that must agree with it. A new validation block in variables.tf
+// fails TestValidatorsMatchModuleRules until it is added here.
+var moduleValidators = map[string]func(string) error{
+	"cluster_version": ValidateKubernetesVersion,
+}
+
+// ruleCorpus is the shared set of inputs both sides are evaluated against.
+var ruleCorpus = map[string][]string{
+	"cluster_version": {
+		"1.31", "1.32", "1.35", "1.39", "1.40", "1.99",
+		"1.30", "1.29", "1.20", "1.5", "1.3", "1.05", "1.0",
+		"1.310", "2.31", "0.31", "v1.31", "1.31.0", "1.31-eks", "1.x",
+		"", " 1.31", "1.31 ", "131",
+	},
+}
+
+func TestValidatorsMatchModuleRules(t *testing.T) {
+	rules, err := LoadVariableRules(moduleVariablesFile)
+	require.NoError(t, err)
+	require.NotEmpty(t, rules, "No validation blocks found in %s", moduleVariablesFile)
+
+	for variable, varRules := range rules {
+		t.Run(variable, func(t *testing.T) {
+			validate, ok := moduleValidators[variable]
+			require.True(t, ok, "variables.tf validates %q but no Go validator is mapped in moduleValidators", variable)
+
+			corpus := ruleCorpus[variable]
+			require.NotEmpty(t, corpus, "no corpus inputs for %q in ruleCorpus", variable)
+
+			for _, input := range corpus {
+				hclAllows := true
+				for _, rule := range varRules {
+					hclAllows = hclAllows && rule.Allows(input)
+				}
+				goErr := validate(input)
+
+				assert.Equal(t, hclAllows, goErr == nil,
+					"validators disagree on %q: variables.tf allows=%v, Go error=%v", input, hclAllows, goErr)
+			}
+		})
+	}
+}
+
+func TestLoadVariableRules(t *testing.T) {
+	rules, err := LoadVariableRules(filepath.Join("testdata", "rules", "variables.tf"))
+	require.Error(t, err, "!= is not a supported condition")
+	assert.Contains(t, err.Error(), `variable "retention": unsupported operator`)
+
+	require.Len(t, rules["name"], 1)
+	name := rules["name"][0]
+	assert.Equal(t, RuleRegex, name.Kind)
+	assert.Equal(t, "^[a-z]+$", name.Pattern.String())
+	assert.Equal(t, "Name must be lowercase letters.", name.ErrorMessage)
+	assert.True(t, name.Allows("abc"))
+	assert.False(t, name.Allows("Abc"))
+
+	require.Len(t, rules["retention"], 1)
+	retention := rules["retention"][0]
+	assert.Equal(t, RuleRange, retention.Kind)
+	require.Len(t, retention.Bounds, 2)
+	assert.Equal(t, ">=", retention.Bounds[0].Op)
+	assert.Equal(t, "<=", retention.Bounds[1].Op)
+
+	for input, want := range map[string]bool{
+		"1": true, "7": true, "3653": true,
+		"0": false, "3654": false, "seven": false,
+	} {
+		assert.Equal(t, want, retention.Allows(input), "retention %q", input)
+	}
+
+	assert.NotContains(t, rules, "unvalidated")
+}
@@ -0,0 +1,48 @@
+package unit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apex/terratest-eks/catalog"
+	"github.com/apex/terratest-eks/version"
+)
+
+// DefaultSupportWarningWindow is how far ahead of a support deadline
+// VersionLifecycleWarnings starts warning.
+const DefaultSupportWarningWindow = 90 * 24 * time.Hour
+
+// VersionLifecycleWarnings returns advisory warnings about where a cluster
+// version sits in the EKS support lifecycle at time at: unknown to the catalog,
+// not yet released, in (paid) extended support, past end of life, or within
+// window of its next support deadline. Malformed versions are left to
+// ValidateKubernetesVersion and produce no warnings.
+func VersionLifecycleWarnings(cat *catalog.Catalog, raw string, at time.Time, window time.Duration) []string {
+	v, err := version.Parse(raw)
+	if err != nil {
+		return nil
+	}
+
+	entry, ok := cat.Lookup(v)
+	if !ok {
+		return []string{fmt.Sprintf("EKS %s is not in the version catalog (updated %s)", v, cat.Updated.Format("2006-01-02"))}
+	}
+
+	ends := entry.PhaseEnds(at)
+	var warnings []string
+
+	switch entry.Phase(at) {
+	case catalog.PhaseUnreleased:
+		warnings = append(warnings, fmt.Sprintf("EKS %s is not released until %s", v, ends.Format("2006-01-02")))
+	case catalog.PhaseStandard:
+		if ends.Sub(at) <= window {
+			warnings = append(warnings, fmt.Sprintf("EKS %s leaves standard support on %s", v, ends.Format("2006-01-02")))
+		}
+	case catalog.PhaseExtended:
+		warnings = append(warnings, fmt.Sprintf("EKS %s is in extended support (additional cost) until %s", v, ends.Format("2006-01-02")))
+	case catalog.PhaseEndOfLife:
+		warnings = append(warnings, fmt.Sprintf("EKS %s reached end of extended support on %s", v, entry.EndOfExtendedSupport.Format("2006-01-02")))
+	}
+
+	return warnings
+}
@@ -0,0 +1,54 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apex/terratest-eks/catalog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionLifecycleWarnings(t *testing.T) {
+	cat, err := catalog.Parse([]byte(`{
+		"schema_version": 1,
+		"updated": "2025-06-01",
+		"versions": [
+			{"version": "1.31", "release_date": "2024-09-26", "end_of_standard_support": "2025-11-26", "end_of_extended_support": "2026-11-26"},
+			{"version": "1.33", "release_date": "2025-05-29", "end_of_standard_support": "2026-07-29", "end_of_extended_support": "2027-07-29"}
+		]
+	}`))
+	require.NoError(t, err)
+
+	at := func(s string) time.Time {
+		tm, err := time.Parse("2006-01-02", s)
+		require.NoError(t, err)
+		return tm
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		at      string
+		want    string
+	}{
+		{"standard support, far from deadline", "1.33", "2025-06-01", ""},
+		{"standard support, deadline within window", "1.31", "2025-10-01", "EKS 1.31 leaves standard support on 2025-11-26"},
+		{"extended support", "1.31", "2026-01-01", "EKS 1.31 is in extended support (additional cost) until 2026-11-26"},
+		{"end of life", "1.31", "2027-01-01", "EKS 1.31 reached end of extended support on 2026-11-26"},
+		{"unreleased", "1.33", "2025-05-01", "EKS 1.33 is not released until 2025-05-29"},
+		{"unknown to catalog", "1.32", "2025-06-01", "EKS 1.32 is not in the version catalog (updated 2025-06-01)"},
+		{"malformed is left to the format validator", "v1.31", "2025-06-01", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := VersionLifecycleWarnings(cat, tt.version, at(tt.at), DefaultSupportWarningWindow)
+			if tt.want == "" {
+				assert.Empty(t, warnings)
+			} else {
+				assert.Equal(t, []string{tt.want}, warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package unit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrorCode is a stable, machine-readable identifier for a validation failure.
+// Codes are part of the pipeline's reporting contract — do not rename them.
+type ErrorCode string
+
+const (
+	CodeRequired           ErrorCode = "required"
+	CodeTooLong            ErrorCode = "too_long"
+	CodeInvalidFormat      ErrorCode = "invalid_format"
+	CodeBelowMinimum       ErrorCode = "below_minimum"
+	CodeUnsupportedVersion ErrorCode = "unsupported_version"
+	CodeTooFew             ErrorCode = "too_few"
+	CodeEmptyValue         ErrorCode = "empty_value"
+	CodeMissingTag         ErrorCode = "missing_tag"
+	CodeNegative           ErrorCode = "negative"
+	CodeMaxBelowMin        ErrorCode = "max_below_min"
+	CodeDesiredBelowMin    ErrorCode = "desired_below_min"
+	CodeDesiredAboveMax    ErrorCode = "desired_above_max"
+)
+
+// FieldError is a single validation failure tied to a field path
+// (e.g. "node_groups.default.max_size").
+type FieldError struct {
+	Field   string
+	Code    ErrorCode
+	Message string
+}
+
+func newFieldError(field string, code ErrorCode, message string) *FieldError {
+	return &FieldError{Field: field, Code: code, Message: message}
+}
+
+// Error returns the human-readable message so the single-value validators keep
+// their original wording. Use Field and Code for structured reporting.
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors collects every FieldError found in a ClusterSpec.
+type ValidationErrors []*FieldError
+
+// Error lists every violation on its own line as "field: message [code]".
+func (v ValidationErrors) Error() string {
+	lines := make([]string, 0, len(v)+1)
+	lines = append(lines, fmt.Sprintf("%d validation error(s):", len(v)))
+	for _, e := range v {
+		lines = append(lines, fmt.Sprintf("  %s: %s [%s]", e.Field, e.Message, e.Code))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// NodeGroupSpec holds the inputs for a single managed node group.
+type NodeGroupSpec struct {
+	InstanceTypes []string
+	MinSize       int
+	MaxSize       int
+	DesiredSize   int
+}
+
+// ClusterSpec bundles every input checked by the validators in this package,
+// so a whole configuration can be validated in one pass.
+type ClusterSpec struct {
+	Name         string
+	Version      string
+	SubnetIDs    []string
+	MinSubnets   int
+	Tags         map[string]string
+	RequiredTags []string
+	NodeGroups   map[string]NodeGroupSpec
+}
+
+// ValidateClusterSpec runs every validator over spec and returns all violations
+// as ValidationErrors, or nil if the spec is valid. Node groups are checked in
+// name order so the output is stable.
+func ValidateClusterSpec(spec ClusterSpec) error {
+	var errs ValidationErrors
+	errs = append(errs, clusterNameErrors("cluster_name", spec.Name)...)
+	errs = append(errs, kubernetesVersionErrors("cluster_version", spec.Version)...)
+	errs = append(errs, subnetErrors("subnet_ids", spec.SubnetIDs, spec.MinSubnets)...)
+	errs = append(errs, tagErrors("tags", spec.Tags, spec.RequiredTags)...)
+
+	names := make([]string, 0, len(spec.NodeGroups))
+	for name := range spec.NodeGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ng := spec.NodeGroups[name]
+		prefix := fmt.Sprintf("node_groups.%s.", name)
+		errs = append(errs, instanceTypeErrors(prefix+"instance_types", ng.InstanceTypes)...)
+		errs = append(errs, nodeGroupSizeErrors(prefix, ng.MinSize, ng.MaxSize, ng.DesiredSize)...)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
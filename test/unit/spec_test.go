@@ -0,0 +1,137 @@
+package unit
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validClusterSpec() ClusterSpec {
+	return ClusterSpec{
+		Name:         "my-cluster",
+		Version:      "1.31",
+		SubnetIDs:    []string{"subnet-1", "subnet-2"},
+		MinSubnets:   2,
+		Tags:         map[string]string{"Environment": "test", "Team": "platform"},
+		RequiredTags: []string{"Environment", "Team"},
+		NodeGroups: map[string]NodeGroupSpec{
+			"default": {
+				InstanceTypes: []string{"t3.medium"},
+				MinSize:       1,
+				MaxSize:       3,
+				DesiredSize:   2,
+			},
+		},
+	}
+}
+
+func TestValidateClusterSpec(t *testing.T) {
+	type fieldCode struct {
+		Field string
+		Code  ErrorCode
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*ClusterSpec)
+		want   []fieldCode
+	}{
+		{
+			name:   "valid spec",
+			mutate: func(*ClusterSpec) {},
+		},
+		{
+			name:   "single violation",
+			mutate: func(s *ClusterSpec) { s.Name = "" },
+			want:   []fieldCode{{"cluster_name", CodeRequired}},
+		},
+		{
+			name: "reports every violation at once",
+			mutate: func(s *ClusterSpec) {
+				s.Name = "bad_name"
+				s.Version = "v1.31"
+				s.SubnetIDs = []string{" "}
+				s.Tags = map[string]string{}
+				s.NodeGroups["default"] = NodeGroupSpec{
+					InstanceTypes: []string{"t3.medium", "T3.LARGE"},
+					MinSize:       2,
+					MaxSize:       1,
+					DesiredSize:   3,
+				}
+			},
+			want: []fieldCode{
+				{"cluster_name", CodeInvalidFormat},
+				{"cluster_version", CodeInvalidFormat},
+				{"subnet_ids", CodeTooFew},
+				{"subnet_ids[0]", CodeEmptyValue},
+				{"tags.Environment", CodeMissingTag},
+				{"tags.Team", CodeMissingTag},
+				{"node_groups.default.instance_types[1]", CodeInvalidFormat},
+				{"node_groups.default.max_size", CodeMaxBelowMin},
+				{"node_groups.default.desired_size", CodeDesiredAboveMax},
+			},
+		},
+		{
+			name: "node groups reported in name order",
+			mutate: func(s *ClusterSpec) {
+				s.NodeGroups["zeta"] = NodeGroupSpec{InstanceTypes: []string{"t3.small"}, MinSize: -1, MaxSize: 1}
+				s.NodeGroups["alpha"] = NodeGroupSpec{MinSize: 1, MaxSize: 1, DesiredSize: 1}
+			},
+			want: []fieldCode{
+				{"node_groups.alpha.instance_types", CodeRequired},
+				{"node_groups.zeta.min_size", CodeNegative},
+			},
+		},
+		{
+			name:   "nil tags",
+			mutate: func(s *ClusterSpec) { s.Tags = nil },
+			want:   []fieldCode{{"tags", CodeRequired}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := validClusterSpec()
+			tt.mutate(&spec)
+
+			err := ValidateClusterSpec(spec)
+			if len(tt.want) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verrs ValidationErrors
+			require.True(t, errors.As(err, &verrs), "expected ValidationErrors, got %T", err)
+
+			got := make([]fieldCode, 0, len(verrs))
+			for _, e := range verrs {
+				got = append(got, fieldCode{e.Field, e.Code})
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidationErrorsMessage(t *testing.T) {
+	spec := validClusterSpec()
+	spec.Name = ""
+	spec.NodeGroups["default"] = NodeGroupSpec{InstanceTypes: []string{"t3.small"}, MinSize: 1, MaxSize: 3, DesiredSize: 5}
+
+	err := ValidateClusterSpec(spec)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 validation error(s):")
+	assert.Contains(t, err.Error(), "cluster_name: cluster name cannot be empty [required]")
+	assert.Contains(t, err.Error(), "node_groups.default.desired_size: desired size (5) cannot exceed max size (3) [desired_above_max]")
+}
+
+func TestSingleValidatorsExposeFieldError(t *testing.T) {
+	err := ValidateNodeGroupSize(3, 5, 2)
+
+	var fe *FieldError
+	require.True(t, errors.As(err, &fe))
+	assert.Equal(t, "desired_size", fe.Field)
+	assert.Equal(t, CodeDesiredBelowMin, fe.Code)
+	assert.Equal(t, "desired size (2) cannot be less than min size (3)", err.Error())
+}
@@ -0,0 +1,26 @@
+variable "name" {
+  type = string
+
+  validation {
+    condition     = can(regex("^[a-z]+$", var.name))
+    error_message = "Name must be lowercase letters."
+  }
+}
+
+variable "retention" {
+  type = number
+
+  validation {
+    condition     = var.retention >= 1 && (3653 >= var.retention)
+    error_message = "Retention must be between 1 and 3653 days."
+  }
+
+  validation {
+    condition     = var.retention != 2
+    error_message = "Two days is not allowed."
+  }
+}
+
+variable "unvalidated" {
+  type = string
+}
@@ -0,0 +1,398 @@
+package unit
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+const (
+	CodeInvalidType  ErrorCode = "invalid_type"
+	CodeInvalidValue ErrorCode = "invalid_value"
+)
+
+// Defaults mirrored from modules/eks-cluster/variables.tf and the upstream
+// terraform-aws-modules/eks managed node group, applied when a var file omits them.
+const (
+	defaultClusterVersion   = "1.31"
+	defaultNodeGroupMinSize = 1
+	defaultNodeGroupMaxSize = 3
+	defaultNodeGroupDesired = 1
+
+	// DefaultMinSubnets is the subnet count EKS needs to spread control plane ENIs across two AZs.
+	DefaultMinSubnets = 2
+)
+
+var defaultNodeGroupInstanceTypes = []string{"t3.medium"}
+
+// VarFile is a Terraform variable file (*.tfvars or *.tfvars.json) mapped onto
+// the eks-cluster module's inputs. Spec may be adjusted (e.g. RequiredTags)
+// before calling Validate.
+type VarFile struct {
+	Filename string
+	Spec     ClusterSpec
+
+	positions    map[string]hcl.Range
+	fallback     hcl.Range
+	decodeErrors []*FieldError
+}
+
+// PositionedError is a FieldError located in a variable file.
+type PositionedError struct {
+	*FieldError
+	Filename string
+	Line     int
+	Column   int
+}
+
+// Error formats the failure as "file:line:col: field: message [code]".
+func (e *PositionedError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s [%s]", e.Filename, e.Line, e.Column, e.Field, e.Message, e.Code)
+}
+
+// VarFileErrors collects every PositionedError found in a variable file.
+type VarFileErrors []*PositionedError
+
+// Error lists every violation on its own line.
+func (v VarFileErrors) Error() string {
+	lines := make([]string, 0, len(v))
+	for _, e := range v {
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LoadVarFile parses a *.tfvars or *.tfvars.json file and maps the module's
+// variables onto a ClusterSpec. Syntax errors are returned immediately; type
+// errors in individual values are deferred to Validate so they can be
+// reported alongside everything else.
+func LoadVarFile(filename string) (*VarFile, error) {
+	parser := hclparse.NewParser()
+
+	var file *hcl.File
+	var diags hcl.Diagnostics
+	if strings.HasSuffix(filename, ".json") {
+		file, diags = parser.ParseJSONFile(filename)
+	} else {
+		file, diags = parser.ParseHCLFile(filename)
+	}
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, diags)
+	}
+
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to read variables from %s: %w", filename, diags)
+	}
+
+	d := &varFileDecoder{positions: make(map[string]hcl.Range)}
+	vf := &VarFile{
+		Filename: filename,
+		Spec: ClusterSpec{
+			Version:    defaultClusterVersion,
+			MinSubnets: DefaultMinSubnets,
+			Tags:       map[string]string{},
+			NodeGroups: map[string]NodeGroupSpec{},
+		},
+		positions: d.positions,
+		fallback:  file.Body.MissingItemRange(),
+	}
+
+	if attr, ok := attrs["cluster_name"]; ok {
+		if s, ok := d.str("cluster_name", attr.Expr); ok {
+			vf.Spec.Name = s
+		}
+	}
+	if attr, ok := attrs["cluster_version"]; ok {
+		if s, ok := d.str("cluster_version", attr.Expr); ok {
+			vf.Spec.Version = s
+		}
+	}
+	if attr, ok := attrs["subnet_ids"]; ok {
+		if l, ok := d.strList("subnet_ids", attr.Expr); ok {
+			vf.Spec.SubnetIDs = l
+		}
+	}
+	if attr, ok := attrs["tags"]; ok {
+		if m, ok := d.strMap("tags", attr.Expr); ok {
+			vf.Spec.Tags = m
+		}
+	}
+
+	defaultTypes := defaultNodeGroupInstanceTypes
+	if attr, ok := attrs["eks_managed_node_group_defaults"]; ok {
+		if obj, ok := d.object("node_group_defaults", attr.Expr); ok {
+			if expr, ok := obj["instance_types"]; ok {
+				if l, ok := d.strList("node_group_defaults.instance_types", expr); ok {
+					defaultTypes = l
+				}
+			}
+		}
+	}
+
+	if attr, ok := attrs["eks_managed_node_groups"]; ok {
+		if groups, ok := d.object("node_groups", attr.Expr); ok {
+			for name, expr := range groups {
+				vf.Spec.NodeGroups[name] = d.nodeGroup("node_groups."+name, expr, defaultTypes)
+			}
+		}
+	}
+
+	vf.decodeErrors = d.errs
+	return vf, nil
+}
+
+// Validate runs ValidateClusterSpec over the file's spec and returns every
+// violation, including value type errors found while loading, as VarFileErrors
+// sorted by position. It returns nil if the file is valid.
+func (f *VarFile) Validate() error {
+	fieldErrs := append([]*FieldError{}, f.decodeErrors...)
+
+	var verrs ValidationErrors
+	if err := ValidateClusterSpec(f.Spec); err != nil {
+		verrs = err.(ValidationErrors)
+	}
+	fieldErrs = append(fieldErrs, verrs...)
+
+	if len(fieldErrs) == 0 {
+		return nil
+	}
+
+	errs := make(VarFileErrors, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		rng := f.rangeFor(fe.Field)
+		errs = append(errs, &PositionedError{
+			FieldError: fe,
+			Filename:   f.Filename,
+			Line:       rng.Start.Line,
+			Column:     rng.Start.Column,
+		})
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Column < errs[j].Column
+	})
+
+	return errs
+}
+
+// rangeFor finds the closest recorded source range for a field path, walking
+// up from "node_groups.default.max_size" to "node_groups.default" to
+// "node_groups". Fields absent from the file resolve to the file start.
+func (f *VarFile) rangeFor(field string) hcl.Range {
+	for field != "" {
+		if rng, ok := f.positions[field]; ok {
+			return rng
+		}
+		field = parentField(field)
+	}
+	return f.fallback
+}
+
+// parentField strips the last path component: "a.b[1]" → "a.b" → "a".
+func parentField(field string) string {
+	if strings.HasSuffix(field, "]") {
+		if i := strings.LastIndex(field, "["); i >= 0 {
+			return field[:i]
+		}
+	}
+	if i := strings.LastIndex(field, "."); i >= 0 {
+		return field[:i]
+	}
+	return ""
+}
+
+// FindVarFiles returns every *.tfvars and *.tfvars.json file under root,
+// skipping .terraform, .task, and testdata directories.
+func FindVarFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			switch entry.Name() {
+			case ".terraform", ".task", ".git", "testdata":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".tfvars") || strings.HasSuffix(path, ".tfvars.json") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return files, nil
+}
+
+// varFileDecoder converts HCL expressions into Go values, recording the
+// source range of every field it visits and collecting type errors.
+type varFileDecoder struct {
+	positions map[string]hcl.Range
+	errs      []*FieldError
+}
+
+func (d *varFileDecoder) fail(field string, code ErrorCode, format string, args ...interface{}) {
+	d.errs = append(d.errs, newFieldError(field, code, fmt.Sprintf(format, args...)))
+}
+
+// value evaluates a literal expression. Null values are treated as unset.
+func (d *varFileDecoder) value(field string, expr hcl.Expression, want cty.Type) (cty.Value, bool) {
+	d.positions[field] = expr.Range()
+
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		d.fail(field, CodeInvalidValue, "%s", diags[0].Detail)
+		return cty.NilVal, false
+	}
+	if val.IsNull() {
+		return cty.NilVal, false
+	}
+
+	val, err := convert.Convert(val, want)
+	if err != nil {
+		d.fail(field, CodeInvalidType, "must be %s", want.FriendlyName())
+		return cty.NilVal, false
+	}
+	return val, true
+}
+
+func (d *varFileDecoder) str(field string, expr hcl.Expression) (string, bool) {
+	val, ok := d.value(field, expr, cty.String)
+	if !ok {
+		return "", false
+	}
+	return val.AsString(), true
+}
+
+func (d *varFileDecoder) number(field string, expr hcl.Expression) (int, bool) {
+	val, ok := d.value(field, expr, cty.Number)
+	if !ok {
+		return 0, false
+	}
+
+	var n int
+	if err := gocty.FromCtyValue(val, &n); err != nil {
+		d.fail(field, CodeInvalidType, "must be a whole number")
+		return 0, false
+	}
+	return n, true
+}
+
+func (d *varFileDecoder) strList(field string, expr hcl.Expression) ([]string, bool) {
+	d.positions[field] = expr.Range()
+
+	elems, diags := hcl.ExprList(expr)
+	if diags.HasErrors() {
+		d.fail(field, CodeInvalidType, "must be a list of strings")
+		return nil, false
+	}
+
+	list := make([]string, 0, len(elems))
+	for i, elem := range elems {
+		// Keep bad elements as blanks so indexes still line up.
+		s, _ := d.str(fmt.Sprintf("%s[%d]", field, i), elem)
+		list = append(list, s)
+	}
+	return list, true
+}
+
+func (d *varFileDecoder) strMap(field string, expr hcl.Expression) (map[string]string, bool) {
+	obj, ok := d.object(field, expr)
+	if !ok {
+		return nil, false
+	}
+
+	m := make(map[string]string, len(obj))
+	for key, valueExpr := range obj {
+		if s, ok := d.str(field+"."+key, valueExpr); ok {
+			m[key] = s
+		}
+	}
+	return m, true
+}
+
+// object returns the value expressions of an object or map literal by key.
+func (d *varFileDecoder) object(field string, expr hcl.Expression) (map[string]hcl.Expression, bool) {
+	d.positions[field] = expr.Range()
+
+	pairs, diags := hcl.ExprMap(expr)
+	if diags.HasErrors() {
+		d.fail(field, CodeInvalidType, "must be an object")
+		return nil, false
+	}
+
+	obj := make(map[string]hcl.Expression, len(pairs))
+	for _, pair := range pairs {
+		key, diags := pair.Key.Value(nil)
+		if diags.HasErrors() || key.Type() != cty.String || key.IsNull() {
+			d.fail(field, CodeInvalidValue, "object keys must be strings")
+			continue
+		}
+		obj[key.AsString()] = pair.Value
+	}
+	return obj, true
+}
+
+// nodeGroup decodes one eks_managed_node_groups entry, falling back to the
+// node group defaults for anything it omits.
+func (d *varFileDecoder) nodeGroup(prefix string, expr hcl.Expression, defaultTypes []string) NodeGroupSpec {
+	ng := NodeGroupSpec{
+		InstanceTypes: defaultTypes,
+		MinSize:       defaultNodeGroupMinSize,
+		MaxSize:       defaultNodeGroupMaxSize,
+		DesiredSize:   defaultNodeGroupDesired,
+	}
+
+	obj, ok := d.object(prefix, expr)
+	if !ok {
+		return ng
+	}
+
+	if e, ok := obj["instance_types"]; ok {
+		if l, ok := d.strList(prefix+".instance_types", e); ok {
+			ng.InstanceTypes = l
+		}
+	} else if rng, ok := d.positions["node_group_defaults.instance_types"]; ok {
+		// Inherited types are reported where the defaults declare them.
+		d.positions[prefix+".instance_types"] = rng
+		for i := range defaultTypes {
+			if r, ok := d.positions[fmt.Sprintf("node_group_defaults.instance_types[%d]", i)]; ok {
+				d.positions[fmt.Sprintf("%s.instance_types[%d]", prefix, i)] = r
+			}
+		}
+	}
+
+	sizes := []struct {
+		name string
+		dst  *int
+	}{
+		{"min_size", &ng.MinSize},
+		{"max_size", &ng.MaxSize},
+		{"desired_size", &ng.DesiredSize},
+	}
+	for _, size := range sizes {
+		if e, ok := obj[size.name]; ok {
+			if n, ok := d.number(prefix+"."+size.name, e); ok {
+				*size.dst = n
+			}
+		}
+	}
+
+	return ng
+}
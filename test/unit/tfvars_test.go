@@ -0,0 +1,133 @@
+package unit
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apex/terratest-eks/catalog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadVarFile(t *testing.T) {
+	vf, err := LoadVarFile(filepath.Join("testdata", "tfvars", "valid.tfvars"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "platform-dev", vf.Spec.Name)
+	assert.Equal(t, "1.31", vf.Spec.Version)
+	assert.Equal(t, []string{"subnet-aaa", "subnet-bbb"}, vf.Spec.SubnetIDs)
+	assert.Equal(t, map[string]string{"Environment": "dev", "Team": "platform"}, vf.Spec.Tags)
+	assert.Equal(t, map[string]NodeGroupSpec{
+		"default": {InstanceTypes: []string{"t3.medium"}, MinSize: 1, MaxSize: 3, DesiredSize: 2},
+	}, vf.Spec.NodeGroups)
+
+	vf.Spec.RequiredTags = []string{"Environment", "Team"}
+	assert.NoError(t, vf.Validate())
+}
+
+func TestVarFileValidate(t *testing.T) {
+	type located struct {
+		Line  int
+		Field string
+		Code  ErrorCode
+	}
+
+	tests := []struct {
+		name         string
+		file         string
+		requiredTags []string
+		want         []located
+	}{
+		{
+			name:         "hcl",
+			file:         "invalid.tfvars",
+			requiredTags: []string{"Environment", "Team"},
+			want: []located{
+				{1, "cluster_name", CodeInvalidFormat},
+				{2, "cluster_version", CodeInvalidFormat},
+				{3, "subnet_ids[1]", CodeEmptyValue},
+				{5, "tags.Team", CodeMissingTag},
+				{11, "node_groups.default.instance_types[1]", CodeInvalidFormat},
+				{13, "node_groups.default.max_size", CodeMaxBelowMin},
+				{14, "node_groups.default.desired_size", CodeDesiredAboveMax},
+			},
+		},
+		{
+			name: "json with module defaults",
+			file: "invalid.tfvars.json",
+			want: []located{
+				{3, "subnet_ids", CodeTooFew},
+				{7, "node_groups.default.max_size", CodeInvalidType},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join("testdata", "tfvars", tt.file)
+			vf, err := LoadVarFile(path)
+			require.NoError(t, err)
+			vf.Spec.RequiredTags = tt.requiredTags
+
+			var errs VarFileErrors
+			require.True(t, errors.As(vf.Validate(), &errs))
+
+			got := make([]located, 0, len(errs))
+			for _, e := range errs {
+				assert.Equal(t, path, e.Filename)
+				got = append(got, located{e.Line, e.Field, e.Code})
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestVarFileErrorFormat(t *testing.T) {
+	path := filepath.Join("testdata", "tfvars", "invalid.tfvars")
+	vf, err := LoadVarFile(path)
+	require.NoError(t, err)
+
+	err = vf.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), path+":2:19: cluster_version: kubernetes version must be in format 1.XX (e.g., 1.31) [invalid_format]")
+}
+
+func TestLoadVarFileSyntaxError(t *testing.T) {
+	_, err := LoadVarFile(filepath.Join("testdata", "tfvars", "malformed.tfvars"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "malformed.tfvars:1")
+}
+
+func TestParentField(t *testing.T) {
+	assert.Equal(t, "node_groups.default", parentField("node_groups.default.max_size"))
+	assert.Equal(t, "subnet_ids", parentField("subnet_ids[3]"))
+	assert.Equal(t, "", parentField("cluster_name"))
+}
+
+// TestCommittedVarFiles validates every variable file checked into the repo,
+// so bad configs fail here instead of during terraform apply.
+func TestCommittedVarFiles(t *testing.T) {
+	files, err := FindVarFiles(filepath.Join("..", ".."))
+	require.NoError(t, err)
+
+	if len(files) == 0 {
+		t.Log("No *.tfvars or *.tfvars.json files found")
+	}
+
+	cat, err := catalog.Load()
+	require.NoError(t, err)
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			vf, err := LoadVarFile(file)
+			require.NoError(t, err)
+			assert.NoError(t, vf.Validate())
+
+			for _, warning := range VersionLifecycleWarnings(cat, vf.Spec.Version, time.Now(), DefaultSupportWarningWindow) {
+				t.Logf("WARNING: %s", warning)
+			}
+		})
+	}
+}
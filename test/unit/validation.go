@@ -4,90 +4,154 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/apex/terratest-eks/version"
+)
+
+var (
+	// MinKubernetesVersion is the oldest version accepted by the cluster_version
+	// validation in modules/eks-cluster/variables.tf.
+	MinKubernetesVersion = version.MustParse("1.31")
+
+	// SupportedKubernetesVersions mirrors the module's ^1\.(3[1-9]|[4-9][0-9])$ pattern.
+	SupportedKubernetesVersions = version.MustParseConstraint(">=1.31,<=1.99")
+)
+
+var (
+	validNamePattern     = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9-]*$`)
+	validInstancePattern = regexp.MustCompile(`^[a-z][a-z0-9]+\.[a-z0-9]+$`)
 )
 
 // ValidateClusterName checks if a cluster name is valid for EKS
 // EKS cluster names must be 1-100 characters, alphanumeric plus hyphens
 func ValidateClusterName(name string) error {
+	return firstError(clusterNameErrors("cluster_name", name))
+}
+
+// ValidateKubernetesVersion checks if a Kubernetes version is valid format
+// and no older than the module's minimum supported version
+func ValidateKubernetesVersion(version string) error {
+	return firstError(kubernetesVersionErrors("cluster_version", version))
+}
+
+// ValidateSubnetCount checks if the minimum subnet count requirement is met
+func ValidateSubnetCount(subnets []string, minRequired int) error {
+	return firstError(subnetErrors("subnet_ids", subnets, minRequired))
+}
+
+// ValidateTags checks if required tags are present
+func ValidateTags(tags map[string]string, requiredTags []string) error {
+	return firstError(tagErrors("tags", tags, requiredTags))
+}
+
+// ValidateInstanceTypes checks if instance types are valid AWS EC2 types
+func ValidateInstanceTypes(instanceTypes []string) error {
+	return firstError(instanceTypeErrors("instance_types", instanceTypes))
+}
+
+// ValidateNodeGroupSize validates min/max/desired node group configuration
+func ValidateNodeGroupSize(min, max, desired int) error {
+	return firstError(nodeGroupSizeErrors("", min, max, desired))
+}
+
+// clusterNameErrors reports every problem with an EKS cluster name.
+func clusterNameErrors(field, name string) []*FieldError {
 	if name == "" {
-		return fmt.Errorf("cluster name cannot be empty")
+		return []*FieldError{newFieldError(field, CodeRequired, "cluster name cannot be empty")}
 	}
 
+	var errs []*FieldError
 	if len(name) > 100 {
-		return fmt.Errorf("cluster name cannot exceed 100 characters, got %d", len(name))
+		errs = append(errs, newFieldError(field, CodeTooLong,
+			fmt.Sprintf("cluster name cannot exceed 100 characters, got %d", len(name))))
 	}
 
 	// EKS cluster names: alphanumeric and hyphens only, must start with letter/number
-	validNamePattern := regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9-]*$`)
 	if !validNamePattern.MatchString(name) {
-		return fmt.Errorf("cluster name must contain only alphanumeric characters and hyphens, and must start with a letter or number")
+		errs = append(errs, newFieldError(field, CodeInvalidFormat,
+			"cluster name must contain only alphanumeric characters and hyphens, and must start with a letter or number"))
 	}
 
-	return nil
+	return errs
 }
 
-// ValidateKubernetesVersion checks if a Kubernetes version is valid format
-func ValidateKubernetesVersion(version string) error {
-	if version == "" {
-		return fmt.Errorf("kubernetes version cannot be empty")
+// kubernetesVersionErrors reports every problem with a Kubernetes version string.
+func kubernetesVersionErrors(field, raw string) []*FieldError {
+	if raw == "" {
+		return []*FieldError{newFieldError(field, CodeRequired, "kubernetes version cannot be empty")}
 	}
 
 	// Version format: 1.XX
-	validVersionPattern := regexp.MustCompile(`^1\.\d{1,2}$`)
-	if !validVersionPattern.MatchString(version) {
-		return fmt.Errorf("kubernetes version must be in format 1.XX (e.g., 1.29)")
+	v, err := version.Parse(raw)
+	if err != nil {
+		return []*FieldError{newFieldError(field, CodeInvalidFormat, "kubernetes version must be in format 1.XX (e.g., 1.31)")}
+	}
+
+	if v.Less(MinKubernetesVersion) {
+		return []*FieldError{newFieldError(field, CodeBelowMinimum,
+			fmt.Sprintf("kubernetes version must be %s or higher, got %s", MinKubernetesVersion, raw))}
+	}
+
+	if !SupportedKubernetesVersions.Check(v) {
+		return []*FieldError{newFieldError(field, CodeUnsupportedVersion,
+			fmt.Sprintf("kubernetes version must satisfy %s, got %s", SupportedKubernetesVersions, raw))}
 	}
 
 	return nil
 }
 
-// ValidateSubnetCount checks if the minimum subnet count requirement is met
-func ValidateSubnetCount(subnets []string, minRequired int) error {
+// subnetErrors reports a short subnet list and every blank subnet ID.
+func subnetErrors(field string, subnets []string, minRequired int) []*FieldError {
+	var errs []*FieldError
 	if len(subnets) < minRequired {
-		return fmt.Errorf("at least %d subnets required for high availability, got %d", minRequired, len(subnets))
+		errs = append(errs, newFieldError(field, CodeTooFew,
+			fmt.Sprintf("at least %d subnets required for high availability, got %d", minRequired, len(subnets))))
 	}
 
 	// Check for empty subnet IDs
 	for i, subnet := range subnets {
 		if strings.TrimSpace(subnet) == "" {
-			return fmt.Errorf("subnet at index %d is empty", i)
+			errs = append(errs, newFieldError(fmt.Sprintf("%s[%d]", field, i), CodeEmptyValue,
+				fmt.Sprintf("subnet at index %d is empty", i)))
 		}
 	}
 
-	return nil
+	return errs
 }
 
-// ValidateTags checks if required tags are present
-func ValidateTags(tags map[string]string, requiredTags []string) error {
+// tagErrors reports every required tag missing from tags.
+func tagErrors(field string, tags map[string]string, requiredTags []string) []*FieldError {
 	if tags == nil {
-		return fmt.Errorf("tags map cannot be nil")
+		return []*FieldError{newFieldError(field, CodeRequired, "tags map cannot be nil")}
 	}
 
+	var errs []*FieldError
 	for _, required := range requiredTags {
 		if _, exists := tags[required]; !exists {
-			return fmt.Errorf("required tag '%s' is missing", required)
+			errs = append(errs, newFieldError(field+"."+required, CodeMissingTag,
+				fmt.Sprintf("required tag '%s' is missing", required)))
 		}
 	}
 
-	return nil
+	return errs
 }
 
-// ValidateInstanceTypes checks if instance types are valid AWS EC2 types
-func ValidateInstanceTypes(instanceTypes []string) error {
+// instanceTypeErrors reports an empty list and every malformed instance type.
+func instanceTypeErrors(field string, instanceTypes []string) []*FieldError {
 	if len(instanceTypes) == 0 {
-		return fmt.Errorf("at least one instance type must be specified")
+		return []*FieldError{newFieldError(field, CodeRequired, "at least one instance type must be specified")}
 	}
 
 	// Basic format check for instance types (e.g., t3.medium, m5.large)
-	validInstancePattern := regexp.MustCompile(`^[a-z][a-z0-9]+\.[a-z0-9]+$`)
-
-	for _, instanceType := range instanceTypes {
+	var errs []*FieldError
+	for i, instanceType := range instanceTypes {
 		if !validInstancePattern.MatchString(instanceType) {
-			return fmt.Errorf("invalid instance type format: %s", instanceType)
+			errs = append(errs, newFieldError(fmt.Sprintf("%s[%d]", field, i), CodeInvalidFormat,
+				fmt.Sprintf("invalid instance type format: %s", instanceType)))
 		}
 	}
 
-	return nil
+	return errs
 }
 
 // GenerateClusterTags merges default tags with custom tags
@@ -107,23 +171,38 @@ func GenerateClusterTags(defaultTags, customTags map[string]string) map[string]s
 	return result
 }
 
-// ValidateNodeGroupSize validates min/max/desired node group configuration
-func ValidateNodeGroupSize(min, max, desired int) error {
+// nodeGroupSizeErrors reports every inconsistency between min, max, and desired size.
+// prefix is prepended to the min_size/max_size/desired_size field names.
+func nodeGroupSizeErrors(prefix string, min, max, desired int) []*FieldError {
+	var errs []*FieldError
 	if min < 0 {
-		return fmt.Errorf("min size cannot be negative, got %d", min)
+		errs = append(errs, newFieldError(prefix+"min_size", CodeNegative,
+			fmt.Sprintf("min size cannot be negative, got %d", min)))
 	}
 
 	if max < min {
-		return fmt.Errorf("max size (%d) cannot be less than min size (%d)", max, min)
+		errs = append(errs, newFieldError(prefix+"max_size", CodeMaxBelowMin,
+			fmt.Sprintf("max size (%d) cannot be less than min size (%d)", max, min)))
 	}
 
 	if desired < min {
-		return fmt.Errorf("desired size (%d) cannot be less than min size (%d)", desired, min)
+		errs = append(errs, newFieldError(prefix+"desired_size", CodeDesiredBelowMin,
+			fmt.Sprintf("desired size (%d) cannot be less than min size (%d)", desired, min)))
 	}
 
 	if desired > max {
-		return fmt.Errorf("desired size (%d) cannot exceed max size (%d)", desired, max)
+		errs = append(errs, newFieldError(prefix+"desired_size", CodeDesiredAboveMax,
+			fmt.Sprintf("desired size (%d) cannot exceed max size (%d)", desired, max)))
 	}
 
-	return nil
+	return errs
+}
+
+// firstError returns the first entry of errs, or nil. The single-value validators
+// keep their fail-fast contract on top of the collecting checks.
+func firstError(errs []*FieldError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[0]
 }
@@ -81,9 +81,11 @@ func TestValidateKubernetesVersion(t *testing.T) {
 		input     string
 		wantError bool
 	}{
-		{"valid version 1.29", "1.29", false},
-		{"valid version 1.28", "1.28", false},
-		{"valid version 1.5", "1.5", false},
+		{"valid version 1.31", "1.31", false},
+		{"valid version 1.34", "1.34", false},
+		{"below minimum 1.29", "1.29", true},
+		{"below minimum 1.20", "1.20", true},
+		{"below minimum 1.5", "1.5", true},
 		{"empty version", "", true},
 		{"invalid format - no minor", "1", true},
 		{"invalid format - three parts", "1.29.0", true},
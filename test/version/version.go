@@ -0,0 +1,235 @@
+// Package version parses, compares, and filters Kubernetes major.minor versions
+// as used by EKS (e.g. "1.31"). It is shared by the unit validators and the
+// integration version matrix so both agree on ordering and ranges.
+package version
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// KubeVersion is a Kubernetes major.minor version.
+type KubeVersion struct {
+	Major int
+	Minor int
+}
+
+// ParseError reports why a string is not a valid KubeVersion or constraint.
+type ParseError struct {
+	Input  string
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid kubernetes version %q: %s", e.Input, e.Reason)
+}
+
+// Parse parses a strict "MAJOR.MINOR" version. Prefixes ("v1.31"), patch
+// components ("1.31.0"), and leading zeros ("1.05") are rejected.
+func Parse(s string) (KubeVersion, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 2 {
+		return KubeVersion{}, &ParseError{Input: s, Reason: "must be in MAJOR.MINOR format"}
+	}
+
+	major, err := parseComponent(parts[0])
+	if err != nil {
+		return KubeVersion{}, &ParseError{Input: s, Reason: "major " + err.Error()}
+	}
+	minor, err := parseComponent(parts[1])
+	if err != nil {
+		return KubeVersion{}, &ParseError{Input: s, Reason: "minor " + err.Error()}
+	}
+
+	return KubeVersion{Major: major, Minor: minor}, nil
+}
+
+// MustParse is like Parse but panics on error. Use it for constants only.
+func MustParse(s string) KubeVersion {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// ParseAll parses every string in ss, failing on the first invalid entry.
+func ParseAll(ss []string) ([]KubeVersion, error) {
+	versions := make([]KubeVersion, 0, len(ss))
+	for _, s := range ss {
+		v, err := Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func parseComponent(s string) (int, error) {
+	if s == "" {
+		return 0, fmt.Errorf("is empty")
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("%q must contain only digits", s)
+		}
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("%q has a leading zero", s)
+	}
+	return strconv.Atoi(s)
+}
+
+// String formats the version as "MAJOR.MINOR".
+func (v KubeVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// Compare returns -1, 0, or 1 as v is older than, equal to, or newer than o.
+func (v KubeVersion) Compare(o KubeVersion) int {
+	if v.Major != o.Major {
+		return cmp.Compare(v.Major, o.Major)
+	}
+	return cmp.Compare(v.Minor, o.Minor)
+}
+
+// Less reports whether v is older than o.
+func (v KubeVersion) Less(o KubeVersion) bool {
+	return v.Compare(o) < 0
+}
+
+// NextMinor returns the version one minor release after v.
+func (v KubeVersion) NextMinor() KubeVersion {
+	return KubeVersion{Major: v.Major, Minor: v.Minor + 1}
+}
+
+// Sort orders versions oldest first.
+func Sort(versions []KubeVersion) {
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Less(versions[j])
+	})
+}
+
+// Strings formats each version as "MAJOR.MINOR".
+func Strings(versions []KubeVersion) []string {
+	out := make([]string, 0, len(versions))
+	for _, v := range versions {
+		out = append(out, v.String())
+	}
+	return out
+}
+
+// LatestMinors returns the newest n versions, oldest first. The input is not modified.
+func LatestMinors(versions []KubeVersion, n int) []KubeVersion {
+	sorted := append([]KubeVersion(nil), versions...)
+	Sort(sorted)
+	if n < 0 {
+		n = 0
+	}
+	if n < len(sorted) {
+		sorted = sorted[len(sorted)-n:]
+	}
+	return sorted
+}
+
+// Constraint is a set of comparisons that must all hold, e.g. ">=1.31,<1.35".
+type Constraint struct {
+	terms []term
+	raw   string
+}
+
+type term struct {
+	op      string
+	version KubeVersion
+}
+
+// constraintOps is ordered so two-character operators match before their prefixes.
+var constraintOps = []string{">=", "<=", "!=", ">", "<", "="}
+
+// ParseConstraint parses comma-separated comparisons. Supported operators are
+// >=, >, <=, <, =, and !=; a bare version means "=".
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: s}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return Constraint{}, &ParseError{Input: s, Reason: "empty constraint term"}
+		}
+
+		op := "="
+		for _, candidate := range constraintOps {
+			if strings.HasPrefix(part, candidate) {
+				op = candidate
+				part = strings.TrimSpace(strings.TrimPrefix(part, candidate))
+				break
+			}
+		}
+
+		v, err := Parse(part)
+		if err != nil {
+			return Constraint{}, &ParseError{Input: s, Reason: err.(*ParseError).Reason + " in term " + strings.TrimSpace(part)}
+		}
+		c.terms = append(c.terms, term{op: op, version: v})
+	}
+	return c, nil
+}
+
+// MustParseConstraint is like ParseConstraint but panics on error.
+func MustParseConstraint(s string) Constraint {
+	c, err := ParseConstraint(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// AtLeast returns the constraint ">=v".
+func AtLeast(v KubeVersion) Constraint {
+	return Constraint{terms: []term{{op: ">=", version: v}}, raw: ">=" + v.String()}
+}
+
+// Check reports whether v satisfies every term. The zero Constraint matches everything.
+func (c Constraint) Check(v KubeVersion) bool {
+	for _, t := range c.terms {
+		diff := v.Compare(t.version)
+		var ok bool
+		switch t.op {
+		case ">=":
+			ok = diff >= 0
+		case ">":
+			ok = diff > 0
+		case "<=":
+			ok = diff <= 0
+		case "<":
+			ok = diff < 0
+		case "=":
+			ok = diff == 0
+		case "!=":
+			ok = diff != 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the versions that satisfy c, preserving order.
+func (c Constraint) Filter(versions []KubeVersion) []KubeVersion {
+	var out []KubeVersion
+	for _, v := range versions {
+		if c.Check(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// String returns the constraint as it was written.
+func (c Constraint) String() string {
+	return c.raw
+}
@@ -0,0 +1,118 @@
+package version
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input     string
+		want      KubeVersion
+		wantError string
+	}{
+		{input: "1.31", want: KubeVersion{1, 31}},
+		{input: "1.5", want: KubeVersion{1, 5}},
+		{input: "2.0", want: KubeVersion{2, 0}},
+		{input: "1.3x", wantError: `minor "3x" must contain only digits`},
+		{input: "1.05", wantError: `minor "05" has a leading zero`},
+		{input: "v1.31", wantError: `major "v1" must contain only digits`},
+		{input: "1.31.0", wantError: "must be in MAJOR.MINOR format"},
+		{input: "1", wantError: "must be in MAJOR.MINOR format"},
+		{input: "1.", wantError: "minor is empty"},
+		{input: "", wantError: "must be in MAJOR.MINOR format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if tt.wantError != "" {
+				var perr *ParseError
+				require.True(t, errors.As(err, &perr), "expected *ParseError, got %v", err)
+				assert.Equal(t, tt.input, perr.Input)
+				assert.Contains(t, err.Error(), tt.wantError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.input, got.String())
+		})
+	}
+}
+
+func TestCompareAndSort(t *testing.T) {
+	assert.Equal(t, -1, MustParse("1.9").Compare(MustParse("1.10")))
+	assert.Equal(t, 0, MustParse("1.31").Compare(MustParse("1.31")))
+	assert.Equal(t, 1, MustParse("2.0").Compare(MustParse("1.99")))
+	assert.True(t, MustParse("1.30").Less(MustParse("1.31")))
+	assert.Equal(t, MustParse("1.32"), MustParse("1.31").NextMinor())
+
+	versions, err := ParseAll([]string{"1.33", "1.9", "1.31", "1.10"})
+	require.NoError(t, err)
+	Sort(versions)
+	assert.Equal(t, []string{"1.9", "1.10", "1.31", "1.33"}, Strings(versions))
+
+	_, err = ParseAll([]string{"1.31", "bad"})
+	assert.Error(t, err)
+}
+
+func TestLatestMinors(t *testing.T) {
+	versions, err := ParseAll([]string{"1.33", "1.31", "1.34", "1.32"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"1.33", "1.34"}, Strings(LatestMinors(versions, 2)))
+	assert.Equal(t, []string{"1.31", "1.32", "1.33", "1.34"}, Strings(LatestMinors(versions, 10)))
+	assert.Empty(t, LatestMinors(versions, 0))
+	assert.Equal(t, "1.33", versions[0].String(), "input must not be reordered")
+}
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		allowed    []string
+		rejected   []string
+	}{
+		{">=1.31,<1.35", []string{"1.31", "1.34"}, []string{"1.30", "1.35", "2.0"}},
+		{"> 1.31, <= 1.33", []string{"1.32", "1.33"}, []string{"1.31", "1.34"}},
+		{"1.32", []string{"1.32"}, []string{"1.31", "1.33"}},
+		{"=1.32", []string{"1.32"}, []string{"1.33"}},
+		{">=1.31,!=1.33", []string{"1.31", "1.32", "1.34"}, []string{"1.33", "1.30"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			c, err := ParseConstraint(tt.constraint)
+			require.NoError(t, err)
+			assert.Equal(t, tt.constraint, c.String())
+
+			for _, v := range tt.allowed {
+				assert.True(t, c.Check(MustParse(v)), "%s should satisfy %s", v, tt.constraint)
+			}
+			for _, v := range tt.rejected {
+				assert.False(t, c.Check(MustParse(v)), "%s should not satisfy %s", v, tt.constraint)
+			}
+		})
+	}
+}
+
+func TestConstraintFilter(t *testing.T) {
+	versions, err := ParseAll([]string{"1.30", "1.31", "1.32", "1.35"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"1.31", "1.32"}, Strings(MustParseConstraint(">=1.31,<1.35").Filter(versions)))
+	assert.Equal(t, []string{"1.32", "1.35"}, Strings(AtLeast(MustParse("1.32")).Filter(versions)))
+	assert.Len(t, Constraint{}.Filter(versions), 4, "zero constraint matches everything")
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, input := range []string{"", ">=1.31,", ">=1.3x", "~>1.31", ">=1.31,<"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParseConstraint(input)
+			var perr *ParseError
+			assert.True(t, errors.As(err, &perr), "expected *ParseError for %q, got %v", input, err)
+		})
+	}
+}
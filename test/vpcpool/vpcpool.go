@@ -0,0 +1,252 @@
+// Package vpcpool leases pre-existing VPCs to matrix runs, so a run can skip
+// applying examples/vpc and paying for its NAT gateway.
+//
+// A pool is a set of VPCs tagged VPCPool=<name>. A run leases one by tagging
+// it with its RunID and an expiry, and releases it by removing those tags. An
+// expired lease is free again, so a run that dies without releasing strands
+// its VPC for at most TTL.
+//
+// EC2 has no conditional tag write, so two runs can tag the same VPC at once.
+// Acquire re-reads the tags after Settle and backs off if another run's write
+// landed last.
+package vpcpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+// Tag keys on pool VPCs.
+const (
+	TagPool           = "VPCPool"
+	TagLeaseHolder    = "VPCPoolLeaseHolder"
+	TagLeaseExpiresAt = "VPCPoolLeaseExpiresAt"
+)
+
+// privateSubnetTag marks the subnets examples/vpc creates as private.
+const privateSubnetTag = "kubernetes.io/role/internal-elb"
+
+// Defaults for New.
+const (
+	DefaultTTL    = 2 * time.Hour
+	DefaultSettle = 5 * time.Second
+)
+
+// ErrNoneFree is returned by Acquire when every VPC in the pool is leased.
+var ErrNoneFree = errors.New("no free VPC in the pool")
+
+// Lease is a VPC held by one run.
+type Lease struct {
+	Pool           string
+	VPCID          string
+	PrivateSubnets []string
+	Holder         string
+	ExpiresAt      time.Time
+}
+
+// Pool leases the VPCs tagged VPCPool=Name.
+type Pool struct {
+	EC2  ec2iface.EC2API
+	Name string
+	// TTL is how long a lease lasts. It should outlive the longest run.
+	TTL time.Duration
+	// Settle is how long Acquire waits after tagging a VPC before checking
+	// that no other run tagged it too.
+	Settle time.Duration
+
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// New returns a pool with the default TTL and Settle.
+func New(client ec2iface.EC2API, name string) *Pool {
+	return &Pool{
+		EC2:    client,
+		Name:   name,
+		TTL:    DefaultTTL,
+		Settle: DefaultSettle,
+		now:    time.Now,
+		sleep:  sleep,
+	}
+}
+
+// Acquire leases a free VPC for holder, normally the run's RunID. A VPC
+// holder already leases is returned first, so a resumed run gets its VPC
+// back. VPCs without private subnets are skipped. Returns ErrNoneFree if no
+// VPC could be leased.
+func (p *Pool) Acquire(ctx context.Context, holder string) (*Lease, error) {
+	if p.Name == "" || holder == "" {
+		return nil, errors.New("vpcpool: pool name and holder are required")
+	}
+
+	vpcs, err := p.describe(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := p.now()
+	var candidates []*ec2.Vpc
+	for _, vpc := range vpcs {
+		switch p.holder(vpc, now) {
+		case holder:
+			candidates = append([]*ec2.Vpc{vpc}, candidates...)
+		case "":
+			candidates = append(candidates, vpc)
+		}
+	}
+
+	var errs []error
+	for _, vpc := range candidates {
+		lease, err := p.lease(ctx, aws.StringValue(vpc.VpcId), holder)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if lease != nil {
+			return lease, nil
+		}
+	}
+	return nil, errors.Join(append([]error{ErrNoneFree}, errs...)...)
+}
+
+// lease tags vpcID for holder and confirms the tag stuck. It returns nil, nil
+// if another run won the VPC.
+func (p *Pool) lease(ctx context.Context, vpcID, holder string) (*Lease, error) {
+	expires := p.now().Add(p.TTL).UTC().Truncate(time.Second)
+	_, err := p.EC2.CreateTagsWithContext(ctx, &ec2.CreateTagsInput{
+		Resources: aws.StringSlice([]string{vpcID}),
+		Tags: []*ec2.Tag{
+			{Key: aws.String(TagLeaseHolder), Value: aws.String(holder)},
+			{Key: aws.String(TagLeaseExpiresAt), Value: aws.String(expires.Format(time.RFC3339))},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lease %s: %w", vpcID, err)
+	}
+
+	if err := p.sleep(ctx, p.Settle); err != nil {
+		return nil, err
+	}
+	vpcs, err := p.describe(ctx, []string{vpcID})
+	if err != nil {
+		return nil, err
+	}
+	if len(vpcs) == 0 {
+		return nil, nil
+	}
+	if p.holder(vpcs[0], p.now()) != holder {
+		return nil, nil
+	}
+
+	subnets, err := p.privateSubnets(ctx, vpcID)
+	if err == nil && len(subnets) == 0 {
+		err = fmt.Errorf("%s has no subnets tagged %s", vpcID, privateSubnetTag)
+	}
+	if err != nil {
+		return nil, errors.Join(err, p.Release(ctx, &Lease{Pool: p.Name, VPCID: vpcID, Holder: holder}))
+	}
+
+	return &Lease{Pool: p.Name, VPCID: vpcID, PrivateSubnets: subnets, Holder: holder, ExpiresAt: expires}, nil
+}
+
+// Release ends a lease. A lease that already passed to another run, after
+// expiring, is left alone.
+func (p *Pool) Release(ctx context.Context, lease *Lease) error {
+	vpcs, err := p.describe(ctx, []string{lease.VPCID})
+	if err != nil {
+		return err
+	}
+	if len(vpcs) == 0 || tagValue(vpcs[0].Tags, TagLeaseHolder) != lease.Holder {
+		return nil
+	}
+
+	_, err = p.EC2.DeleteTagsWithContext(ctx, &ec2.DeleteTagsInput{
+		Resources: aws.StringSlice([]string{lease.VPCID}),
+		Tags:      []*ec2.Tag{{Key: aws.String(TagLeaseHolder)}, {Key: aws.String(TagLeaseExpiresAt)}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release %s: %w", lease.VPCID, err)
+	}
+	return nil
+}
+
+// holder returns who leases vpc at now, or "" if it's free. An unparseable
+// expiry counts as expired.
+func (p *Pool) holder(vpc *ec2.Vpc, now time.Time) string {
+	expires, err := time.Parse(time.RFC3339, tagValue(vpc.Tags, TagLeaseExpiresAt))
+	if err != nil || !now.Before(expires) {
+		return ""
+	}
+	return tagValue(vpc.Tags, TagLeaseHolder)
+}
+
+// describe returns the pool's available VPCs, narrowed to ids if given,
+// ordered by ID.
+func (p *Pool) describe(ctx context.Context, ids []string) ([]*ec2.Vpc, error) {
+	input := &ec2.DescribeVpcsInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("tag:" + TagPool), Values: aws.StringSlice([]string{p.Name})},
+			{Name: aws.String("state"), Values: aws.StringSlice([]string{ec2.VpcStateAvailable})},
+		},
+	}
+	if len(ids) > 0 {
+		input.VpcIds = aws.StringSlice(ids)
+	}
+
+	var vpcs []*ec2.Vpc
+	err := p.EC2.DescribeVpcsPagesWithContext(ctx, input, func(page *ec2.DescribeVpcsOutput, _ bool) bool {
+		vpcs = append(vpcs, page.Vpcs...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pool %s: %w", p.Name, err)
+	}
+	sort.Slice(vpcs, func(i, j int) bool { return aws.StringValue(vpcs[i].VpcId) < aws.StringValue(vpcs[j].VpcId) })
+	return vpcs, nil
+}
+
+func (p *Pool) privateSubnets(ctx context.Context, vpcID string) ([]string, error) {
+	out, err := p.EC2.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("vpc-id"), Values: aws.StringSlice([]string{vpcID})},
+			{Name: aws.String("tag:" + privateSubnetTag), Values: aws.StringSlice([]string{"1"})},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subnets of %s: %w", vpcID, err)
+	}
+
+	var ids []string
+	for _, subnet := range out.Subnets {
+		ids = append(ids, aws.StringValue(subnet.SubnetId))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func tagValue(tags []*ec2.Tag, key string) string {
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) == key {
+			return aws.StringValue(tag.Value)
+		}
+	}
+	return ""
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,257 @@
+package vpcpool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeEC2 holds VPCs and their subnets. onCreateTags, if set, runs after
+// every CreateTags, so a test can play a competing run.
+type fakeEC2 struct {
+	ec2iface.EC2API
+
+	vpcs         map[string]map[string]string
+	subnets      map[string][]*ec2.Subnet
+	onCreateTags func(vpcID string)
+	describeErr  error
+}
+
+func (f *fakeEC2) DescribeVpcsPagesWithContext(_ context.Context, in *ec2.DescribeVpcsInput, fn func(*ec2.DescribeVpcsOutput, bool) bool, _ ...request.Option) error {
+	if f.describeErr != nil {
+		return f.describeErr
+	}
+	ids := aws.StringValueSlice(in.VpcIds)
+	out := &ec2.DescribeVpcsOutput{}
+	for id, tags := range f.vpcs {
+		if len(ids) > 0 && !contains(ids, id) {
+			continue
+		}
+		vpc := &ec2.Vpc{VpcId: aws.String(id), State: aws.String(ec2.VpcStateAvailable)}
+		for k, v := range tags {
+			vpc.Tags = append(vpc.Tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		if matches(in.Filters, vpc) {
+			out.Vpcs = append(out.Vpcs, vpc)
+		}
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeEC2) DescribeSubnetsWithContext(_ context.Context, in *ec2.DescribeSubnetsInput, _ ...request.Option) (*ec2.DescribeSubnetsOutput, error) {
+	var vpcID string
+	for _, filter := range in.Filters {
+		if aws.StringValue(filter.Name) == "vpc-id" {
+			vpcID = aws.StringValue(filter.Values[0])
+		}
+	}
+	return &ec2.DescribeSubnetsOutput{Subnets: f.subnets[vpcID]}, nil
+}
+
+func (f *fakeEC2) CreateTagsWithContext(_ context.Context, in *ec2.CreateTagsInput, _ ...request.Option) (*ec2.CreateTagsOutput, error) {
+	for _, id := range aws.StringValueSlice(in.Resources) {
+		for _, tag := range in.Tags {
+			f.vpcs[id][aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		if f.onCreateTags != nil {
+			f.onCreateTags(id)
+		}
+	}
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+func (f *fakeEC2) DeleteTagsWithContext(_ context.Context, in *ec2.DeleteTagsInput, _ ...request.Option) (*ec2.DeleteTagsOutput, error) {
+	for _, id := range aws.StringValueSlice(in.Resources) {
+		for _, tag := range in.Tags {
+			delete(f.vpcs[id], aws.StringValue(tag.Key))
+		}
+	}
+	return &ec2.DeleteTagsOutput{}, nil
+}
+
+// matches applies the tag:<key> filters; the fake's VPCs are all available.
+func matches(filters []*ec2.Filter, vpc *ec2.Vpc) bool {
+	for _, f := range filters {
+		key, ok := strings.CutPrefix(aws.StringValue(f.Name), "tag:")
+		if ok && !contains(aws.StringValueSlice(f.Values), tagValue(vpc.Tags, key)) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func leasedBy(holder string, expires time.Time) map[string]string {
+	return map[string]string{
+		TagPool:           "ci",
+		TagLeaseHolder:    holder,
+		TagLeaseExpiresAt: expires.Format(time.RFC3339),
+	}
+}
+
+func privateSubnets(ids ...string) []*ec2.Subnet {
+	var subnets []*ec2.Subnet
+	for _, id := range ids {
+		subnets = append(subnets, &ec2.Subnet{SubnetId: aws.String(id)})
+	}
+	return subnets
+}
+
+func newTestPool(f *fakeEC2) *Pool {
+	p := New(f, "ci")
+	p.now = func() time.Time { return now }
+	p.sleep = func(context.Context, time.Duration) error { return nil }
+	return p
+}
+
+func TestAcquire(t *testing.T) {
+	tests := []struct {
+		name    string
+		vpcs    map[string]map[string]string
+		wantVPC string
+	}{
+		{
+			name: "first free",
+			vpcs: map[string]map[string]string{
+				"vpc-a": leasedBy("run-1", now.Add(time.Hour)),
+				"vpc-b": {TagPool: "ci"},
+				"vpc-c": {TagPool: "ci"},
+			},
+			wantVPC: "vpc-b",
+		},
+		{
+			name: "expired lease is free",
+			vpcs: map[string]map[string]string{
+				"vpc-a": leasedBy("run-1", now.Add(-time.Minute)),
+			},
+			wantVPC: "vpc-a",
+		},
+		{
+			name: "own lease first",
+			vpcs: map[string]map[string]string{
+				"vpc-a": {TagPool: "ci"},
+				"vpc-b": leasedBy("run-2", now.Add(time.Hour)),
+			},
+			wantVPC: "vpc-b",
+		},
+		{
+			name: "other pools ignored",
+			vpcs: map[string]map[string]string{
+				"vpc-a": {TagPool: "other"},
+				"vpc-b": {TagPool: "ci"},
+			},
+			wantVPC: "vpc-b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeEC2{vpcs: tt.vpcs, subnets: map[string][]*ec2.Subnet{
+				"vpc-a": privateSubnets("subnet-a2", "subnet-a1"),
+				"vpc-b": privateSubnets("subnet-b1"),
+				"vpc-c": privateSubnets("subnet-c1"),
+			}}
+
+			lease, err := newTestPool(f).Acquire(context.Background(), "run-2")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantVPC, lease.VPCID)
+			assert.Equal(t, "run-2", lease.Holder)
+			assert.Equal(t, now.Add(DefaultTTL), lease.ExpiresAt)
+			assert.Equal(t, "run-2", f.vpcs[tt.wantVPC][TagLeaseHolder])
+			assert.Equal(t, "2026-03-01T14:00:00Z", f.vpcs[tt.wantVPC][TagLeaseExpiresAt])
+			if tt.wantVPC == "vpc-a" {
+				assert.Equal(t, []string{"subnet-a1", "subnet-a2"}, lease.PrivateSubnets)
+			}
+		})
+	}
+}
+
+func TestAcquireNoneFree(t *testing.T) {
+	f := &fakeEC2{vpcs: map[string]map[string]string{
+		"vpc-a": leasedBy("run-1", now.Add(time.Hour)),
+	}}
+
+	_, err := newTestPool(f).Acquire(context.Background(), "run-2")
+	assert.ErrorIs(t, err, ErrNoneFree)
+	assert.Equal(t, "run-1", f.vpcs["vpc-a"][TagLeaseHolder])
+}
+
+func TestAcquireLosesRace(t *testing.T) {
+	f := &fakeEC2{
+		vpcs: map[string]map[string]string{
+			"vpc-a": {TagPool: "ci"},
+			"vpc-b": {TagPool: "ci"},
+		},
+		subnets: map[string][]*ec2.Subnet{"vpc-a": privateSubnets("subnet-a1"), "vpc-b": privateSubnets("subnet-b1")},
+	}
+	// Another run tags vpc-a right after we do, so its write lands last.
+	f.onCreateTags = func(id string) {
+		if id == "vpc-a" && f.vpcs[id][TagLeaseHolder] == "run-2" {
+			f.vpcs[id][TagLeaseHolder] = "run-1"
+		}
+	}
+
+	lease, err := newTestPool(f).Acquire(context.Background(), "run-2")
+	require.NoError(t, err)
+	assert.Equal(t, "vpc-b", lease.VPCID)
+	assert.Equal(t, "run-1", f.vpcs["vpc-a"][TagLeaseHolder], "the winner keeps its lease")
+}
+
+func TestAcquireSkipsVPCsWithoutPrivateSubnets(t *testing.T) {
+	f := &fakeEC2{
+		vpcs: map[string]map[string]string{
+			"vpc-a": {TagPool: "ci"},
+			"vpc-b": {TagPool: "ci"},
+		},
+		subnets: map[string][]*ec2.Subnet{"vpc-b": privateSubnets("subnet-b1")},
+	}
+
+	lease, err := newTestPool(f).Acquire(context.Background(), "run-2")
+	require.NoError(t, err)
+	assert.Equal(t, "vpc-b", lease.VPCID)
+	assert.NotContains(t, f.vpcs["vpc-a"], TagLeaseHolder, "the unusable VPC is released")
+}
+
+func TestAcquireErrors(t *testing.T) {
+	_, err := newTestPool(&fakeEC2{}).Acquire(context.Background(), "")
+	assert.Error(t, err)
+
+	f := &fakeEC2{describeErr: errors.New("UnauthorizedOperation")}
+	_, err = newTestPool(f).Acquire(context.Background(), "run-2")
+	assert.ErrorContains(t, err, "UnauthorizedOperation")
+	assert.NotErrorIs(t, err, ErrNoneFree)
+}
+
+func TestRelease(t *testing.T) {
+	f := &fakeEC2{vpcs: map[string]map[string]string{
+		"vpc-a": leasedBy("run-2", now.Add(time.Hour)),
+		"vpc-b": leasedBy("run-3", now.Add(time.Hour)),
+	}}
+	p := newTestPool(f)
+
+	require.NoError(t, p.Release(context.Background(), &Lease{VPCID: "vpc-a", Holder: "run-2"}))
+	assert.Equal(t, map[string]string{TagPool: "ci"}, f.vpcs["vpc-a"])
+
+	require.NoError(t, p.Release(context.Background(), &Lease{VPCID: "vpc-b", Holder: "run-2"}))
+	assert.Equal(t, "run-3", f.vpcs["vpc-b"][TagLeaseHolder], "a lease that passed to another run is kept")
+}